@@ -3,18 +3,23 @@ package main
 import (
 	"log"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
-	"github.com/go-chi/jwtauth"
 	"github.com/gsouza97/go-expert-api/configs"
 	_ "github.com/gsouza97/go-expert-api/docs"
 	"github.com/gsouza97/go-expert-api/internal/entity"
 	"github.com/gsouza97/go-expert-api/internal/infra/database"
+	"github.com/gsouza97/go-expert-api/internal/infra/database/connection"
+	"github.com/gsouza97/go-expert-api/internal/infra/database/migrations"
 	"github.com/gsouza97/go-expert-api/internal/infra/webserver/handlers"
+	"github.com/gsouza97/go-expert-api/internal/infra/webserver/middlewares"
+	"github.com/gsouza97/go-expert-api/internal/infra/webserver/problem"
+	"github.com/gsouza97/go-expert-api/pkg/cursor"
+	"github.com/gsouza97/go-expert-api/pkg/jwtkeys"
 	httpSwagger "github.com/swaggo/http-swagger"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
@@ -36,42 +41,246 @@ import (
 // @securityDefinitions.apikey  ApiKeyAuth
 // @in  header
 // @name Authorization
+
+// @securityDefinitions.apikey  XAPIKey
+// @in  header
+// @name X-API-Key
 func main() {
 	config, err := configs.LoadConfig(".")
 	if err != nil {
 		panic(err)
 	}
-	println(config.DBDriver)
 
-	db, err := gorm.Open(sqlite.Open("test.db"), &gorm.Config{})
+	openDB := func() (*gorm.DB, error) {
+		return connection.Open(connection.Config{
+			Driver:          config.DBDriver,
+			Host:            config.DBHost,
+			Port:            config.DBPort,
+			User:            config.DBUser,
+			Password:        config.DBPassword,
+			Name:            config.DBName,
+			SSLMode:         config.DBSSLMode,
+			MaxOpenConns:    config.DBMaxOpenConns,
+			MaxIdleConns:    config.DBMaxIdleConns,
+			ConnMaxLifetime: time.Minute * time.Duration(config.DBConnMaxLifetime),
+		})
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(openDB, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "tenant" {
+		if err := runTenant(openDB, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	db, err := openDB()
+	if err != nil {
+		panic(err)
+	}
+	migrator, err := migrations.NewMigrator(db, migrations.All())
+	if err != nil {
+		panic(err)
+	}
+	pending, err := migrator.Pending()
+	if err != nil {
+		panic(err)
+	}
+	if len(pending) > 0 {
+		log.Printf("database has %d pending migrations, run \"migrate up\"", len(pending))
+	}
+
+	passwordPolicy, err := config.NewPasswordPolicy()
+	if err != nil {
+		panic(err)
+	}
+	passwordHasher, err := config.NewPasswordHasher()
 	if err != nil {
 		panic(err)
 	}
-	db.AutoMigrate(&entity.Product{}, &entity.User{})
+	entity.SetPasswordPolicy(passwordPolicy)
+	entity.SetPasswordHasher(passwordHasher)
 
 	productDB := database.NewProductDB(db)
-	productHandler := handlers.NewProductHandler(productDB)
+	// Cada propósito sem segredo próprio usa uma chave derivada do JWT_SECRET
+	rootSecret := []byte(config.JWTSecret)
+	if len(rootSecret) == 0 {
+		log.Println("JWT_SECRET is not set, MFA challenges, cursors and links without a dedicated secret will not survive a restart")
+		random, _ := entity.NewOpaqueToken()
+		rootSecret = []byte(random)
+	}
+	signingKey := func(secret, purpose string) []byte {
+		if secret != "" {
+			return []byte(secret)
+		}
+		return cursor.DeriveKey(rootSecret, purpose)
+	}
+	productHandler := handlers.NewProductHandler(productDB, config.MaxPageSize, cursor.NewSigner(signingKey(config.CursorSecret, "cursor-v1")), config.RequireIfMatch)
 
 	userDB := database.NewUserDB(db)
-	userHandler := handlers.NewUserHandler(userDB, config.TokenAuthKey, config.JWTExpiresIn)
+	refreshTokenDB := database.NewRefreshTokenDB(db)
+	revokedTokenDB := database.NewRevokedTokenDB(db)
+	tokenIssuer := handlers.NewTokenIssuer(
+		config.TokenAuthKey,
+		refreshTokenDB,
+		time.Minute*time.Duration(config.JWTAccessExpiresIn),
+		time.Hour*time.Duration(config.JWTRefreshExpiresIn),
+	)
+	mailer, err := config.NewMailer()
+	if err != nil {
+		panic(err)
+	}
+	emailVerifier := handlers.NewEmailVerifier(
+		userDB,
+		cursor.NewSigner(signingKey(config.EmailVerificationSecret, "email-verification-v1")),
+		mailer,
+		time.Hour*time.Duration(config.EmailVerificationExpiresIn),
+		config.EmailVerificationURL,
+		config.EmailVerificationRequired,
+	)
+	passwordResetter := handlers.NewPasswordResetter(
+		userDB,
+		database.NewPasswordResetTokenDB(db),
+		refreshTokenDB,
+		mailer,
+		time.Minute*time.Duration(config.PasswordResetExpiresIn),
+		config.PasswordResetURL,
+	)
+	totpManager := handlers.NewTOTPManager(
+		userDB,
+		database.NewRecoveryCodeDB(db),
+		cursor.NewSigner(signingKey("", "mfa-challenge-v1")),
+		config.MFAIssuer,
+		time.Minute*time.Duration(config.MFAChallengeExpiresIn),
+	)
+	loginThrottleDB := database.NewLoginThrottleDB(db)
+	loginThrottler := handlers.NewLoginThrottler(
+		loginThrottleDB,
+		config.LoginMaxAttempts,
+		config.LoginMaxAttemptsPerIP,
+		time.Minute*time.Duration(config.LoginAttemptWindow),
+		time.Second*time.Duration(config.LoginLockoutBase),
+		time.Minute*time.Duration(config.LoginLockoutMax),
+	)
+	tenantResolver := handlers.NewTenantResolver(database.NewTenantDB(db), config.DefaultTenant)
+	userHandler := handlers.NewUserHandler(userDB, revokedTokenDB, tokenIssuer, emailVerifier, passwordResetter, totpManager, loginThrottler, tenantResolver)
+	adminHandler := handlers.NewAdminHandler(userDB, refreshTokenDB, loginThrottleDB, loginThrottler, config.MaxPageSize)
+	apiKeyDB := database.NewAPIKeyDB(db)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyDB, userDB)
+	oauthHandler := handlers.NewOAuthHandler(database.NewOAuthClientDB(db), userDB, tokenIssuer, emailVerifier, loginThrottler)
+	jwksHandler := handlers.NewJWKSHandler(config.TokenAuthKey)
+
+	// Remove periodicamente da denylist os tokens que já expiraram
+	go func() {
+		for range time.Tick(time.Hour) {
+			if err := revokedTokenDB.DeleteExpired(); err != nil {
+				log.Printf("error cleaning revoked tokens: %v", err)
+			}
+		}
+	}()
+
+	// Esvazia a lixeira de produtos após o prazo de retenção
+	trashRetention := time.Hour * 24 * time.Duration(config.ProductTrashRetention)
+	go func() {
+		for range time.Tick(time.Hour) {
+			purged, err := productDB.PurgeDeletedBefore(time.Now().Add(-trashRetention))
+			if err != nil {
+				log.Printf("error purging product trash: %v", err)
+				continue
+			}
+			if purged > 0 {
+				log.Printf("purged %d products from the trash", purged)
+			}
+		}
+	}()
 
 	r := chi.NewRouter()
 	r.Use(middleware.Logger)
-	r.Use(middleware.Recoverer)
+	r.Use(middlewares.Recoverer)
 	r.Use(middleware.Timeout(time.Second * 10))
+	r.NotFound(problem.NotFoundHandler)
+	r.MethodNotAllowed(problem.MethodNotAllowedHandler)
 
+	// Integrações podem usar o header X-API-Key em vez do access token
+	productAuth := middlewares.AcceptAPIKeys(
+		middlewares.APIKeyAuthenticator(apiKeyDB, userDB),
+		jwtkeys.Verifier(config.TokenAuthKey),
+		middlewares.Authenticator,
+		middlewares.RejectRevokedTokens(revokedTokenDB),
+		middlewares.RejectInactiveSessions(userDB),
+	)
+	canRead := middlewares.RequirePermission(entity.PermissionProductsRead)
+	canWrite := middlewares.RequirePermission(entity.PermissionProductsWrite)
 	r.Route("/products", func(r chi.Router) {
-		r.Use(jwtauth.Verifier(config.TokenAuthKey))
-		r.Use(jwtauth.Authenticator)
-		r.Post("/", productHandler.CreateProduct)
-		r.Get("/", productHandler.GetProducts)
-		r.Get("/{id}", productHandler.GetProduct)
-		r.Put("/{id}", productHandler.UpdateProduct)
-		r.Delete("/{id}", productHandler.DeleteProduct)
+		r.Use(productAuth)
+		r.With(canWrite).Post("/", productHandler.CreateProduct)
+		r.With(canRead).Get("/", productHandler.GetProducts)
+		r.With(canRead).Get("/{id}", productHandler.GetProduct)
+		r.With(canWrite).Put("/{id}", productHandler.UpdateProduct)
+		r.With(canWrite).Patch("/{id}", productHandler.PatchProduct)
+		r.With(canWrite).Delete("/{id}", productHandler.DeleteProduct)
+		r.With(canWrite).Get("/trash", productHandler.GetTrash)
+		r.With(canWrite).Post("/{id}/restore", productHandler.RestoreProduct)
+		r.With(middlewares.RequirePermission(entity.PermissionProductsManage)).Delete("/trash/{id}", productHandler.PurgeProduct)
 	})
+	r.With(productAuth, canRead).Get("/users/me/products", productHandler.GetMyProducts)
 
 	r.Post("/users", userHandler.CreateUser)
 	r.Post("/users/getToken", userHandler.GetJWT)
+	r.Post("/users/getToken/mfa", userHandler.GetJWTWithMFA)
+	r.Post("/users/refresh", userHandler.RefreshJWT)
+	r.Post("/users/verify", userHandler.VerifyEmail)
+	r.Post("/users/verify/resend", userHandler.ResendVerification)
+	r.Post("/users/password/forgot", userHandler.ForgotPassword)
+	r.Post("/users/password/reset", userHandler.ResetPassword)
+	r.Post("/oauth/token", oauthHandler.Token)
+	r.Group(func(r chi.Router) {
+		r.Use(jwtkeys.Verifier(config.TokenAuthKey))
+		r.Use(middlewares.Authenticator)
+		r.Use(middlewares.RejectRevokedTokens(revokedTokenDB))
+		r.Use(middlewares.RejectInactiveSessions(userDB))
+		r.Use(middlewares.RejectScopedTokens)
+		r.Post("/users/logout", userHandler.Logout)
+		r.Get("/users/me", userHandler.GetMe)
+		r.Patch("/users/me", userHandler.UpdateMe)
+		r.Delete("/users/me", userHandler.DeleteMe)
+		r.Put("/users/me/password", userHandler.ChangePassword)
+		r.Post("/users/me/api-keys", apiKeyHandler.CreateAPIKey)
+		r.Get("/users/me/api-keys", apiKeyHandler.GetAPIKeys)
+		r.Delete("/users/me/api-keys/{id}", apiKeyHandler.RevokeAPIKey)
+		r.Post("/users/me/mfa/totp", userHandler.EnrollTOTP)
+		r.Post("/users/me/mfa/totp/confirm", userHandler.ConfirmTOTP)
+		r.Delete("/users/me/mfa/totp", userHandler.DisableTOTP)
+	})
+
+	r.Route("/admin", func(r chi.Router) {
+		r.Use(jwtkeys.Verifier(config.TokenAuthKey))
+		r.Use(middlewares.Authenticator)
+		r.Use(middlewares.RejectRevokedTokens(revokedTokenDB))
+		r.Use(middlewares.RejectInactiveSessions(userDB))
+		r.Use(middlewares.RequirePermission(entity.PermissionUsersManage))
+		r.Get("/lockouts", adminHandler.GetLockouts)
+		r.Get("/users", adminHandler.GetUsers)
+		r.Get("/users/{id}", adminHandler.GetUser)
+		r.Put("/users/{id}/role", adminHandler.UpdateUserRole)
+		r.Post("/users/{id}/disable", adminHandler.DisableUser)
+		r.Post("/users/{id}/enable", adminHandler.EnableUser)
+		r.Post("/users/{id}/logout", adminHandler.LogoutUser)
+		r.Post("/users/{id}/unlock", adminHandler.UnlockUser)
+		r.Post("/users/{id}/api-keys", apiKeyHandler.CreateUserAPIKey)
+		r.Get("/users/{id}/api-keys", apiKeyHandler.GetUserAPIKeys)
+		r.Delete("/users/{id}/api-keys/{keyID}", apiKeyHandler.RevokeUserAPIKey)
+		r.Post("/oauth/clients", oauthHandler.CreateClient)
+		r.Get("/oauth/clients", oauthHandler.GetClients)
+		r.Delete("/oauth/clients/{id}", oauthHandler.RevokeClient)
+	})
+
+	r.Get("/.well-known/jwks.json", jwksHandler.GetJWKS)
 
 	r.Get("/docs/*", httpSwagger.Handler(httpSwagger.URL("http://localhost:8000/docs/doc.json")))
 
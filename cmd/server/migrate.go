@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+
+	"github.com/gsouza97/go-expert-api/internal/infra/database/migrations"
+	"gorm.io/gorm"
+)
+
+const migrateUsage = `usage: server migrate <command>
+
+commands:
+  up                 apply all pending migrations
+  down [N]           revert the last N applied migrations (default 1)
+  status             list migrations and whether they were applied
+  create [-dir DIR] NAME
+                     generate a new numbered migration file`
+
+var ErrMigrateUsage = errors.New(migrateUsage)
+
+// runMigrate executa o subcomando "migrate"
+func runMigrate(openDB func() (*gorm.DB, error), args []string) error {
+	if len(args) == 0 {
+		return ErrMigrateUsage
+	}
+
+	if args[0] == "create" {
+		fs := flag.NewFlagSet("create", flag.ContinueOnError)
+		dir := fs.String("dir", "internal/infra/database/migrations", "migrations directory")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() != 1 {
+			return ErrMigrateUsage
+		}
+		path, err := migrations.Create(*dir, fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		fmt.Println("created", path)
+		return nil
+	}
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+	migrator, err := migrations.NewMigrator(db, migrations.All())
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		done, err := migrator.Up()
+		for _, m := range done {
+			fmt.Printf("applied  %04d_%s\n", m.Version, m.Name)
+		}
+		if err == nil && len(done) == 0 {
+			fmt.Println("no pending migrations")
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil {
+				return ErrMigrateUsage
+			}
+		}
+		done, err := migrator.Down(steps)
+		for _, m := range done {
+			fmt.Printf("reverted %04d_%s\n", m.Version, m.Name)
+		}
+		return err
+	case "status":
+		status, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		for _, s := range status {
+			if s.Applied {
+				fmt.Printf("%04d_%-40s applied at %s\n", s.Version, s.Name, s.AppliedAt.Format("2006-01-02 15:04:05"))
+			} else {
+				fmt.Printf("%04d_%-40s pending\n", s.Version, s.Name)
+			}
+		}
+		return nil
+	}
+	return ErrMigrateUsage
+}
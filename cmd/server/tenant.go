@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	"github.com/gsouza97/go-expert-api/internal/infra/database"
+	"gorm.io/gorm"
+)
+
+const tenantUsage = `usage: server tenant <command>
+
+commands:
+  create SLUG NAME       register a new tenant
+  list                   list the tenants
+  add-user SLUG EMAIL    move a registered user to the tenant`
+
+var ErrTenantUsage = errors.New(tenantUsage)
+
+// runTenant executa o subcomando "tenant"
+func runTenant(openDB func() (*gorm.DB, error), args []string) error {
+	if len(args) == 0 {
+		return ErrTenantUsage
+	}
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+	tenantDB := database.NewTenantDB(db)
+
+	switch args[0] {
+	case "create":
+		if len(args) != 3 {
+			return ErrTenantUsage
+		}
+		tenant, err := entity.NewTenant(args[2], args[1])
+		if err != nil {
+			return err
+		}
+		if err := tenantDB.Create(tenant); err != nil {
+			if errors.Is(err, database.ErrConflict) {
+				return fmt.Errorf("tenant %s already exists", tenant.Slug)
+			}
+			return err
+		}
+		fmt.Printf("created  %s %s\n", tenant.ID, tenant.Slug)
+		return nil
+	case "list":
+		tenants, err := tenantDB.FindAll()
+		if err != nil {
+			return err
+		}
+		for _, t := range tenants {
+			fmt.Printf("%s %-30s %s\n", t.ID, t.Slug, t.Name)
+		}
+		return nil
+	case "add-user":
+		if len(args) != 3 {
+			return ErrTenantUsage
+		}
+		tenant, err := tenantDB.FindBySlug(args[1])
+		if errors.Is(err, database.ErrNotFound) {
+			return fmt.Errorf("tenant %s does not exist", args[1])
+		}
+		if err != nil {
+			return err
+		}
+		userDB := database.NewUserDB(db)
+		user, err := userDB.FindByEmail(args[2])
+		if errors.Is(err, database.ErrNotFound) {
+			return fmt.Errorf("user %s does not exist", args[2])
+		}
+		if err != nil {
+			return err
+		}
+		if err := userDB.MoveToTenant(user.ID.String(), tenant.ID.String()); err != nil {
+			return err
+		}
+		fmt.Printf("moved    %s %s\n", user.Email, tenant.Slug)
+		return nil
+	}
+	return ErrTenantUsage
+}
@@ -1,23 +1,75 @@
 package configs
 
 import (
-	"github.com/go-chi/jwtauth"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	"github.com/gsouza97/go-expert-api/internal/infra/mail"
+	"github.com/gsouza97/go-expert-api/pkg/jwtkeys"
+	"github.com/gsouza97/go-expert-api/pkg/password"
 	"github.com/spf13/viper"
 )
 
 var cfg *conf
 
 type conf struct {
-	DBDriver      string `mapstructure:"DB_DRIVER"`
-	DBHost        string `mapstructure:"DB_HOST"`
-	DBPort        string `mapstructure:"DB_PORT"`
-	DBUser        string `mapstructure:"DB_USER"`
-	DBPassword    string `mapstructure:"DB_PASSWORD"`
-	DBName        string `mapstructure:"DB_NAME"`
-	WebServerPort string `mapstructure:"WEB_SERVER_PORT"`
-	JWTSecret     string `mapstructure:"JWT_SECRET"`
-	JWTExpiresIn  int    `mapstructure:"JWT_EXPIRES_IN"`
-	TokenAuthKey  *jwtauth.JWTAuth
+	DBDriver                   string `mapstructure:"DB_DRIVER"`
+	DBHost                     string `mapstructure:"DB_HOST"`
+	DBPort                     string `mapstructure:"DB_PORT"`
+	DBUser                     string `mapstructure:"DB_USER"`
+	DBPassword                 string `mapstructure:"DB_PASSWORD"`
+	DBName                     string `mapstructure:"DB_NAME"`
+	DBSSLMode                  string `mapstructure:"DB_SSL_MODE"`
+	DBMaxOpenConns             int    `mapstructure:"DB_MAX_OPEN_CONNS"`
+	DBMaxIdleConns             int    `mapstructure:"DB_MAX_IDLE_CONNS"`
+	DBConnMaxLifetime          int    `mapstructure:"DB_CONN_MAX_LIFETIME"` // Em minutos
+	WebServerPort              string `mapstructure:"WEB_SERVER_PORT"`
+	MaxPageSize                int    `mapstructure:"MAX_PAGE_SIZE"`
+	CursorSecret               string `mapstructure:"CURSOR_SECRET"` // Vazio usa uma chave derivada do JWT_SECRET
+	RequireIfMatch             bool   `mapstructure:"REQUIRE_IF_MATCH"`
+	ProductTrashRetention      int    `mapstructure:"PRODUCT_TRASH_RETENTION"` // Em dias
+	JWTSecret                  string `mapstructure:"JWT_SECRET"`
+	JWTExpiresIn               int    `mapstructure:"JWT_EXPIRES_IN"`         // Obsoleto, em horas
+	JWTAccessExpiresIn         int    `mapstructure:"JWT_ACCESS_EXPIRES_IN"`  // Em minutos
+	JWTRefreshExpiresIn        int    `mapstructure:"JWT_REFRESH_EXPIRES_IN"` // Em horas
+	JWTPrivateKeyFile          string `mapstructure:"JWT_PRIVATE_KEY_FILE"`   // PEM RSA/ECDSA, substitui o JWT_SECRET
+	JWTPublicKeyFiles          string `mapstructure:"JWT_PUBLIC_KEY_FILES"`   // Separados por vírgula, só para verificação
+	MailDriver                 string `mapstructure:"MAIL_DRIVER"`            // smtp, file ou memory
+	MailFrom                   string `mapstructure:"MAIL_FROM"`
+	MailDir                    string `mapstructure:"MAIL_DIR"`
+	SMTPHost                   string `mapstructure:"SMTP_HOST"`
+	SMTPPort                   string `mapstructure:"SMTP_PORT"`
+	SMTPUsername               string `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword               string `mapstructure:"SMTP_PASSWORD"`
+	EmailVerificationRequired  bool   `mapstructure:"EMAIL_VERIFICATION_REQUIRED"`
+	EmailVerificationSecret    string `mapstructure:"EMAIL_VERIFICATION_SECRET"`     // Vazio usa uma chave derivada do JWT_SECRET
+	EmailVerificationExpiresIn int    `mapstructure:"EMAIL_VERIFICATION_EXPIRES_IN"` // Em horas
+	EmailVerificationURL       string `mapstructure:"EMAIL_VERIFICATION_URL"`
+	PasswordResetExpiresIn     int    `mapstructure:"PASSWORD_RESET_EXPIRES_IN"` // Em minutos
+	PasswordResetURL           string `mapstructure:"PASSWORD_RESET_URL"`
+	MFAIssuer                  string `mapstructure:"MFA_ISSUER"`
+	MFAChallengeExpiresIn      int    `mapstructure:"MFA_CHALLENGE_EXPIRES_IN"` // Em minutos
+	LoginMaxAttempts           int    `mapstructure:"LOGIN_MAX_ATTEMPTS"`
+	LoginMaxAttemptsPerIP      int    `mapstructure:"LOGIN_MAX_ATTEMPTS_PER_IP"`
+	LoginAttemptWindow         int    `mapstructure:"LOGIN_ATTEMPT_WINDOW"` // Em minutos
+	LoginLockoutBase           int    `mapstructure:"LOGIN_LOCKOUT_BASE"`   // Em segundos, dobra a cada falha
+	LoginLockoutMax            int    `mapstructure:"LOGIN_LOCKOUT_MAX"`    // Em minutos
+	PasswordMinLength          int    `mapstructure:"PASSWORD_MIN_LENGTH"`
+	PasswordRequireUpper       bool   `mapstructure:"PASSWORD_REQUIRE_UPPER"`
+	PasswordRequireLower       bool   `mapstructure:"PASSWORD_REQUIRE_LOWER"`
+	PasswordRequireDigit       bool   `mapstructure:"PASSWORD_REQUIRE_DIGIT"`
+	PasswordRequireSymbol      bool   `mapstructure:"PASSWORD_REQUIRE_SYMBOL"`
+	PasswordBreachedListFile   string `mapstructure:"PASSWORD_BREACHED_LIST_FILE"` // Uma senha por linha
+	PasswordHashAlgorithm      string `mapstructure:"PASSWORD_HASH_ALGORITHM"`     // argon2id ou bcrypt
+	PasswordBcryptCost         int    `mapstructure:"PASSWORD_BCRYPT_COST"`
+	Argon2Memory               int    `mapstructure:"ARGON2_MEMORY"` // Em KiB
+	Argon2Iterations           int    `mapstructure:"ARGON2_ITERATIONS"`
+	Argon2Parallelism          int    `mapstructure:"ARGON2_PARALLELISM"`
+	DefaultTenant              string `mapstructure:"DEFAULT_TENANT"`
+	TokenAuthKey               *jwtkeys.KeySet
 }
 
 func LoadConfig(path string) (*conf, error) {
@@ -35,6 +87,181 @@ func LoadConfig(path string) (*conf, error) {
 	if err != nil {
 		panic(err)
 	}
-	cfg.TokenAuthKey = jwtauth.New("HS256", []byte(cfg.JWTSecret), nil)
+	if cfg.MaxPageSize == 0 {
+		cfg.MaxPageSize = 100
+	}
+	// Zero faria o job de retenção apagar toda a lixeira, então só a ausência da chave usa o padrão
+	if !viper.IsSet("PRODUCT_TRASH_RETENTION") {
+		cfg.ProductTrashRetention = 30
+	}
+	if cfg.ProductTrashRetention <= 0 {
+		return nil, fmt.Errorf("PRODUCT_TRASH_RETENTION must be greater than zero, got %d", cfg.ProductTrashRetention)
+	}
+	// JWT_EXPIRES_IN continua em horas
+	if cfg.JWTAccessExpiresIn == 0 && cfg.JWTExpiresIn > 0 {
+		log.Println("JWT_EXPIRES_IN is deprecated, use JWT_ACCESS_EXPIRES_IN (in minutes)")
+		cfg.JWTAccessExpiresIn = cfg.JWTExpiresIn * 60
+	}
+	if cfg.JWTAccessExpiresIn == 0 {
+		cfg.JWTAccessExpiresIn = 15
+	}
+	if cfg.JWTRefreshExpiresIn == 0 {
+		cfg.JWTRefreshExpiresIn = 24 * 7
+	}
+	if cfg.MailDriver == "" {
+		cfg.MailDriver = "file"
+	}
+	if cfg.MailFrom == "" {
+		cfg.MailFrom = "no-reply@localhost"
+	}
+	if cfg.MailDir == "" {
+		cfg.MailDir = "mail"
+	}
+	if cfg.SMTPPort == "" {
+		cfg.SMTPPort = "587"
+	}
+	if cfg.EmailVerificationExpiresIn == 0 {
+		cfg.EmailVerificationExpiresIn = 24
+	}
+	if cfg.EmailVerificationURL == "" {
+		cfg.EmailVerificationURL = "http://localhost:8000/users/verify"
+	}
+	if cfg.PasswordResetExpiresIn == 0 {
+		cfg.PasswordResetExpiresIn = 30
+	}
+	if cfg.PasswordResetURL == "" {
+		cfg.PasswordResetURL = "http://localhost:8000/users/password/reset"
+	}
+	if cfg.MFAIssuer == "" {
+		cfg.MFAIssuer = "Go Expert API"
+	}
+	if cfg.MFAChallengeExpiresIn == 0 {
+		cfg.MFAChallengeExpiresIn = 5
+	}
+	if cfg.LoginMaxAttempts == 0 {
+		cfg.LoginMaxAttempts = 5
+	}
+	if cfg.LoginMaxAttemptsPerIP == 0 {
+		cfg.LoginMaxAttemptsPerIP = 20
+	}
+	if cfg.LoginAttemptWindow == 0 {
+		cfg.LoginAttemptWindow = 15
+	}
+	if cfg.LoginLockoutBase == 0 {
+		cfg.LoginLockoutBase = 30
+	}
+	if cfg.LoginLockoutMax == 0 {
+		cfg.LoginLockoutMax = 60
+	}
+	if cfg.PasswordMinLength == 0 {
+		cfg.PasswordMinLength = 8
+	}
+	if cfg.PasswordHashAlgorithm == "" {
+		cfg.PasswordHashAlgorithm = "argon2id"
+	}
+	if cfg.PasswordBcryptCost == 0 {
+		cfg.PasswordBcryptCost = 10
+	}
+	if cfg.Argon2Memory == 0 {
+		cfg.Argon2Memory = 64 * 1024
+	}
+	if cfg.Argon2Iterations == 0 {
+		cfg.Argon2Iterations = 3
+	}
+	if cfg.Argon2Parallelism == 0 {
+		cfg.Argon2Parallelism = 2
+	}
+	if cfg.DefaultTenant == "" {
+		cfg.DefaultTenant = entity.DefaultTenantSlug
+	}
+	cfg.TokenAuthKey, err = loadTokenAuthKey(cfg)
+	if err != nil {
+		panic(err)
+	}
 	return cfg, nil
 }
+
+// loadTokenAuthKey usa HS256 com o JWT_SECRET ou, se houver uma chave privada, RS256/ES256
+func loadTokenAuthKey(cfg *conf) (*jwtkeys.KeySet, error) {
+	if cfg.JWTPrivateKeyFile == "" {
+		if cfg.JWTSecret == "" {
+			return nil, fmt.Errorf("JWT_SECRET is required when JWT_PRIVATE_KEY_FILE is not set")
+		}
+		return jwtkeys.NewKeySet(jwtkeys.NewHMACKey([]byte(cfg.JWTSecret)))
+	}
+
+	signing, err := readKeyFile(cfg.JWTPrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	var verification []*jwtkeys.Key
+	for _, path := range strings.Split(cfg.JWTPublicKeyFiles, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		key, err := readKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+		verification = append(verification, key)
+	}
+	return jwtkeys.NewKeySet(signing, verification...)
+}
+
+// NewMailer cria o Mailer de acordo com o MAIL_DRIVER
+func (c *conf) NewMailer() (mail.Mailer, error) {
+	switch c.MailDriver {
+	case "smtp":
+		if c.SMTPHost == "" {
+			return nil, fmt.Errorf("SMTP_HOST is required when MAIL_DRIVER is smtp")
+		}
+		return mail.NewSMTPMailer(c.SMTPHost, c.SMTPPort, c.SMTPUsername, c.SMTPPassword, c.MailFrom), nil
+	case "file":
+		return mail.NewFileMailer(c.MailDir, c.MailFrom), nil
+	case "memory":
+		return mail.NewMemoryMailer(), nil
+	}
+	return nil, fmt.Errorf("unsupported MAIL_DRIVER %q", c.MailDriver)
+}
+
+// NewPasswordPolicy cria a política de senhas, carregando a lista de senhas vazadas se configurada
+func (c *conf) NewPasswordPolicy() (*password.Policy, error) {
+	policy := password.DefaultPolicy()
+	policy.MinLength = c.PasswordMinLength
+	policy.RequireUpper = c.PasswordRequireUpper
+	policy.RequireLower = c.PasswordRequireLower
+	policy.RequireDigit = c.PasswordRequireDigit
+	policy.RequireSymbol = c.PasswordRequireSymbol
+	if c.PasswordBreachedListFile != "" {
+		if err := policy.LoadBreachedList(c.PasswordBreachedListFile); err != nil {
+			return nil, err
+		}
+	}
+	return policy, nil
+}
+
+// NewPasswordHasher mantém o outro algoritmo só para validar os hashes existentes
+func (c *conf) NewPasswordHasher() (*password.Manager, error) {
+	bcryptHasher := password.NewBcryptHasher(c.PasswordBcryptCost)
+	argon2Hasher := password.NewArgon2idHasher(uint32(c.Argon2Memory), uint32(c.Argon2Iterations), uint8(c.Argon2Parallelism))
+	switch c.PasswordHashAlgorithm {
+	case "argon2id":
+		return password.NewManager(argon2Hasher, bcryptHasher), nil
+	case "bcrypt":
+		return password.NewManager(bcryptHasher, argon2Hasher), nil
+	}
+	return nil, fmt.Errorf("unsupported PASSWORD_HASH_ALGORITHM %q", c.PasswordHashAlgorithm)
+}
+
+func readKeyFile(path string) (*jwtkeys.Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := jwtkeys.ParsePEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
@@ -1,10 +1,30 @@
 package dto
 
+import (
+	"time"
+
+	"github.com/gsouza97/go-expert-api/internal/entity"
+)
+
 type CreateProductInput struct {
 	Name  string  `json:"name"`
 	Price float64 `json:"price"`
 }
 
+type ProductListOutput struct {
+	Items      []*entity.Product `json:"items"`
+	Page       int               `json:"page"`
+	Limit      int               `json:"limit"`
+	Total      int64             `json:"total"`
+	TotalPages int               `json:"total_pages"`
+}
+
+type ProductCursorOutput struct {
+	Items      []*entity.Product `json:"items"`
+	Limit      int               `json:"limit"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
 type CreateUserInput struct {
 	Name     string `json:"name"`
 	Email    string `json:"email"`
@@ -17,5 +37,151 @@ type GetJWTInput struct {
 }
 
 type GetJWTOutput struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// MFAChallengeOutput é retornado pelo getToken quando o usuário tem autenticação em dois fatores
+type MFAChallengeOutput struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	ExpiresIn   int64  `json:"expires_in"` // Em segundos
+}
+
+type MFALoginInput struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"` // Código TOTP ou código de recuperação
+}
+
+type TOTPEnrollmentOutput struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"` // otpauth:// para gerar o QR code
+}
+
+type TOTPCodeInput struct {
+	Code string `json:"code"`
+}
+
+type DisableTOTPInput struct {
+	Code            string `json:"code"` // Código TOTP ou código de recuperação
+	CurrentPassword string `json:"current_password"`
+}
+
+type RecoveryCodesOutput struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type RefreshTokenInput struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type LogoutInput struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type VerifyEmailInput struct {
+	Token string `json:"token"`
+}
+
+type ResendVerificationInput struct {
+	Email string `json:"email"`
+}
+
+type ForgotPasswordInput struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordInput struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// UpdateUserInput altera apenas os campos informados. A senha atual é exigida para trocar o email.
+type UpdateUserInput struct {
+	Name            *string `json:"name"`
+	Email           *string `json:"email"`
+	CurrentPassword string  `json:"current_password"`
+}
+
+type ChangePasswordInput struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type DeleteUserInput struct {
+	Password string `json:"password"`
+}
+
+type UserListOutput struct {
+	Items      []*entity.User `json:"items"`
+	Page       int            `json:"page"`
+	Limit      int            `json:"limit"`
+	Total      int64          `json:"total"`
+	TotalPages int            `json:"total_pages"`
+}
+
+type UpdateRoleInput struct {
+	Role string `json:"role"`
+}
+
+type CreateAPIKeyInput struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`     // Vazio usa todas as permissões da role do usuário
+	ExpiresAt *time.Time `json:"expires_at"` // Vazio não expira
+}
+
+type APIKeyOutput struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// CreateAPIKeyOutput é a única resposta que contém a chave completa
+type CreateAPIKeyOutput struct {
+	APIKeyOutput
+	Key string `json:"key"`
+}
+
+// OAuthTokenOutput segue a resposta de sucesso da RFC 6749, sem refresh token
+type OAuthTokenOutput struct {
 	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+// OAuthErrorOutput segue a resposta de erro da RFC 6749, seção 5.2
+type OAuthErrorOutput struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+type CreateOAuthClientInput struct {
+	Name   string   `json:"name"`
+	Grants []string `json:"grants"`
+	Scopes []string `json:"scopes"`
+	UserID string   `json:"user_id"` // Conta de serviço do client_credentials
+}
+
+type OAuthClientOutput struct {
+	ClientID  string     `json:"client_id"`
+	Name      string     `json:"name"`
+	Grants    []string   `json:"grants"`
+	Scopes    []string   `json:"scopes"`
+	UserID    string     `json:"user_id,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// CreateOAuthClientOutput é a única resposta que contém o secret do cliente
+type CreateOAuthClientOutput struct {
+	OAuthClientOutput
+	ClientSecret string `json:"client_secret"`
 }
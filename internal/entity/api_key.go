@@ -0,0 +1,104 @@
+package entity
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gsouza97/go-expert-api/pkg/entity"
+)
+
+// apiKeyPrefix identifica as chaves da API em logs e ferramentas de detecção de segredos
+const apiKeyPrefix = "gea"
+
+var (
+	ErrInvalidScope  = errors.New("scope is invalid")
+	ErrInvalidExpiry = errors.New("expiration must be in the future")
+	ErrInvalidAPIKey = errors.New("api key is invalid")
+)
+
+// APIKey é uma credencial de integrações. O Prefix localiza o registro e só o hash da chave é persistido.
+type APIKey struct {
+	ID         entity.ID  `json:"id"`
+	UserID     entity.ID  `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix" gorm:"uniqueIndex"`
+	KeyHash    string     `json:"-"`
+	Scopes     string     `json:"-"` // Separadas por espaço, vazio usa as permissões da role
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// NewAPIKey retorna a chave a ser persistida e o valor em texto puro no formato gea_<prefix>_<secret>
+func NewAPIKey(userID entity.ID, name string, scopes []Permission, expiresAt *time.Time) (*APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", ErrRequiredName
+	}
+	for _, scope := range scopes {
+		if !IsValidPermission(scope) {
+			return nil, "", ErrInvalidScope
+		}
+	}
+	now := time.Now()
+	if expiresAt != nil && !expiresAt.After(now) {
+		return nil, "", ErrInvalidExpiry
+	}
+
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", err
+	}
+	prefix := hex.EncodeToString(b)
+	secret, err := NewOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+	plain := apiKeyPrefix + "_" + prefix + "_" + secret
+
+	values := make([]string, len(scopes))
+	for i, scope := range scopes {
+		values[i] = string(scope)
+	}
+	return &APIKey{
+		ID:        entity.NewId(),
+		UserID:    userID,
+		Name:      name,
+		Prefix:    prefix,
+		KeyHash:   HashToken(plain),
+		Scopes:    strings.Join(values, " "),
+		ExpiresAt: expiresAt,
+		CreatedAt: now,
+	}, plain, nil
+}
+
+// ParseAPIKeyPrefix extrai o prefixo usado para localizar a chave
+func ParseAPIKeyPrefix(plain string) (string, error) {
+	parts := strings.SplitN(plain, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyPrefix || parts[1] == "" || parts[2] == "" {
+		return "", ErrInvalidAPIKey
+	}
+	return parts[1], nil
+}
+
+// Matches compara o hash da chave informada em tempo constante
+func (k *APIKey) Matches(plain string) bool {
+	return subtle.ConstantTimeCompare([]byte(k.KeyHash), []byte(HashToken(plain))) == 1
+}
+
+func (k *APIKey) IsExpired() bool {
+	return k.ExpiresAt != nil && time.Now().After(*k.ExpiresAt)
+}
+
+func (k *APIKey) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
+
+func (k *APIKey) ScopeList() []string {
+	return strings.Fields(k.Scopes)
+}
@@ -0,0 +1,48 @@
+package entity
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gsouza97/go-expert-api/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAPIKey(t *testing.T) {
+	key, plain, err := NewAPIKey(entity.NewId(), " CI ", []Permission{PermissionProductsRead}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "CI", key.Name)
+	assert.True(t, strings.HasPrefix(plain, "gea_"+key.Prefix+"_"))
+	assert.NotContains(t, key.KeyHash, plain)
+	assert.True(t, key.Matches(plain))
+	assert.False(t, key.Matches(plain+"x"))
+	assert.Equal(t, []string{"products:read"}, key.ScopeList())
+	assert.False(t, key.IsExpired())
+	assert.False(t, key.IsRevoked())
+
+	prefix, err := ParseAPIKeyPrefix(plain)
+	assert.Nil(t, err)
+	assert.Equal(t, key.Prefix, prefix)
+	for _, invalid := range []string{"", "gea_", "gea__secret", "abc_123_secret", "gea_123"} {
+		_, err := ParseAPIKeyPrefix(invalid)
+		assert.Equal(t, ErrInvalidAPIKey, err, invalid)
+	}
+}
+
+func TestNewAPIKey_Invalid(t *testing.T) {
+	_, _, err := NewAPIKey(entity.NewId(), " ", nil, nil)
+	assert.Equal(t, ErrRequiredName, err)
+	_, _, err = NewAPIKey(entity.NewId(), "CI", []Permission{"products:delete"}, nil)
+	assert.Equal(t, ErrInvalidScope, err)
+	past := time.Now().Add(-time.Minute)
+	_, _, err = NewAPIKey(entity.NewId(), "CI", nil, &past)
+	assert.Equal(t, ErrInvalidExpiry, err)
+
+	future := time.Now().Add(time.Hour)
+	key, _, err := NewAPIKey(entity.NewId(), "CI", nil, &future)
+	assert.Nil(t, err)
+	assert.Empty(t, key.ScopeList())
+	key.ExpiresAt = &past
+	assert.True(t, key.IsExpired())
+}
@@ -0,0 +1,63 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/gsouza97/go-expert-api/pkg/entity"
+)
+
+// Prefixos das chaves de LoginThrottle: as tentativas são contadas por conta e por IP de origem
+const (
+	ThrottleKeyEmail = "email:"
+	ThrottleKeyIP    = "ip:"
+)
+
+// LoginThrottle acumula as falhas de login de uma chave (conta ou IP) dentro da janela configurada
+type LoginThrottle struct {
+	Key           string     `json:"key" gorm:"column:throttle_key;primaryKey;size:300"` // "key" é reservado no MySQL
+	Failures      int        `json:"failures"`
+	LastFailureAt time.Time  `json:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until"`
+}
+
+func EmailThrottleKey(email string) string {
+	return ThrottleKeyEmail + NormalizeEmail(email)
+}
+
+func IPThrottleKey(ip string) string {
+	return ThrottleKeyIP + ip
+}
+
+func (t *LoginThrottle) IsLocked(now time.Time) bool {
+	return t.LockedUntil != nil && now.Before(*t.LockedUntil)
+}
+
+// RetryAfter é o tempo restante de bloqueio, zero se a chave não estiver bloqueada
+func (t *LoginThrottle) RetryAfter(now time.Time) time.Duration {
+	if !t.IsLocked(now) {
+		return 0
+	}
+	return t.LockedUntil.Sub(now)
+}
+
+// LockoutEvent registra cada bloqueio aplicado, para auditoria e desbloqueio pelos administradores
+type LockoutEvent struct {
+	ID          entity.ID  `json:"id"`
+	Key         string     `json:"key" gorm:"column:throttle_key;size:300;index"`
+	TenantID    *entity.ID `json:"tenant_id,omitempty" gorm:"size:36;index:idx_lockout_events_tenant_id"` // Tenant da conta cuja tentativa causou o bloqueio
+	Failures    int        `json:"failures"`
+	LockedUntil time.Time  `json:"locked_until"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UnlockedAt  *time.Time `json:"unlocked_at,omitempty"`
+}
+
+func NewLockoutEvent(key string, tenantID *entity.ID, failures int, lockedUntil time.Time) *LockoutEvent {
+	return &LockoutEvent{
+		ID:          entity.NewId(),
+		Key:         key,
+		TenantID:    tenantID,
+		Failures:    failures,
+		LockedUntil: lockedUntil,
+		CreatedAt:   time.Now(),
+	}
+}
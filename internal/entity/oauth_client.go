@@ -0,0 +1,127 @@
+package entity
+
+import (
+	"crypto/subtle"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gsouza97/go-expert-api/pkg/entity"
+)
+
+// Grants OAuth2 suportados pelo endpoint /oauth/token
+const (
+	GrantClientCredentials = "client_credentials"
+	GrantPassword          = "password"
+)
+
+var (
+	ErrInvalidGrant        = errors.New("grant type is invalid")
+	ErrRequiredScope       = errors.New("at least one scope is required")
+	ErrRequiredServiceUser = errors.New("user_id is required for the client_credentials grant")
+)
+
+// OAuthClient é um sistema parceiro do endpoint OAuth2. Só o hash do secret é persistido.
+type OAuthClient struct {
+	ID         entity.ID  `json:"client_id"`
+	TenantID   entity.ID  `json:"tenant_id" gorm:"size:36;index:idx_oauth_clients_tenant_id"`
+	Name       string     `json:"name"`
+	SecretHash string     `json:"-"`
+	Grants     string     `json:"-"`                 // Grants permitidos separados por espaço
+	Scopes     string     `json:"-"`                 // Permissões que o cliente pode pedir, separadas por espaço
+	UserID     *entity.ID `json:"user_id,omitempty"` // Conta de serviço do client_credentials
+	CreatedAt  time.Time  `json:"created_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// TableName evita o nome o_auth_clients gerado pelo GORM
+func (OAuthClient) TableName() string {
+	return "oauth_clients"
+}
+
+// NewOAuthClient retorna o cliente a ser persistido e o secret em texto puro
+func NewOAuthClient(name string, grants []string, scopes []Permission, userID *entity.ID) (*OAuthClient, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", ErrRequiredName
+	}
+	if len(grants) == 0 {
+		return nil, "", ErrInvalidGrant
+	}
+	for _, grant := range grants {
+		if grant != GrantClientCredentials && grant != GrantPassword {
+			return nil, "", ErrInvalidGrant
+		}
+		if grant == GrantClientCredentials && userID == nil {
+			return nil, "", ErrRequiredServiceUser
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, "", ErrRequiredScope
+	}
+	values := make([]string, len(scopes))
+	for i, scope := range scopes {
+		if !IsValidPermission(scope) {
+			return nil, "", ErrInvalidScope
+		}
+		values[i] = string(scope)
+	}
+
+	secret, err := NewOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+	return &OAuthClient{
+		ID:         entity.NewId(),
+		Name:       name,
+		SecretHash: HashToken(secret),
+		Grants:     strings.Join(grants, " "),
+		Scopes:     strings.Join(values, " "),
+		UserID:     userID,
+		CreatedAt:  time.Now(),
+	}, secret, nil
+}
+
+// Matches compara o hash do secret informado em tempo constante
+func (c *OAuthClient) Matches(secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(c.SecretHash), []byte(HashToken(secret))) == 1
+}
+
+func (c *OAuthClient) IsRevoked() bool {
+	return c.RevokedAt != nil
+}
+
+func (c *OAuthClient) AllowsGrant(grant string) bool {
+	for _, g := range strings.Fields(c.Grants) {
+		if g == grant {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *OAuthClient) GrantList() []string {
+	return strings.Fields(c.Grants)
+}
+
+func (c *OAuthClient) ScopeList() []string {
+	return strings.Fields(c.Scopes)
+}
+
+// ResolveScope retorna todas as permissões do cliente quando o scope é vazio
+func (c *OAuthClient) ResolveScope(requested string) (string, error) {
+	fields := strings.Fields(requested)
+	if len(fields) == 0 {
+		return c.Scopes, nil
+	}
+	allowed := map[string]bool{}
+	for _, scope := range c.ScopeList() {
+		allowed[scope] = true
+	}
+	for _, scope := range fields {
+		if !allowed[scope] {
+			return "", ErrInvalidScope
+		}
+	}
+	return strings.Join(fields, " "), nil
+}
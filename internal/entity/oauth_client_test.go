@@ -0,0 +1,44 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/gsouza97/go-expert-api/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewOAuthClient(t *testing.T) {
+	userID := entity.NewId()
+	client, secret, err := NewOAuthClient("Partner", []string{GrantClientCredentials, GrantPassword}, []Permission{PermissionProductsRead, PermissionProductsWrite}, &userID)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, secret)
+	assert.True(t, client.Matches(secret))
+	assert.False(t, client.Matches(secret+"x"))
+	assert.True(t, client.AllowsGrant(GrantPassword))
+	assert.False(t, client.AllowsGrant("authorization_code"))
+
+	scope, err := client.ResolveScope("")
+	assert.Nil(t, err)
+	assert.Equal(t, "products:read products:write", scope)
+	scope, err = client.ResolveScope(" products:read ")
+	assert.Nil(t, err)
+	assert.Equal(t, "products:read", scope)
+	_, err = client.ResolveScope("products:read users:manage")
+	assert.Equal(t, ErrInvalidScope, err)
+}
+
+func TestNewOAuthClient_Invalid(t *testing.T) {
+	read := []Permission{PermissionProductsRead}
+	_, _, err := NewOAuthClient(" ", []string{GrantPassword}, read, nil)
+	assert.Equal(t, ErrRequiredName, err)
+	_, _, err = NewOAuthClient("Partner", nil, read, nil)
+	assert.Equal(t, ErrInvalidGrant, err)
+	_, _, err = NewOAuthClient("Partner", []string{"implicit"}, read, nil)
+	assert.Equal(t, ErrInvalidGrant, err)
+	_, _, err = NewOAuthClient("Partner", []string{GrantClientCredentials}, read, nil)
+	assert.Equal(t, ErrRequiredServiceUser, err)
+	_, _, err = NewOAuthClient("Partner", []string{GrantPassword}, nil, nil)
+	assert.Equal(t, ErrRequiredScope, err)
+	_, _, err = NewOAuthClient("Partner", []string{GrantPassword}, []Permission{"products:delete"}, nil)
+	assert.Equal(t, ErrInvalidScope, err)
+}
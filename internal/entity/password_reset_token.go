@@ -0,0 +1,41 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/gsouza97/go-expert-api/pkg/entity"
+)
+
+// PasswordResetToken é persistido apenas com o hash do valor enviado por email e pode ser usado uma única vez
+type PasswordResetToken struct {
+	ID        entity.ID  `json:"id"`
+	UserID    entity.ID  `json:"user_id"`
+	TokenHash string     `json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UsedAt    *time.Time `json:"used_at"`
+}
+
+// NewPasswordResetToken retorna o token a ser persistido e o valor em texto puro que deve ser enviado ao usuário
+func NewPasswordResetToken(userID entity.ID, expiresIn time.Duration) (*PasswordResetToken, string, error) {
+	plain, err := NewOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+	now := time.Now()
+	return &PasswordResetToken{
+		ID:        entity.NewId(),
+		UserID:    userID,
+		TokenHash: HashToken(plain),
+		ExpiresAt: now.Add(expiresIn),
+		CreatedAt: now,
+	}, plain, nil
+}
+
+func (t *PasswordResetToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
+
+func (t *PasswordResetToken) IsUsed() bool {
+	return t.UsedAt != nil
+}
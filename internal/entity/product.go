@@ -16,10 +16,15 @@ var (
 )
 
 type Product struct {
-	ID        entity.ID `json:"id"`
-	Name      string    `json:"name"`
-	Price     float64   `json:"price"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        entity.ID  `json:"id"`
+	TenantID  entity.ID  `json:"tenant_id" gorm:"size:36;index:idx_products_tenant_id"`
+	Name      string     `json:"name"`
+	Price     float64    `json:"price"`
+	CreatedAt time.Time  `json:"created_at"`
+	CreatedBy *entity.ID `json:"created_by,omitempty"` // Vazio nos produtos criados antes do registro do dono
+	UpdatedBy *entity.ID `json:"updated_by,omitempty"`
+	Version   int64      `json:"version" gorm:"not null;default:1"`                         // Incrementado a cada alteração, base do ETag
+	DeletedAt *time.Time `json:"deleted_at,omitempty" gorm:"index:idx_products_deleted_at"` // Preenchido enquanto o produto está na lixeira
 }
 
 func NewProduct(name string, price float64) (*Product, error) {
@@ -28,6 +33,7 @@ func NewProduct(name string, price float64) (*Product, error) {
 		Name:      name,
 		Price:     price,
 		CreatedAt: time.Now(),
+		Version:   1,
 	}
 
 	err := p.Validate()
@@ -61,3 +67,8 @@ func (p *Product) Validate() error {
 
 	return nil
 }
+
+// IsOwnedBy indica se o produto foi criado pelo usuário
+func (p *Product) IsOwnedBy(userID entity.ID) bool {
+	return p.CreatedBy != nil && *p.CreatedBy == userID
+}
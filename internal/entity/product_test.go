@@ -48,3 +48,14 @@ func TestProductValidate(t *testing.T) {
 	err := product.Validate()
 	assert.Nil(t, err)
 }
+
+func TestProduct_IsOwnedBy(t *testing.T) {
+	product, err := NewProduct("Product 1", 10.0)
+	assert.Nil(t, err)
+	owner := entity.NewId()
+	assert.False(t, product.IsOwnedBy(owner))
+
+	product.CreatedBy = &owner
+	assert.True(t, product.IsOwnedBy(owner))
+	assert.False(t, product.IsOwnedBy(entity.NewId()))
+}
@@ -0,0 +1,51 @@
+package entity
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"strings"
+	"time"
+
+	"github.com/gsouza97/go-expert-api/pkg/entity"
+)
+
+// RecoveryCodeCount é a quantidade de códigos gerados a cada ativação do TOTP
+const RecoveryCodeCount = 10
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// RecoveryCode substitui um código TOTP quando o usuário perde o autenticador. Cada código vale uma vez.
+type RecoveryCode struct {
+	ID       entity.ID  `json:"id"`
+	UserID   entity.ID  `json:"user_id"`
+	CodeHash string     `json:"-"`
+	UsedAt   *time.Time `json:"used_at"`
+}
+
+// NewRecoveryCodes retorna os códigos a serem persistidos e os valores em texto puro que devem ser mostrados ao usuário
+func NewRecoveryCodes(userID entity.ID, count int) ([]*RecoveryCode, []string, error) {
+	codes := make([]*RecoveryCode, 0, count)
+	plain := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		// 16 caracteres em dois grupos, ex: abcd2efgh-ijkl3mno
+		code := strings.ToLower(recoveryEncoding.EncodeToString(b))
+		code = code[:8] + "-" + code[8:]
+		codes = append(codes, &RecoveryCode{
+			ID:       entity.NewId(),
+			UserID:   userID,
+			CodeHash: HashRecoveryCode(code),
+		})
+		plain = append(plain, code)
+	}
+	return codes, plain, nil
+}
+
+// HashRecoveryCode ignora maiúsculas, espaços e hífens digitados pelo usuário
+func HashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(code))
+	return HashToken(code)
+}
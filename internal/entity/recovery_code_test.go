@@ -0,0 +1,30 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/gsouza97/go-expert-api/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRecoveryCodes(t *testing.T) {
+	userID := entity.NewId()
+	codes, plain, err := NewRecoveryCodes(userID, RecoveryCodeCount)
+	assert.Nil(t, err)
+	assert.Len(t, codes, RecoveryCodeCount)
+	assert.Len(t, plain, RecoveryCodeCount)
+
+	seen := map[string]bool{}
+	for i, code := range codes {
+		assert.Equal(t, userID, code.UserID)
+		assert.Len(t, plain[i], 17)
+		assert.Equal(t, HashRecoveryCode(plain[i]), code.CodeHash)
+		assert.NotContains(t, seen, code.CodeHash)
+		seen[code.CodeHash] = true
+	}
+}
+
+func TestHashRecoveryCode_IgnoresFormatting(t *testing.T) {
+	assert.Equal(t, HashRecoveryCode("abcdefgh-ijklmnop"), HashRecoveryCode(" ABCDEFGH IJKLMNOP "))
+	assert.NotEqual(t, HashRecoveryCode("abcdefgh-ijklmnop"), HashRecoveryCode("abcdefgh-ijklmnoq"))
+}
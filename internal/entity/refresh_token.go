@@ -0,0 +1,68 @@
+package entity
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/gsouza97/go-expert-api/pkg/entity"
+)
+
+// RefreshToken guarda só o hash; os tokens de um mesmo login compartilham o FamilyID
+type RefreshToken struct {
+	ID        entity.ID  `json:"id"`
+	UserID    entity.ID  `json:"user_id"`
+	FamilyID  entity.ID  `json:"family_id"`
+	TokenHash string     `json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
+
+// NewRefreshToken retorna o token a ser persistido e o valor em texto puro que deve ser entregue ao cliente
+func NewRefreshToken(userID, familyID entity.ID, expiresIn time.Duration) (*RefreshToken, string, error) {
+	plain, err := NewOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+	now := time.Now()
+	return &RefreshToken{
+		ID:        entity.NewId(),
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: HashToken(plain),
+		ExpiresAt: now.Add(expiresIn),
+		CreatedAt: now,
+	}, plain, nil
+}
+
+func (t *RefreshToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
+
+func (t *RefreshToken) IsRevoked() bool {
+	return t.RevokedAt != nil
+}
+
+// NewOpaqueToken gera um valor aleatório de 256 bits codificado em base64 url-safe
+func NewOpaqueToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken retorna o sha256 do token, usado para busca e armazenamento
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RevokedToken é uma entrada da denylist de access tokens, identificados pelo claim jti
+type RevokedToken struct {
+	JTI       string    `json:"jti" gorm:"primaryKey"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,33 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gsouza97/go-expert-api/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRefreshToken(t *testing.T) {
+	userID := entity.NewId()
+	familyID := entity.NewId()
+	token, plain, err := NewRefreshToken(userID, familyID, time.Hour)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, plain)
+	assert.Equal(t, userID, token.UserID)
+	assert.Equal(t, familyID, token.FamilyID)
+	assert.Equal(t, HashToken(plain), token.TokenHash)
+	assert.NotEqual(t, plain, token.TokenHash)
+	assert.False(t, token.IsExpired())
+	assert.False(t, token.IsRevoked())
+
+	_, other, err := NewRefreshToken(userID, familyID, time.Hour)
+	assert.Nil(t, err)
+	assert.NotEqual(t, plain, other)
+}
+
+func TestRefreshToken_IsExpired(t *testing.T) {
+	token, _, err := NewRefreshToken(entity.NewId(), entity.NewId(), -time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, token.IsExpired())
+}
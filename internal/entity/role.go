@@ -0,0 +1,76 @@
+package entity
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+var ErrInvalidRole = errors.New("role is invalid")
+
+type Permission string
+
+const (
+	PermissionProductsRead   Permission = "products:read"
+	PermissionProductsWrite  Permission = "products:write"
+	PermissionProductsManage Permission = "products:manage" // Alterar e remover produtos de outros usuários
+	PermissionUsersManage    Permission = "users:manage"
+)
+
+// rolePermissions define o que cada role pode fazer
+var rolePermissions = map[string][]Permission{
+	RoleAdmin:  {PermissionProductsRead, PermissionProductsWrite, PermissionProductsManage, PermissionUsersManage},
+	RoleEditor: {PermissionProductsRead, PermissionProductsWrite},
+	RoleViewer: {PermissionProductsRead},
+}
+
+func IsValidPermission(permission Permission) bool {
+	for _, permissions := range rolePermissions {
+		for _, p := range permissions {
+			if p == permission {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// RoleHasPermission verifica se a role possui a permissão informada
+func RoleHasPermission(role string, permission Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// RoleIncludes verifica se a role possui todas as permissões da outra
+func RoleIncludes(role, other string) bool {
+	for _, p := range rolePermissions[other] {
+		if !RoleHasPermission(role, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// ScopeHasPermission verifica se o escopo de uma credencial, com as permissões separadas por espaço, inclui a permissão
+func ScopeHasPermission(scope string, permission Permission) bool {
+	for _, s := range strings.Fields(scope) {
+		if s == string(permission) {
+			return true
+		}
+	}
+	return false
+}
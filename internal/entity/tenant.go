@@ -0,0 +1,42 @@
+package entity
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gsouza97/go-expert-api/pkg/entity"
+)
+
+// DefaultTenantSlug é o tenant criado pela migração para os dados existentes antes da separação por tenant
+const DefaultTenantSlug = "default"
+
+var ErrInvalidSlug = errors.New("slug must have 2 to 50 lowercase letters, digits or hyphens")
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,48}[a-z0-9]$`)
+
+// Tenant é uma organização; seus usuários e produtos não são visíveis para as outras
+type Tenant struct {
+	ID        entity.ID `json:"id"`
+	Name      string    `json:"name"`
+	Slug      string    `json:"slug" gorm:"size:50;not null;uniqueIndex:idx_tenants_slug"` // Identificador usado no cadastro de usuários
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewTenant(name, slug string) (*Tenant, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ErrRequiredName
+	}
+	slug = strings.ToLower(strings.TrimSpace(slug))
+	if !slugPattern.MatchString(slug) {
+		return nil, ErrInvalidSlug
+	}
+	return &Tenant{
+		ID:        entity.NewId(),
+		Name:      name,
+		Slug:      slug,
+		CreatedAt: time.Now(),
+	}, nil
+}
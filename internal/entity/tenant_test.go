@@ -0,0 +1,22 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTenant(t *testing.T) {
+	tenant, err := NewTenant(" Retail ", "Retail-BR")
+	assert.Nil(t, err)
+	assert.Equal(t, "Retail", tenant.Name)
+	assert.Equal(t, "retail-br", tenant.Slug)
+	assert.NotEmpty(t, tenant.ID.String())
+
+	_, err = NewTenant("", "retail")
+	assert.Equal(t, ErrRequiredName, err)
+	for _, slug := range []string{"", "r", "-retail", "retail-", "retail br", "retail_br"} {
+		_, err = NewTenant("Retail", slug)
+		assert.Equal(t, ErrInvalidSlug, err, slug)
+	}
+}
@@ -1,19 +1,69 @@
 package entity
 
 import (
+	"errors"
+	"net/mail"
+	"strings"
+	"time"
+
 	"github.com/gsouza97/go-expert-api/pkg/entity"
+	"github.com/gsouza97/go-expert-api/pkg/password"
 	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	ErrRequiredEmail = errors.New("email is required")
+	ErrInvalidEmail  = errors.New("email is invalid")
+)
+
+// Configurados no boot da aplicação
+var (
+	passwordPolicy = password.DefaultPolicy()
+	passwordHasher = password.NewManager(password.NewBcryptHasher(bcrypt.DefaultCost))
+)
+
+func SetPasswordPolicy(policy *password.Policy) {
+	passwordPolicy = policy
+}
+
+func SetPasswordHasher(hasher *password.Manager) {
+	passwordHasher = hasher
+}
+
+// CheckPasswordPolicy valida a senha sem gerar o hash
+func CheckPasswordPolicy(plain string) error {
+	return passwordPolicy.Validate(plain)
+}
+
 type User struct {
 	ID       entity.ID `json:"id"`
+	TenantID entity.ID `json:"tenant_id" gorm:"size:36;index:idx_users_tenant_id"`
 	Name     string    `json:"name"`
-	Email    string    `json:"email"`
+	Email    string    `json:"email" gorm:"size:255;not null;uniqueIndex:idx_users_email"` // Sempre normalizado, ver NormalizeEmail
 	Password string    `json:"-"`
+	Role     string    `json:"role"`
+	// Nulo até o email ser confirmado
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	// Segredo TOTP em base32, pendente até o primeiro código confirmado
+	TOTPSecret  string `json:"-"`
+	TOTPEnabled bool   `json:"totp_enabled"`
+	// Último período TOTP aceito, impede reuso do código
+	TOTPLastStep int64 `json:"-"`
+	// Preenchido quando a conta é desativada
+	DisabledAt *time.Time `json:"disabled_at,omitempty"`
+	// Claim sv dos access tokens, incrementar invalida os já emitidos
+	SessionVersion int64 `json:"-"`
 }
 
 func NewUser(name, email, password string) (*User, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	email, err := ValidateEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckPasswordPolicy(password); err != nil {
+		return nil, err
+	}
+	hash, err := passwordHasher.Hash(password)
 	if err != nil {
 		return nil, err
 	}
@@ -21,11 +71,105 @@ func NewUser(name, email, password string) (*User, error) {
 		ID:       entity.NewId(),
 		Name:     name,
 		Email:    email,
-		Password: string(hash),
+		Password: hash,
+		Role:     RoleViewer,
 	}, nil
 }
 
-func (u *User) ValidatePassword(password string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
-	return err == nil
+// SetPassword valida a nova senha contra a política e substitui o hash
+func (u *User) SetPassword(plain string) error {
+	if err := CheckPasswordPolicy(plain); err != nil {
+		return err
+	}
+	return u.RehashPassword(plain)
+}
+
+// RehashPassword não aplica a política, para atualizar no login as senhas anteriores a ela
+func (u *User) RehashPassword(plain string) error {
+	hash, err := passwordHasher.Hash(plain)
+	if err != nil {
+		return err
+	}
+	u.Password = hash
+	return nil
+}
+
+func (u *User) ValidatePassword(plain string) bool {
+	return passwordHasher.Verify(u.Password, plain)
+}
+
+// PasswordNeedsRehash indica que o hash foi gerado por outro algoritmo ou com parâmetros antigos
+func (u *User) PasswordNeedsRehash() bool {
+	return passwordHasher.NeedsRehash(u.Password)
+}
+
+// ChangeEmail troca o email. O novo endereço precisa ser verificado novamente.
+func (u *User) ChangeEmail(email string) error {
+	email, err := ValidateEmail(email)
+	if err != nil {
+		return err
+	}
+	if email == u.Email {
+		return nil
+	}
+	u.Email = email
+	u.EmailVerifiedAt = nil
+	return nil
+}
+
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+func (u *User) IsMFAEnabled() bool {
+	return u.TOTPEnabled
+}
+
+func (u *User) IsDisabled() bool {
+	return u.DisabledAt != nil
+}
+
+func (u *User) Disable(at time.Time) {
+	if u.DisabledAt == nil {
+		u.DisabledAt = &at
+	}
+}
+
+func (u *User) Enable() {
+	u.DisabledAt = nil
+}
+
+func (u *User) SetRole(role string) error {
+	if !IsValidRole(role) {
+		return ErrInvalidRole
+	}
+	u.Role = role
+	return nil
+}
+
+func (u *User) HasPermission(permission Permission) bool {
+	return RoleHasPermission(u.Role, permission)
+}
+
+// NormalizeEmail remove espaços e converte para minúsculas
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// ValidateEmail retorna o email normalizado, ou erro se ele não for um endereço simples (sem nome de exibição)
+func ValidateEmail(email string) (string, error) {
+	email = NormalizeEmail(email)
+	if email == "" {
+		return "", ErrRequiredEmail
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || addr.Name != "" {
+		return "", ErrInvalidEmail
+	}
+	// ParseAddress aceita domínios sem ponto (ex: user@localhost), que não recebem email pela internet
+	domain := email[strings.LastIndex(email, "@")+1:]
+	if !strings.Contains(domain, ".") || strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") {
+		return "", ErrInvalidEmail
+	}
+	return email, nil
 }
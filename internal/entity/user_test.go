@@ -3,24 +3,114 @@ package entity
 // Import testify
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func TestNewUser(t *testing.T) {
-	user, err := NewUser("John Doe", "johndoe@test.com", "123456")
+	user, err := NewUser("John Doe", "johndoe@test.com", "12345678")
 	assert.Nil(t, err)
 	assert.NotEmpty(t, user.ID)
 	assert.NotNil(t, user)
 	assert.Equal(t, "John Doe", user.Name)
 	assert.NotEmpty(t, user.Password)
 	assert.Equal(t, "johndoe@test.com", user.Email)
+	assert.Equal(t, RoleViewer, user.Role)
+	assert.False(t, user.IsEmailVerified())
+}
+
+func TestNewUser_NormalizesEmail(t *testing.T) {
+	user, err := NewUser("John Doe", "  JohnDoe@Test.COM ", "12345678")
+	assert.Nil(t, err)
+	assert.Equal(t, "johndoe@test.com", user.Email)
+}
+
+func TestNewUser_InvalidEmail(t *testing.T) {
+	_, err := NewUser("John Doe", "   ", "12345678")
+	assert.Equal(t, ErrRequiredEmail, err)
+
+	for _, email := range []string{"johndoe", "johndoe@", "@test.com", "john doe@test.com", "John <johndoe@test.com>", "johndoe@localhost", "johndoe@test."} {
+		_, err := NewUser("John Doe", email, "12345678")
+		assert.Equal(t, ErrInvalidEmail, err, email)
+	}
 }
 
 func TestUser_ValidatePassword(t *testing.T) {
-	user, err := NewUser("John Doe", "johndoe@test.com", "123456")
+	user, err := NewUser("John Doe", "johndoe@test.com", "12345678")
 	assert.Nil(t, err)
-	assert.True(t, user.ValidatePassword("123456"))
+	assert.True(t, user.ValidatePassword("12345678"))
 	assert.False(t, user.ValidatePassword("1234567"))
-	assert.NotEqual(t, user.Password, "123456")
+	assert.NotEqual(t, user.Password, "12345678")
+}
+
+func TestUser_SetPassword(t *testing.T) {
+	user, err := NewUser("John Doe", "johndoe@test.com", "12345678")
+	assert.Nil(t, err)
+	assert.Nil(t, user.SetPassword("abcdefgh"))
+	assert.True(t, user.ValidatePassword("abcdefgh"))
+	assert.False(t, user.ValidatePassword("12345678"))
+}
+
+func TestUser_ChangeEmail(t *testing.T) {
+	user, err := NewUser("John Doe", "johndoe@test.com", "12345678")
+	assert.Nil(t, err)
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+
+	assert.Nil(t, user.ChangeEmail(" JohnDoe@Test.com"))
+	assert.True(t, user.IsEmailVerified())
+
+	assert.Equal(t, ErrInvalidEmail, user.ChangeEmail("johndoe"))
+	assert.Equal(t, "johndoe@test.com", user.Email)
+
+	assert.Nil(t, user.ChangeEmail("John@Example.com"))
+	assert.Equal(t, "john@example.com", user.Email)
+	assert.False(t, user.IsEmailVerified())
+}
+
+func TestUser_Disable(t *testing.T) {
+	user, err := NewUser("John Doe", "johndoe@test.com", "12345678")
+	assert.Nil(t, err)
+	assert.False(t, user.IsDisabled())
+
+	first := time.Now()
+	user.Disable(first)
+	user.Disable(first.Add(time.Hour))
+	assert.True(t, user.IsDisabled())
+	assert.Equal(t, first, *user.DisabledAt)
+
+	user.Enable()
+	assert.False(t, user.IsDisabled())
+}
+
+func TestUser_SetRole(t *testing.T) {
+	user, err := NewUser("John Doe", "johndoe@test.com", "12345678")
+	assert.Nil(t, err)
+	assert.Nil(t, user.SetRole(RoleEditor))
+	assert.Equal(t, RoleEditor, user.Role)
+	assert.Equal(t, ErrInvalidRole, user.SetRole("superuser"))
+	assert.Equal(t, RoleEditor, user.Role)
+}
+
+func TestUser_HasPermission(t *testing.T) {
+	user, err := NewUser("John Doe", "johndoe@test.com", "12345678")
+	assert.Nil(t, err)
+	assert.True(t, user.HasPermission(PermissionProductsRead))
+	assert.False(t, user.HasPermission(PermissionProductsWrite))
+
+	user.Role = RoleEditor
+	assert.True(t, user.HasPermission(PermissionProductsWrite))
+	assert.False(t, user.HasPermission(PermissionUsersManage))
+
+	user.Role = RoleAdmin
+	assert.True(t, user.HasPermission(PermissionProductsWrite))
+	assert.True(t, user.HasPermission(PermissionUsersManage))
+}
+
+func TestRoleIncludes(t *testing.T) {
+	assert.True(t, RoleIncludes(RoleAdmin, RoleEditor))
+	assert.True(t, RoleIncludes(RoleEditor, RoleEditor))
+	assert.False(t, RoleIncludes(RoleViewer, RoleEditor))
+	assert.False(t, RoleIncludes(RoleEditor, RoleAdmin))
 }
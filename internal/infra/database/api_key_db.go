@@ -0,0 +1,53 @@
+package database
+
+import (
+	"time"
+
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	"gorm.io/gorm"
+)
+
+type APIKeyDB struct {
+	DB *gorm.DB
+}
+
+func NewAPIKeyDB(db *gorm.DB) *APIKeyDB {
+	return &APIKeyDB{DB: db}
+}
+
+func (db *APIKeyDB) Create(key *entity.APIKey) error {
+	return translateError(db.DB.Create(key).Error)
+}
+
+func (db *APIKeyDB) FindByPrefix(prefix string) (*entity.APIKey, error) {
+	var key entity.APIKey
+	err := db.DB.Where("prefix = ?", prefix).First(&key).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &key, nil
+}
+
+// FindByUser lista as chaves do usuário, inclusive as revogadas, das mais recentes para as mais antigas
+func (db *APIKeyDB) FindByUser(userID string) ([]*entity.APIKey, error) {
+	var keys []*entity.APIKey
+	err := db.DB.Where("user_id = ?", userID).Order("created_at desc").Find(&keys).Error
+	return keys, translateError(err)
+}
+
+// Revoke revoga a chave do usuário. Retorna ErrNotFound se ela não existir ou pertencer a outro usuário.
+func (db *APIKeyDB) Revoke(id, userID string) error {
+	var key entity.APIKey
+	err := db.DB.Where("id = ? AND user_id = ?", id, userID).First(&key).Error
+	if err != nil {
+		return translateError(err)
+	}
+	err = db.DB.Model(&entity.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+	return translateError(err)
+}
+
+func (db *APIKeyDB) TouchLastUsed(id string, at time.Time) error {
+	return translateError(db.DB.Model(&entity.APIKey{}).Where("id = ?", id).Update("last_used_at", at).Error)
+}
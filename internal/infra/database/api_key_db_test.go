@@ -0,0 +1,48 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	entityPkg "github.com/gsouza97/go-expert-api/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestAPIKeyDB(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	db.AutoMigrate(&entity.APIKey{})
+	keyDB := NewAPIKeyDB(db)
+	userID := entityPkg.NewId()
+
+	key, plain, err := entity.NewAPIKey(userID, "CI", []entity.Permission{entity.PermissionProductsRead}, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, keyDB.Create(key))
+
+	prefix, err := entity.ParseAPIKeyPrefix(plain)
+	assert.Nil(t, err)
+	found, err := keyDB.FindByPrefix(prefix)
+	assert.Nil(t, err)
+	assert.Equal(t, key.ID, found.ID)
+	assert.True(t, found.Matches(plain))
+	_, err = keyDB.FindByPrefix("unknown")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	now := time.Now()
+	assert.Nil(t, keyDB.TouchLastUsed(key.ID.String(), now))
+
+	// Outro usuário não pode revogar a chave
+	assert.ErrorIs(t, keyDB.Revoke(key.ID.String(), entityPkg.NewId().String()), ErrNotFound)
+	assert.Nil(t, keyDB.Revoke(key.ID.String(), userID.String()))
+
+	keys, err := keyDB.FindByUser(userID.String())
+	assert.Nil(t, err)
+	assert.Len(t, keys, 1)
+	assert.True(t, keys[0].IsRevoked())
+	assert.NotNil(t, keys[0].LastUsedAt)
+}
@@ -0,0 +1,153 @@
+package connection
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+	DriverMySQL    = "mysql"
+
+	defaultSQLiteName = "test.db"
+	defaultSSLMode    = "disable"
+)
+
+var (
+	ErrUnsupportedDriver = errors.New("unsupported database driver")
+	ErrRequiredHost      = errors.New("database host is required")
+	ErrRequiredName      = errors.New("database name is required")
+)
+
+// Config reúne tudo que é necessário para abrir uma conexão com o banco
+type Config struct {
+	Driver          string
+	Host            string
+	Port            string
+	User            string
+	Password        string
+	Name            string
+	SSLMode         string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+// Open cria o *gorm.DB para o driver configurado e aplica as configurações do pool
+func Open(cfg Config) (*gorm.DB, error) {
+	dialector, err := Dialector(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	if cfg.ConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	}
+
+	return db, nil
+}
+
+// Dialector retorna o gorm.Dialector correspondente ao driver configurado
+func Dialector(cfg Config) (gorm.Dialector, error) {
+	dsn, err := DSN(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	switch cfg.Driver {
+	case DriverSQLite, "sqlite3", "":
+		return sqlite.Open(dsn), nil
+	case DriverPostgres, "postgresql":
+		return postgres.Open(dsn), nil
+	case DriverMySQL:
+		return mysql.Open(dsn), nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnsupportedDriver, cfg.Driver)
+}
+
+// DSN monta a string de conexão de cada driver, escapando os valores
+func DSN(cfg Config) (string, error) {
+	switch cfg.Driver {
+	case DriverSQLite, "sqlite3", "":
+		if cfg.Name == "" {
+			return defaultSQLiteName, nil
+		}
+		return cfg.Name, nil
+	case DriverPostgres, "postgresql":
+		if err := validateServerConfig(cfg); err != nil {
+			return "", err
+		}
+		port := cfg.Port
+		if port == "" {
+			port = "5432"
+		}
+		sslMode := cfg.SSLMode
+		if sslMode == "" {
+			sslMode = defaultSSLMode
+		}
+		dsn := url.URL{
+			Scheme:   "postgres",
+			Host:     net.JoinHostPort(cfg.Host, port),
+			Path:     "/" + cfg.Name,
+			RawQuery: url.Values{"sslmode": {sslMode}}.Encode(),
+		}
+		if cfg.User != "" || cfg.Password != "" {
+			dsn.User = url.UserPassword(cfg.User, cfg.Password)
+		}
+		return dsn.String(), nil
+	case DriverMySQL:
+		if err := validateServerConfig(cfg); err != nil {
+			return "", err
+		}
+		port := cfg.Port
+		if port == "" {
+			port = "3306"
+		}
+		mysqlCfg := mysqldriver.NewConfig()
+		mysqlCfg.User = cfg.User
+		mysqlCfg.Passwd = cfg.Password
+		mysqlCfg.Net = "tcp"
+		mysqlCfg.Addr = net.JoinHostPort(cfg.Host, port)
+		mysqlCfg.DBName = cfg.Name
+		mysqlCfg.Params = map[string]string{"charset": "utf8mb4"}
+		mysqlCfg.ParseTime = true
+		mysqlCfg.Loc = time.UTC
+		return mysqlCfg.FormatDSN(), nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnsupportedDriver, cfg.Driver)
+}
+
+func validateServerConfig(cfg Config) error {
+	if cfg.Host == "" {
+		return ErrRequiredHost
+	}
+	if cfg.Name == "" {
+		return ErrRequiredName
+	}
+	return nil
+}
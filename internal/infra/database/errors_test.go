@@ -0,0 +1,52 @@
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestTranslateError(t *testing.T) {
+	assert.Nil(t, translateError(nil))
+	assert.ErrorIs(t, translateError(gorm.ErrRecordNotFound), ErrNotFound)
+	assert.ErrorIs(t, translateError(gorm.ErrRecordNotFound), gorm.ErrRecordNotFound)
+
+	other := errors.New("syntax error")
+	assert.Equal(t, other, translateError(other))
+}
+
+func TestTranslateError_Conflict(t *testing.T) {
+	db, err := ConnectToTestDBAndMigrate()
+	if err != nil {
+		t.Error(err)
+	}
+
+	product, err := entity.NewProduct("Product 1", 10.0)
+	assert.NoError(t, err)
+	productDB := NewProductDB(db)
+	assert.NoError(t, productDB.CreateProduct(product))
+
+	err = productDB.CreateProduct(product)
+	assert.ErrorIs(t, err, ErrConflict)
+}
+
+func TestTranslateError_Unavailable(t *testing.T) {
+	db, err := ConnectToTestDBAndMigrate()
+	if err != nil {
+		t.Error(err)
+	}
+	sqlDB, err := db.DB()
+	assert.NoError(t, err)
+	assert.NoError(t, sqlDB.Close())
+
+	productDB := NewProductDB(db)
+	_, err = productDB.FindByID("any")
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.NotErrorIs(t, err, ErrNotFound)
+
+	_, err = NewUserDB(db).FindByEmail("johndoe@test.com")
+	assert.ErrorIs(t, err, ErrUnavailable)
+}
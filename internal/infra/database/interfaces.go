@@ -1,16 +1,96 @@
 package database
 
-import "github.com/gsouza97/go-expert-api/internal/entity"
+import (
+	"time"
+
+	"github.com/gsouza97/go-expert-api/internal/entity"
+)
 
 type UserDBInterface interface {
 	CreateUser(user *entity.User) error
 	FindByEmail(email string) (*entity.User, error)
+	FindByID(id string) (*entity.User, error)
+	VerifyEmail(id, email string, verifiedAt time.Time) (bool, error)
+	Update(user *entity.User) error
+	UseTOTPStep(id string, step int64) (bool, error)
+	Delete(id string) error
+	FindAll(query UserQuery) ([]*entity.User, error)
+	Count(filter UserFilter) (int64, error)
+	RevokeSessions(id string) error
+	MoveToTenant(id, tenantID string) error
+	ForTenant(tenantID string) UserDBInterface
 }
 
 type ProductDBInterface interface {
 	CreateProduct(product *entity.Product) error
-	FindAll(page int, limit int, sort string) ([]*entity.Product, error)
+	FindAll(query ProductQuery) ([]*entity.Product, error)
+	FindAfter(after *ProductCursor, limit int, filter ProductFilter) ([]*entity.Product, error)
+	Count(filter ProductFilter) (int64, error)
 	FindByID(id string) (*entity.Product, error)
 	Update(product *entity.Product) error
-	Delete(id string) error
+	Delete(id string, version int64) error
+	FindDeletedByID(id string) (*entity.Product, error)
+	Restore(id string, version int64) error
+	Purge(id string) error
+	PurgeDeletedBefore(cutoff time.Time) (int64, error)
+	ForTenant(tenantID string) ProductDBInterface
+}
+
+type TenantDBInterface interface {
+	Create(tenant *entity.Tenant) error
+	FindByID(id string) (*entity.Tenant, error)
+	FindBySlug(slug string) (*entity.Tenant, error)
+	FindAll() ([]*entity.Tenant, error)
+}
+
+type RefreshTokenDBInterface interface {
+	Create(token *entity.RefreshToken) error
+	FindByHash(hash string) (*entity.RefreshToken, error)
+	Revoke(id string) (bool, error)
+	RevokeFamily(familyID string) error
+	RevokeAllByUser(userID string) error
+}
+
+type RevokedTokenDBInterface interface {
+	Revoke(jti string, expiresAt time.Time) error
+	IsRevoked(jti string) (bool, error)
+	DeleteExpired() error
+}
+
+type PasswordResetTokenDBInterface interface {
+	Create(token *entity.PasswordResetToken) error
+	FindByHash(hash string) (*entity.PasswordResetToken, error)
+	MarkUsed(id string) (bool, error)
+	InvalidateByUser(userID string) error
+}
+
+type RecoveryCodeDBInterface interface {
+	Replace(userID string, codes []*entity.RecoveryCode) error
+	Use(userID, hash string) (bool, error)
+	DeleteByUser(userID string) error
+}
+
+type LoginThrottleDBInterface interface {
+	Find(keys ...string) ([]*entity.LoginThrottle, error)
+	RegisterFailure(key string, now, windowStart time.Time) (*entity.LoginThrottle, error)
+	Lock(event *entity.LockoutEvent) error
+	Reset(key string) error
+	Unlock(key string) error
+	FindLockouts(tenantID string, activeOnly bool, limit int) ([]*entity.LockoutEvent, error)
+}
+
+type APIKeyDBInterface interface {
+	Create(key *entity.APIKey) error
+	FindByPrefix(prefix string) (*entity.APIKey, error)
+	FindByUser(userID string) ([]*entity.APIKey, error)
+	Revoke(id, userID string) error
+	TouchLastUsed(id string, at time.Time) error
+}
+
+type OAuthClientDBInterface interface {
+	Create(client *entity.OAuthClient) error
+	FindByID(id string) (*entity.OAuthClient, error)
+	FindAll() ([]*entity.OAuthClient, error)
+	Revoke(id string) error
+	ForTenant(tenantID string) OAuthClientDBInterface
 }
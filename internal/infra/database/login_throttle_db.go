@@ -0,0 +1,91 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	"gorm.io/gorm"
+)
+
+type LoginThrottleDB struct {
+	DB *gorm.DB
+}
+
+func NewLoginThrottleDB(db *gorm.DB) *LoginThrottleDB {
+	return &LoginThrottleDB{DB: db}
+}
+
+// Find retorna as falhas registradas para as chaves. Chaves sem falhas são omitidas.
+func (db *LoginThrottleDB) Find(keys ...string) ([]*entity.LoginThrottle, error) {
+	var throttles []*entity.LoginThrottle
+	err := db.DB.Where("throttle_key IN ?", keys).Find(&throttles).Error
+	return throttles, translateError(err)
+}
+
+// RegisterFailure incrementa as falhas da chave. Falhas anteriores a windowStart são descartadas.
+func (db *LoginThrottleDB) RegisterFailure(key string, now, windowStart time.Time) (*entity.LoginThrottle, error) {
+	var throttle entity.LoginThrottle
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("throttle_key = ?", key).First(&throttle).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			throttle = entity.LoginThrottle{Key: key}
+		} else if err != nil {
+			return err
+		}
+		if throttle.LastFailureAt.Before(windowStart) && !throttle.IsLocked(now) {
+			throttle.Failures = 0
+		}
+		throttle.Failures++
+		throttle.LastFailureAt = now
+		return tx.Save(&throttle).Error
+	})
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &throttle, nil
+}
+
+// Lock bloqueia a chave até o instante informado e registra o evento de bloqueio
+func (db *LoginThrottleDB) Lock(event *entity.LockoutEvent) error {
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&entity.LoginThrottle{}).
+			Where("throttle_key = ?", event.Key).
+			Update("locked_until", event.LockedUntil).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(event).Error
+	})
+	return translateError(err)
+}
+
+// Reset remove as falhas da chave, usado após um login bem sucedido e pelo desbloqueio manual
+func (db *LoginThrottleDB) Reset(key string) error {
+	return translateError(db.DB.Where("throttle_key = ?", key).Delete(&entity.LoginThrottle{}).Error)
+}
+
+// Unlock remove o bloqueio e marca os eventos ativos da chave como desbloqueados
+func (db *LoginThrottleDB) Unlock(key string) error {
+	now := time.Now()
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("throttle_key = ?", key).Delete(&entity.LoginThrottle{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&entity.LockoutEvent{}).
+			Where("throttle_key = ? AND unlocked_at IS NULL AND locked_until > ?", key, now).
+			Update("unlocked_at", now).Error
+	})
+	return translateError(err)
+}
+
+// FindLockouts lista os eventos do tenant, os mais recentes primeiro. Eventos sem tenant nunca aparecem.
+func (db *LoginThrottleDB) FindLockouts(tenantID string, activeOnly bool, limit int) ([]*entity.LockoutEvent, error) {
+	var events []*entity.LockoutEvent
+	tx := db.DB.Where("tenant_id = ?", tenantID).Order("created_at desc").Limit(limit)
+	if activeOnly {
+		tx = tx.Where("unlocked_at IS NULL AND locked_until > ?", time.Now())
+	}
+	err := tx.Find(&events).Error
+	return events, translateError(err)
+}
@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type product0001 struct {
+	ID        string  `gorm:"primaryKey;size:36"`
+	Name      string  `gorm:"size:255;not null"`
+	Price     float64 `gorm:"not null"`
+	CreatedAt time.Time
+}
+
+func (product0001) TableName() string {
+	return "products"
+}
+
+func init() {
+	register(Migration{
+		Version: 1,
+		Name:    "create_products",
+		Up: func(tx *gorm.DB) error {
+			// Bancos criados antes das migrations (via AutoMigrate) já possuem a tabela
+			if tx.Migrator().HasTable("products") {
+				return nil
+			}
+			return tx.Migrator().CreateTable(&product0001{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("products")
+		},
+	})
+}
@@ -0,0 +1,31 @@
+package migrations
+
+import "gorm.io/gorm"
+
+type user0002 struct {
+	ID       string `gorm:"primaryKey;size:36"`
+	Name     string `gorm:"size:255;not null"`
+	Email    string `gorm:"size:255;not null"`
+	Password string `gorm:"size:255;not null"`
+}
+
+func (user0002) TableName() string {
+	return "users"
+}
+
+func init() {
+	register(Migration{
+		Version: 2,
+		Name:    "create_users",
+		Up: func(tx *gorm.DB) error {
+			// Bancos criados antes das migrations (via AutoMigrate) já possuem a tabela
+			if tx.Migrator().HasTable("users") {
+				return nil
+			}
+			return tx.Migrator().CreateTable(&user0002{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("users")
+		},
+	})
+}
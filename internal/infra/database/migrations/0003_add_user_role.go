@@ -0,0 +1,24 @@
+package migrations
+
+import "gorm.io/gorm"
+
+type user0003 struct {
+	Role string `gorm:"size:20;not null;default:viewer"`
+}
+
+func (user0003) TableName() string {
+	return "users"
+}
+
+func init() {
+	register(Migration{
+		Version: 3,
+		Name:    "add_user_role",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().AddColumn(&user0003{}, "Role")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&user0003{}, "Role")
+		},
+	})
+}
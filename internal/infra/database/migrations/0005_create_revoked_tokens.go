@@ -0,0 +1,29 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type revokedToken0005 struct {
+	JTI       string    `gorm:"primaryKey;size:36"`
+	ExpiresAt time.Time `gorm:"index"`
+}
+
+func (revokedToken0005) TableName() string {
+	return "revoked_tokens"
+}
+
+func init() {
+	register(Migration{
+		Version: 5,
+		Name:    "create_revoked_tokens",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&revokedToken0005{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("revoked_tokens")
+		},
+	})
+}
@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Índice usado pela paginação por keyset em (created_at, id)
+type product0006 struct {
+	ID        string    `gorm:"primaryKey;size:36;index:idx_products_created_at_id,priority:2"`
+	CreatedAt time.Time `gorm:"index:idx_products_created_at_id,priority:1"`
+}
+
+func (product0006) TableName() string {
+	return "products"
+}
+
+func init() {
+	register(Migration{
+		Version: 6,
+		Name:    "add_products_created_at_id_index",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateIndex(&product0006{}, "idx_products_created_at_id")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropIndex(&product0006{}, "idx_products_created_at_id")
+		},
+	})
+}
@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Emails passam a ser gravados normalizados (trim + minúsculas) e únicos
+type user0007 struct {
+	Email string `gorm:"size:255;not null;uniqueIndex:idx_users_email"`
+}
+
+func (user0007) TableName() string {
+	return "users"
+}
+
+func init() {
+	register(Migration{
+		Version: 7,
+		Name:    "add_users_email_unique_index",
+		Up: func(tx *gorm.DB) error {
+			err := tx.Exec("UPDATE users SET email = LOWER(TRIM(email))").Error
+			if err != nil {
+				return err
+			}
+			// Contas duplicadas precisam ser resolvidas manualmente antes de criar o índice
+			var duplicated int64
+			err = tx.Table("users").Select("email").Group("email").Having("COUNT(*) > 1").Count(&duplicated).Error
+			if err != nil {
+				return err
+			}
+			if duplicated > 0 {
+				return fmt.Errorf("%d email(s) are used by more than one user, merge them before applying this migration", duplicated)
+			}
+			return tx.Migrator().CreateIndex(&user0007{}, "idx_users_email")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropIndex(&user0007{}, "idx_users_email")
+		},
+	})
+}
@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type user0008 struct {
+	EmailVerifiedAt *time.Time
+}
+
+func (user0008) TableName() string {
+	return "users"
+}
+
+func init() {
+	register(Migration{
+		Version: 8,
+		Name:    "add_user_email_verified_at",
+		Up: func(tx *gorm.DB) error {
+			err := tx.Migrator().AddColumn(&user0008{}, "EmailVerifiedAt")
+			if err != nil {
+				return err
+			}
+			// Contas criadas antes da verificação existir continuam podendo fazer login
+			return tx.Model(&user0008{}).Where("1 = 1").Update("email_verified_at", time.Now()).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			err := tx.Migrator().DropColumn(&user0008{}, "EmailVerifiedAt")
+			if err != nil {
+				return err
+			}
+			return restoreIndexes(tx, tableIndex{&user0007{}, "idx_users_email"})
+		},
+	})
+}
@@ -0,0 +1,54 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type user0010 struct {
+	TOTPSecret   string `gorm:"column:totp_secret;size:64;not null;default:''"`
+	TOTPEnabled  bool   `gorm:"column:totp_enabled;not null;default:false"`
+	TOTPLastStep int64  `gorm:"column:totp_last_step;not null;default:0"`
+}
+
+func (user0010) TableName() string {
+	return "users"
+}
+
+type recoveryCode0010 struct {
+	ID       string `gorm:"primaryKey;size:36"`
+	UserID   string `gorm:"size:36;not null;index"`
+	CodeHash string `gorm:"size:64;not null"`
+	UsedAt   *time.Time
+}
+
+func (recoveryCode0010) TableName() string {
+	return "recovery_codes"
+}
+
+func init() {
+	register(Migration{
+		Version: 10,
+		Name:    "add_user_totp",
+		Up: func(tx *gorm.DB) error {
+			for _, column := range []string{"TOTPSecret", "TOTPEnabled", "TOTPLastStep"} {
+				if err := tx.Migrator().AddColumn(&user0010{}, column); err != nil {
+					return err
+				}
+			}
+			return tx.Migrator().CreateTable(&recoveryCode0010{})
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable("recovery_codes"); err != nil {
+				return err
+			}
+			for _, column := range []string{"TOTPLastStep", "TOTPEnabled", "TOTPSecret"} {
+				if err := tx.Migrator().DropColumn(&user0010{}, column); err != nil {
+					return err
+				}
+			}
+			return restoreIndexes(tx, tableIndex{&user0007{}, "idx_users_email"})
+		},
+	})
+}
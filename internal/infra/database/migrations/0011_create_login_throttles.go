@@ -0,0 +1,44 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type loginThrottle0011 struct {
+	ThrottleKey   string `gorm:"primaryKey;size:300"`
+	Failures      int    `gorm:"not null;default:0"`
+	LastFailureAt time.Time
+	LockedUntil   *time.Time
+}
+
+func (loginThrottle0011) TableName() string {
+	return "login_throttles"
+}
+
+type lockoutEvent0011 struct {
+	ID          string `gorm:"primaryKey;size:36"`
+	ThrottleKey string `gorm:"size:300;not null;index"`
+	Failures    int    `gorm:"not null"`
+	LockedUntil time.Time
+	CreatedAt   time.Time `gorm:"index"`
+	UnlockedAt  *time.Time
+}
+
+func (lockoutEvent0011) TableName() string {
+	return "lockout_events"
+}
+
+func init() {
+	register(Migration{
+		Version: 11,
+		Name:    "create_login_throttles",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&loginThrottle0011{}, &lockoutEvent0011{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("lockout_events", "login_throttles")
+		},
+	})
+}
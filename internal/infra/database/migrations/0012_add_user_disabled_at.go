@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type user0012 struct {
+	DisabledAt     *time.Time
+	SessionVersion int64 `gorm:"not null;default:0"`
+}
+
+func (user0012) TableName() string {
+	return "users"
+}
+
+func init() {
+	register(Migration{
+		Version: 12,
+		Name:    "add_user_disabled_at",
+		Up: func(tx *gorm.DB) error {
+			for _, column := range []string{"DisabledAt", "SessionVersion"} {
+				if err := tx.Migrator().AddColumn(&user0012{}, column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, column := range []string{"SessionVersion", "DisabledAt"} {
+				if err := tx.Migrator().DropColumn(&user0012{}, column); err != nil {
+					return err
+				}
+			}
+			return restoreIndexes(tx, tableIndex{&user0007{}, "idx_users_email"})
+		},
+	})
+}
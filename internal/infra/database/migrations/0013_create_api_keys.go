@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type apiKey0013 struct {
+	ID         string `gorm:"primaryKey;size:36"`
+	UserID     string `gorm:"size:36;not null;index"`
+	Name       string `gorm:"size:100;not null"`
+	Prefix     string `gorm:"size:16;not null;uniqueIndex"`
+	KeyHash    string `gorm:"size:64;not null"`
+	Scopes     string `gorm:"size:255;not null;default:''"`
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+	RevokedAt  *time.Time
+}
+
+func (apiKey0013) TableName() string {
+	return "api_keys"
+}
+
+func init() {
+	register(Migration{
+		Version: 13,
+		Name:    "create_api_keys",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&apiKey0013{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("api_keys")
+		},
+	})
+}
@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type oauthClient0014 struct {
+	ID         string  `gorm:"primaryKey;size:36"`
+	Name       string  `gorm:"size:100;not null"`
+	SecretHash string  `gorm:"size:64;not null"`
+	Grants     string  `gorm:"size:100;not null"`
+	Scopes     string  `gorm:"size:255;not null"`
+	UserID     *string `gorm:"size:36;index"`
+	CreatedAt  time.Time
+	RevokedAt  *time.Time
+}
+
+func (oauthClient0014) TableName() string {
+	return "oauth_clients"
+}
+
+func init() {
+	register(Migration{
+		Version: 14,
+		Name:    "create_oauth_clients",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&oauthClient0014{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("oauth_clients")
+		},
+	})
+}
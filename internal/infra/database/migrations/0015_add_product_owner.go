@@ -0,0 +1,38 @@
+package migrations
+
+import "gorm.io/gorm"
+
+type product0015 struct {
+	CreatedBy *string `gorm:"size:36;index:idx_products_created_by"`
+	UpdatedBy *string `gorm:"size:36"`
+}
+
+func (product0015) TableName() string {
+	return "products"
+}
+
+func init() {
+	register(Migration{
+		Version: 15,
+		Name:    "add_product_owner",
+		Up: func(tx *gorm.DB) error {
+			for _, column := range []string{"CreatedBy", "UpdatedBy"} {
+				if err := tx.Migrator().AddColumn(&product0015{}, column); err != nil {
+					return err
+				}
+			}
+			return tx.Migrator().CreateIndex(&product0015{}, "idx_products_created_by")
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropIndex(&product0015{}, "idx_products_created_by"); err != nil {
+				return err
+			}
+			for _, column := range []string{"UpdatedBy", "CreatedBy"} {
+				if err := tx.Migrator().DropColumn(&product0015{}, column); err != nil {
+					return err
+				}
+			}
+			return restoreIndexes(tx, tableIndex{&product0006{}, "idx_products_created_at_id"})
+		},
+	})
+}
@@ -0,0 +1,100 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type tenant0016 struct {
+	ID        string `gorm:"primaryKey;size:36"`
+	Name      string `gorm:"size:100;not null"`
+	Slug      string `gorm:"size:50;not null;uniqueIndex:idx_tenants_slug"`
+	CreatedAt time.Time
+}
+
+func (tenant0016) TableName() string {
+	return "tenants"
+}
+
+type userTenant0016 struct {
+	TenantID *string `gorm:"size:36;index:idx_users_tenant_id"`
+}
+
+func (userTenant0016) TableName() string {
+	return "users"
+}
+
+type productTenant0016 struct {
+	TenantID *string `gorm:"size:36;index:idx_products_tenant_id"`
+}
+
+func (productTenant0016) TableName() string {
+	return "products"
+}
+
+type oauthClientTenant0016 struct {
+	TenantID *string `gorm:"size:36;index:idx_oauth_clients_tenant_id"`
+}
+
+func (oauthClientTenant0016) TableName() string {
+	return "oauth_clients"
+}
+
+// Os usuários, produtos e clientes OAuth existentes passam a pertencer ao tenant "default"
+func init() {
+	tenantModels := []struct {
+		model interface{}
+		index string
+	}{
+		{&userTenant0016{}, "idx_users_tenant_id"},
+		{&productTenant0016{}, "idx_products_tenant_id"},
+		{&oauthClientTenant0016{}, "idx_oauth_clients_tenant_id"},
+	}
+
+	register(Migration{
+		Version: 16,
+		Name:    "create_tenants",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().CreateTable(&tenant0016{}); err != nil {
+				return err
+			}
+			defaultTenant := tenant0016{ID: uuid.New().String(), Name: "Default", Slug: "default", CreatedAt: time.Now()}
+			if err := tx.Create(&defaultTenant).Error; err != nil {
+				return err
+			}
+			for _, m := range tenantModels {
+				if err := tx.Migrator().AddColumn(m.model, "TenantID"); err != nil {
+					return err
+				}
+				if err := tx.Model(m.model).Where("1 = 1").Update("tenant_id", defaultTenant.ID).Error; err != nil {
+					return err
+				}
+				if err := tx.Migrator().CreateIndex(m.model, m.index); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, m := range tenantModels {
+				if err := tx.Migrator().DropIndex(m.model, m.index); err != nil {
+					return err
+				}
+				if err := tx.Migrator().DropColumn(m.model, "TenantID"); err != nil {
+					return err
+				}
+			}
+			if err := restoreIndexes(tx,
+				tableIndex{&user0007{}, "idx_users_email"},
+				tableIndex{&product0006{}, "idx_products_created_at_id"},
+				tableIndex{&product0015{}, "idx_products_created_by"},
+				tableIndex{&oauthClient0014{}, "UserID"},
+			); err != nil {
+				return err
+			}
+			return tx.Migrator().DropTable("tenants")
+		},
+	})
+}
@@ -0,0 +1,31 @@
+package migrations
+
+import "gorm.io/gorm"
+
+type product0017 struct {
+	Version int64 `gorm:"not null;default:1"`
+}
+
+func (product0017) TableName() string {
+	return "products"
+}
+
+func init() {
+	register(Migration{
+		Version: 17,
+		Name:    "add_product_version",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().AddColumn(&product0017{}, "Version")
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropColumn(&product0017{}, "Version"); err != nil {
+				return err
+			}
+			return restoreIndexes(tx,
+				tableIndex{&product0006{}, "idx_products_created_at_id"},
+				tableIndex{&product0015{}, "idx_products_created_by"},
+				tableIndex{&productTenant0016{}, "idx_products_tenant_id"},
+			)
+		},
+	})
+}
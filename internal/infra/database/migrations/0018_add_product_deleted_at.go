@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type product0018 struct {
+	DeletedAt *time.Time `gorm:"index:idx_products_deleted_at"`
+}
+
+func (product0018) TableName() string {
+	return "products"
+}
+
+func init() {
+	register(Migration{
+		Version: 18,
+		Name:    "add_product_deleted_at",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AddColumn(&product0018{}, "DeletedAt"); err != nil {
+				return err
+			}
+			return tx.Migrator().CreateIndex(&product0018{}, "idx_products_deleted_at")
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropIndex(&product0018{}, "idx_products_deleted_at"); err != nil {
+				return err
+			}
+			if err := tx.Migrator().DropColumn(&product0018{}, "DeletedAt"); err != nil {
+				return err
+			}
+			return restoreIndexes(tx,
+				tableIndex{&product0006{}, "idx_products_created_at_id"},
+				tableIndex{&product0015{}, "idx_products_created_by"},
+				tableIndex{&productTenant0016{}, "idx_products_tenant_id"},
+			)
+		},
+	})
+}
@@ -0,0 +1,65 @@
+package migrations
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+type lockoutEventTenant0019 struct {
+	TenantID *string `gorm:"size:36;index:idx_lockout_events_tenant_id"`
+}
+
+func (lockoutEventTenant0019) TableName() string {
+	return "lockout_events"
+}
+
+// Os bloqueios por conta passam ao tenant da conta; os por IP ficam sem tenant
+func init() {
+	register(Migration{
+		Version: 19,
+		Name:    "add_lockout_event_tenant",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AddColumn(&lockoutEventTenant0019{}, "TenantID"); err != nil {
+				return err
+			}
+			if err := tx.Migrator().CreateIndex(&lockoutEventTenant0019{}, "idx_lockout_events_tenant_id"); err != nil {
+				return err
+			}
+			var keys []string
+			err := tx.Table("lockout_events").Distinct("throttle_key").
+				Where("throttle_key LIKE ?", "email:%").Pluck("throttle_key", &keys).Error
+			if err != nil {
+				return err
+			}
+			for _, key := range keys {
+				var tenantIDs []string
+				err := tx.Table("users").Where("email = ?", strings.TrimPrefix(key, "email:")).
+					Limit(1).Pluck("tenant_id", &tenantIDs).Error
+				if err != nil {
+					return err
+				}
+				if len(tenantIDs) == 0 {
+					continue
+				}
+				err = tx.Table("lockout_events").Where("throttle_key = ?", key).Update("tenant_id", tenantIDs[0]).Error
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropIndex(&lockoutEventTenant0019{}, "idx_lockout_events_tenant_id"); err != nil {
+				return err
+			}
+			if err := tx.Migrator().DropColumn(&lockoutEventTenant0019{}, "TenantID"); err != nil {
+				return err
+			}
+			return restoreIndexes(tx,
+				tableIndex{&lockoutEvent0011{}, "ThrottleKey"},
+				tableIndex{&lockoutEvent0011{}, "CreatedAt"},
+			)
+		},
+	})
+}
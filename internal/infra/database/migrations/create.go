@@ -0,0 +1,88 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+var (
+	ErrInvalidName = errors.New("migration name must contain only lowercase letters, digits and underscores")
+
+	nameRegexp     = regexp.MustCompile(`^[a-z0-9_]+$`)
+	fileNameRegexp = regexp.MustCompile(`^(\d+)_[a-z0-9_]+\.go$`)
+)
+
+var migrationTemplate = template.Must(template.New("migration").Parse(`package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	register(Migration{
+		Version: {{.Version}},
+		Name:    "{{.Name}}",
+		Up: func(tx *gorm.DB) error {
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			return nil
+		},
+	})
+}
+`))
+
+// Create gera o arquivo de uma nova migration no diretório informado usando a próxima versão disponível
+func Create(dir, name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !nameRegexp.MatchString(name) {
+		return "", ErrInvalidName
+	}
+
+	version, err := nextVersion(dir)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%04d_%s.go", version, name))
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	err = migrationTemplate.Execute(f, struct {
+		Version int64
+		Name    string
+	}{version, name})
+	if err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func nextVersion(dir string) (int64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+	var max int64
+	for _, entry := range entries {
+		match := fileNameRegexp.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		if version > max {
+			max = version
+		}
+	}
+	return max + 1, nil
+}
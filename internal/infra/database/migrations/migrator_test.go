@@ -0,0 +1,223 @@
+package migrations
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func connectToTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Cada conexão com file::memory: abre um banco novo, então limitamos o pool a uma conexão
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	return db
+}
+
+func TestMigrator_UpAppliesAllMigrations(t *testing.T) {
+	db := connectToTestDB(t)
+	migrator, err := NewMigrator(db, All())
+	assert.NoError(t, err)
+
+	done, err := migrator.Up()
+	assert.NoError(t, err)
+	assert.Len(t, done, len(All()))
+	assert.True(t, db.Migrator().HasTable(&entity.Product{}))
+	assert.True(t, db.Migrator().HasTable(&entity.User{}))
+
+	product, err := entity.NewProduct("Product 1", 10.0)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Create(product).Error)
+
+	user, err := entity.NewUser("John", "johndoe@test.com", "12345678")
+	assert.NoError(t, err)
+	assert.NoError(t, db.Create(user).Error)
+
+	done, err = migrator.Up()
+	assert.NoError(t, err)
+	assert.Empty(t, done)
+}
+
+func TestMigrator_DownRevertsInReverseOrder(t *testing.T) {
+	db := connectToTestDB(t)
+	migrator, err := NewMigrator(db, All())
+	assert.NoError(t, err)
+
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+
+	done, err := migrator.Down(1)
+	assert.NoError(t, err)
+	assert.Len(t, done, 1)
+	assert.Equal(t, All()[len(All())-1].Version, done[0].Version)
+
+	done, err = migrator.Down(len(All()))
+	assert.NoError(t, err)
+	assert.Len(t, done, len(All())-1)
+	assert.False(t, db.Migrator().HasTable(&entity.Product{}))
+	assert.False(t, db.Migrator().HasTable(&entity.User{}))
+
+	_, err = migrator.Down(0)
+	assert.ErrorIs(t, err, ErrInvalidSteps)
+}
+
+func TestMigrator_DownKeepsExistingIndexes(t *testing.T) {
+	db := connectToTestDB(t)
+	migrator, err := NewMigrator(db, All())
+	assert.NoError(t, err)
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+
+	// Remover colunas (0019 em lockout_events e 0018 em products) não pode levar junto
+	// os índices das migrations anteriores
+	_, err = migrator.Down(2)
+	assert.NoError(t, err)
+	for _, index := range []tableIndex{
+		{&product0006{}, "idx_products_created_at_id"},
+		{&product0015{}, "idx_products_created_by"},
+		{&productTenant0016{}, "idx_products_tenant_id"},
+		{&lockoutEvent0011{}, "ThrottleKey"},
+		{&lockoutEvent0011{}, "CreatedAt"},
+	} {
+		assert.True(t, db.Migrator().HasIndex(index.model, index.name), index.name)
+	}
+}
+
+func TestMigrator_Status(t *testing.T) {
+	db := connectToTestDB(t)
+	migrator, err := NewMigrator(db, []Migration{
+		{Version: 2, Name: "second", Up: func(tx *gorm.DB) error { return nil }, Down: func(tx *gorm.DB) error { return nil }},
+		{Version: 1, Name: "first", Up: func(tx *gorm.DB) error { return nil }, Down: func(tx *gorm.DB) error { return nil }},
+	})
+	assert.NoError(t, err)
+
+	status, err := migrator.Status()
+	assert.NoError(t, err)
+	assert.Len(t, status, 2)
+	assert.Equal(t, int64(1), status[0].Version)
+	assert.False(t, status[0].Applied)
+
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+	_, err = migrator.Down(1)
+	assert.NoError(t, err)
+
+	status, err = migrator.Status()
+	assert.NoError(t, err)
+	assert.True(t, status[0].Applied)
+	assert.NotNil(t, status[0].AppliedAt)
+	assert.False(t, status[1].Applied)
+	assert.Nil(t, status[1].AppliedAt)
+}
+
+func TestMigrator_FailedMigrationIsNotRecorded(t *testing.T) {
+	db := connectToTestDB(t)
+	errBoom := errors.New("boom")
+	migrator, err := NewMigrator(db, []Migration{
+		{Version: 1, Name: "ok", Up: func(tx *gorm.DB) error { return nil }},
+		{Version: 2, Name: "broken", Up: func(tx *gorm.DB) error { return errBoom }},
+	})
+	assert.NoError(t, err)
+
+	done, err := migrator.Up()
+	assert.ErrorIs(t, err, errBoom)
+	assert.Len(t, done, 1)
+
+	pending, err := migrator.Pending()
+	assert.NoError(t, err)
+	assert.Len(t, pending, 1)
+	assert.Equal(t, int64(2), pending[0].Version)
+}
+
+func TestNewMigrator_DuplicateVersion(t *testing.T) {
+	db := connectToTestDB(t)
+	_, err := NewMigrator(db, []Migration{{Version: 1, Name: "a"}, {Version: 1, Name: "b"}})
+	assert.ErrorIs(t, err, ErrDuplicateVersion)
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "0007_add_something.go"), []byte("package migrations\n"), 0644))
+
+	path, err := Create(dir, "Add_Roles")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "0008_add_roles.go"), path)
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "Version: 8,")
+	assert.Contains(t, string(content), `Name:    "add_roles",`)
+
+	_, err = Create(dir, "add roles!")
+	assert.ErrorIs(t, err, ErrInvalidName)
+}
+
+func TestCreateTenants_BackfillsDefaultTenant(t *testing.T) {
+	db := connectToTestDB(t)
+	before, err := NewMigrator(db, All()[:15])
+	assert.NoError(t, err)
+	_, err = before.Up()
+	assert.NoError(t, err)
+	assert.NoError(t, db.Exec("INSERT INTO users (id, name, email, password, role) VALUES ('1', 'John', 'johndoe@test.com', 'x', 'viewer')").Error)
+	assert.NoError(t, db.Exec("INSERT INTO products (id, name, price) VALUES ('1', 'Product 1', 10)").Error)
+
+	migrator, err := NewMigrator(db, All())
+	assert.NoError(t, err)
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+
+	var tenantID string
+	assert.NoError(t, db.Table("tenants").Select("id").Where("slug = ?", entity.DefaultTenantSlug).Scan(&tenantID).Error)
+	assert.NotEmpty(t, tenantID)
+	for _, table := range []string{"users", "products"} {
+		var rowTenant string
+		assert.NoError(t, db.Table(table).Select("tenant_id").Where("id = '1'").Scan(&rowTenant).Error)
+		assert.Equal(t, tenantID, rowTenant, table)
+	}
+}
+
+func TestMigration_LockoutEventTenantBackfill(t *testing.T) {
+	db := connectToTestDB(t)
+	all := All()
+	before, err := NewMigrator(db, all[:len(all)-1])
+	assert.NoError(t, err)
+	_, err = before.Up()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(18), all[len(all)-2].Version)
+
+	var tenantID string
+	assert.NoError(t, db.Table("tenants").Select("id").Where("slug = ?", "default").Row().Scan(&tenantID))
+	user, err := entity.NewUser("John", "johndoe@test.com", "12345678")
+	assert.NoError(t, err)
+	assert.NoError(t, db.Omit("tenant_id").Create(user).Error)
+	assert.NoError(t, db.Table("users").Where("id = ?", user.ID.String()).Update("tenant_id", tenantID).Error)
+	for _, key := range []string{entity.EmailThrottleKey("johndoe@test.com"), entity.IPThrottleKey("192.0.2.1")} {
+		event := lockoutEvent0011{ID: key, ThrottleKey: key, Failures: 3}
+		assert.NoError(t, db.Create(&event).Error)
+	}
+
+	migrator, err := NewMigrator(db, all)
+	assert.NoError(t, err)
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+
+	// Os bloqueios por conta ficam com o tenant da conta; os por IP ficam sem tenant
+	var tenants []sql.NullString
+	assert.NoError(t, db.Table("lockout_events").Order("throttle_key").Pluck("tenant_id", &tenants).Error)
+	assert.Len(t, tenants, 2)
+	assert.Equal(t, sql.NullString{String: tenantID, Valid: true}, tenants[0])
+	assert.False(t, tenants[1].Valid)
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"time"
+
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	"gorm.io/gorm"
+)
+
+type OAuthClientDB struct {
+	DB       *gorm.DB
+	TenantID string // Restringe as consultas ao tenant
+}
+
+func NewOAuthClientDB(db *gorm.DB) *OAuthClientDB {
+	return &OAuthClientDB{DB: db}
+}
+
+// ForTenant retorna uma cópia do repositório usada pelas rotas de administração do tenant
+func (db *OAuthClientDB) ForTenant(tenantID string) OAuthClientDBInterface {
+	return &OAuthClientDB{DB: db.DB, TenantID: tenantID}
+}
+
+func (db *OAuthClientDB) Create(client *entity.OAuthClient) error {
+	if db.TenantID != "" {
+		if err := setTenant(&client.TenantID, db.TenantID); err != nil {
+			return err
+		}
+	}
+	return translateError(db.DB.Create(client).Error)
+}
+
+func (db *OAuthClientDB) FindByID(id string) (*entity.OAuthClient, error) {
+	var client entity.OAuthClient
+	err := tenantScope(db.DB, db.TenantID).Where("id = ?", id).First(&client).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &client, nil
+}
+
+func (db *OAuthClientDB) FindAll() ([]*entity.OAuthClient, error) {
+	var clients []*entity.OAuthClient
+	err := tenantScope(db.DB, db.TenantID).Order("created_at desc").Find(&clients).Error
+	return clients, translateError(err)
+}
+
+func (db *OAuthClientDB) Revoke(id string) error {
+	client, err := db.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if client.IsRevoked() {
+		return nil
+	}
+	return translateError(db.DB.Model(client).Update("revoked_at", time.Now()).Error)
+}
@@ -0,0 +1,37 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestOAuthClientDB(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	db.AutoMigrate(&entity.OAuthClient{})
+	clientDB := NewOAuthClientDB(db)
+
+	client, secret, err := entity.NewOAuthClient("Partner", []string{entity.GrantPassword}, []entity.Permission{entity.PermissionProductsRead}, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, clientDB.Create(client))
+
+	found, err := clientDB.FindByID(client.ID.String())
+	assert.Nil(t, err)
+	assert.True(t, found.Matches(secret))
+	assert.Nil(t, found.UserID)
+	assert.False(t, found.IsRevoked())
+
+	assert.Nil(t, clientDB.Revoke(client.ID.String()))
+	assert.ErrorIs(t, clientDB.Revoke("unknown"), ErrNotFound)
+
+	clients, err := clientDB.FindAll()
+	assert.Nil(t, err)
+	assert.Len(t, clients, 1)
+	assert.True(t, clients[0].IsRevoked())
+}
@@ -0,0 +1,45 @@
+package database
+
+import (
+	"time"
+
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	"gorm.io/gorm"
+)
+
+type PasswordResetTokenDB struct {
+	DB *gorm.DB
+}
+
+func NewPasswordResetTokenDB(db *gorm.DB) *PasswordResetTokenDB {
+	return &PasswordResetTokenDB{DB: db}
+}
+
+func (db *PasswordResetTokenDB) Create(token *entity.PasswordResetToken) error {
+	return translateError(db.DB.Create(token).Error)
+}
+
+func (db *PasswordResetTokenDB) FindByHash(hash string) (*entity.PasswordResetToken, error) {
+	var token entity.PasswordResetToken
+	err := db.DB.Where("token_hash = ?", hash).First(&token).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &token, nil
+}
+
+// MarkUsed consome o token. Retorna false se ele já tinha sido usado, inclusive por um request concorrente.
+func (db *PasswordResetTokenDB) MarkUsed(id string) (bool, error) {
+	result := db.DB.Model(&entity.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	return result.RowsAffected == 1, translateError(result.Error)
+}
+
+// InvalidateByUser consome todos os tokens pendentes do usuário (ex: depois de uma troca de senha)
+func (db *PasswordResetTokenDB) InvalidateByUser(userID string) error {
+	err := db.DB.Model(&entity.PasswordResetToken{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", time.Now()).Error
+	return translateError(err)
+}
@@ -0,0 +1,66 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	entityPkg "github.com/gsouza97/go-expert-api/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func connectToPasswordResetTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.AutoMigrate(&entity.PasswordResetToken{})
+	return db
+}
+
+func TestPasswordResetTokenDB_MarkUsed(t *testing.T) {
+	tokenDB := NewPasswordResetTokenDB(connectToPasswordResetTestDB(t))
+
+	token, plain, err := entity.NewPasswordResetToken(entityPkg.NewId(), time.Hour)
+	assert.NoError(t, err)
+	assert.NoError(t, tokenDB.Create(token))
+
+	found, err := tokenDB.FindByHash(entity.HashToken(plain))
+	assert.NoError(t, err)
+	assert.Equal(t, token.ID, found.ID)
+	assert.False(t, found.IsUsed())
+
+	used, err := tokenDB.MarkUsed(token.ID.String())
+	assert.NoError(t, err)
+	assert.True(t, used)
+
+	used, err = tokenDB.MarkUsed(token.ID.String())
+	assert.NoError(t, err)
+	assert.False(t, used)
+
+	_, err = tokenDB.FindByHash(entity.HashToken("unknown"))
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestPasswordResetTokenDB_InvalidateByUser(t *testing.T) {
+	tokenDB := NewPasswordResetTokenDB(connectToPasswordResetTestDB(t))
+	userID := entityPkg.NewId()
+
+	first, firstPlain, err := entity.NewPasswordResetToken(userID, time.Hour)
+	assert.NoError(t, err)
+	assert.NoError(t, tokenDB.Create(first))
+	other, otherPlain, err := entity.NewPasswordResetToken(entityPkg.NewId(), time.Hour)
+	assert.NoError(t, err)
+	assert.NoError(t, tokenDB.Create(other))
+
+	assert.NoError(t, tokenDB.InvalidateByUser(userID.String()))
+
+	found, err := tokenDB.FindByHash(entity.HashToken(firstPlain))
+	assert.NoError(t, err)
+	assert.True(t, found.IsUsed())
+	found, err = tokenDB.FindByHash(entity.HashToken(otherPlain))
+	assert.NoError(t, err)
+	assert.False(t, found.IsUsed())
+}
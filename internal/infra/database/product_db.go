@@ -1,55 +1,157 @@
 package database
 
 import (
+	"time"
+
 	"github.com/gsouza97/go-expert-api/internal/entity"
 	"gorm.io/gorm"
 )
 
 type ProductDB struct {
-	DB *gorm.DB
+	DB       *gorm.DB
+	TenantID string // Restringe as consultas ao tenant
 }
 
 func NewProductDB(db *gorm.DB) *ProductDB {
 	return &ProductDB{DB: db}
 }
 
+// ForTenant retorna uma cópia do repositório que só lê e grava os produtos do tenant
+func (db *ProductDB) ForTenant(tenantID string) ProductDBInterface {
+	return &ProductDB{DB: db.DB, TenantID: tenantID}
+}
+
+func (db *ProductDB) scoped() *gorm.DB {
+	return tenantScope(db.DB, db.TenantID)
+}
+
 func (db *ProductDB) CreateProduct(product *entity.Product) error {
-	return db.DB.Create(product).Error
+	if db.TenantID != "" {
+		if err := setTenant(&product.TenantID, db.TenantID); err != nil {
+			return err
+		}
+	}
+	return translateError(db.DB.Create(product).Error)
 }
 
+// FindByID não encontra os produtos na lixeira, que só são lidos por FindDeletedByID
 func (db *ProductDB) FindByID(id string) (*entity.Product, error) {
 	var product entity.Product
-	err := db.DB.First(&product, "id = ?", id).Error
-	return &product, err
+	err := db.scoped().First(&product, "id = ? AND deleted_at IS NULL", id).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &product, nil
 }
 
-func (db *ProductDB) FindAll(page int, limit int, sort string) ([]*entity.Product, error) {
+func (db *ProductDB) FindDeletedByID(id string) (*entity.Product, error) {
+	var product entity.Product
+	err := db.scoped().First(&product, "id = ? AND deleted_at IS NOT NULL", id).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &product, nil
+}
+
+func (db *ProductDB) FindAll(query ProductQuery) ([]*entity.Product, error) {
 	var products []*entity.Product
-	var err error
-	if sort != "" && sort != "asc" && sort != "desc" {
-		sort = "asc"
+	tx, err := applySort(query.Filter.apply(db.scoped()), query.Sort)
+	if err != nil {
+		return nil, err
 	}
-	if page != 0 && limit != 0 {
-		err = db.DB.Limit(limit).Offset((page - 1) * limit).Order("created_at " + sort).Find(&products).Error
-	} else {
-		err = db.DB.Order("created_at" + sort).Find(&products).Error
+	if query.Page != 0 && query.Limit != 0 {
+		tx = tx.Limit(query.Limit).Offset((query.Page - 1) * query.Limit)
 	}
+	err = tx.Find(&products).Error
+	return products, translateError(err)
+}
+
+// FindAfter pagina por (created_at, id), retornando os produtos posteriores ao cursor
+func (db *ProductDB) FindAfter(after *ProductCursor, limit int, filter ProductFilter) ([]*entity.Product, error) {
+	var products []*entity.Product
+	tx := filter.apply(db.scoped())
+	if after != nil {
+		tx = tx.Where("(created_at > ? OR (created_at = ? AND id > ?))", after.CreatedAt, after.CreatedAt, after.ID)
+	}
+	err := tx.Order("created_at asc").Order("id asc").Limit(limit).Find(&products).Error
+	return products, translateError(err)
+}
 
-	return products, err
+func (db *ProductDB) Count(filter ProductFilter) (int64, error) {
+	var total int64
+	err := filter.apply(db.scoped().Model(&entity.Product{})).Count(&total).Error
+	return total, translateError(err)
 }
 
+// Update retorna ErrStaleVersion se o produto não estiver mais na versão de product.Version
 func (db *ProductDB) Update(product *entity.Product) error {
-	_, err := db.FindByID(product.ID.String())
+	current, err := db.FindByID(product.ID.String())
 	if err != nil {
 		return err
 	}
-	return db.DB.Save(product).Error
+	if current.Version != product.Version {
+		return ErrStaleVersion
+	}
+	next := *product
+	next.Version = product.Version + 1
+	result := db.scoped().Model(&entity.Product{}).
+		Where("id = ? AND version = ? AND deleted_at IS NULL", product.ID, product.Version).
+		Select("*").Omit("id", "tenant_id", "created_at", "deleted_at").
+		Updates(&next)
+	if result.Error != nil {
+		return translateError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrStaleVersion
+	}
+	product.Version = next.Version
+	return nil
 }
 
-func (db *ProductDB) Delete(id string) error {
-	product, err := db.FindByID(id)
-	if err != nil {
+// Delete move o produto para a lixeira, retornando ErrStaleVersion se ele não estiver mais na versão
+func (db *ProductDB) Delete(id string, version int64) error {
+	if _, err := db.FindByID(id); err != nil {
 		return err
 	}
-	return db.DB.Delete(product).Error
+	return db.setDeletedAt(id, version, "deleted_at IS NULL", time.Now())
+}
+
+// Restore tira o produto da lixeira, retornando ErrStaleVersion se ele não estiver mais na versão
+func (db *ProductDB) Restore(id string, version int64) error {
+	if _, err := db.FindDeletedByID(id); err != nil {
+		return err
+	}
+	return db.setDeletedAt(id, version, "deleted_at IS NOT NULL", nil)
+}
+
+// Purge remove definitivamente um produto da lixeira
+func (db *ProductDB) Purge(id string) error {
+	result := db.scoped().Where("id = ? AND deleted_at IS NOT NULL", id).Delete(&entity.Product{})
+	if result.Error != nil {
+		return translateError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// PurgeDeletedBefore é usado pelo job de retenção, em todos os tenants
+func (db *ProductDB) PurgeDeletedBefore(cutoff time.Time) (int64, error) {
+	result := db.scoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Delete(&entity.Product{})
+	return result.RowsAffected, translateError(result.Error)
+}
+
+// setDeletedAt também incrementa a versão, invalidando o ETag
+func (db *ProductDB) setDeletedAt(id string, version int64, condition string, deletedAt interface{}) error {
+	result := db.scoped().Model(&entity.Product{}).
+		Where("id = ? AND version = ? AND "+condition, id, version).
+		Updates(map[string]interface{}{"deleted_at": deletedAt, "version": gorm.Expr("version + 1")})
+	if result.Error != nil {
+		return translateError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrStaleVersion
+	}
+	return nil
 }
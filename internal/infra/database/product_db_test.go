@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"math/rand"
 	"testing"
+	"time"
 
 	"github.com/gsouza97/go-expert-api/internal/entity"
+	entityPkg "github.com/gsouza97/go-expert-api/pkg/entity"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -49,19 +51,19 @@ func TestFindAllProducts(t *testing.T) {
 		assert.NoError(t, err)
 	}
 
-	products, err := productDB.FindAll(1, 10, "asc")
+	products, err := productDB.FindAll(ProductQuery{Page: 1, Limit: 10, Sort: []SortField{{Field: "created_at"}}})
 	assert.NoError(t, err)
 	assert.Len(t, products, 10)
 	assert.Equal(t, "Product 1", products[0].Name)
 	assert.Equal(t, "Product 10", products[9].Name)
 
-	products, err = productDB.FindAll(2, 10, "asc")
+	products, err = productDB.FindAll(ProductQuery{Page: 2, Limit: 10, Sort: []SortField{{Field: "created_at"}}})
 	assert.NoError(t, err)
 	assert.Len(t, products, 10)
 	assert.Equal(t, "Product 11", products[0].Name)
 	assert.Equal(t, "Product 20", products[9].Name)
 
-	products, err = productDB.FindAll(3, 10, "asc")
+	products, err = productDB.FindAll(ProductQuery{Page: 3, Limit: 10, Sort: []SortField{{Field: "created_at"}}})
 	assert.NoError(t, err)
 	assert.Len(t, products, 3)
 	assert.Equal(t, "Product 21", products[0].Name)
@@ -84,6 +86,10 @@ func TestFindProductByID(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "Product 1", p.Name)
 	assert.Equal(t, 10.0, p.Price)
+
+	p, err = productDB.FindByID(entityPkg.NewId().String())
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Nil(t, p)
 }
 
 func TestUpdateProduct(t *testing.T) {
@@ -107,6 +113,34 @@ func TestUpdateProduct(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "Product 2", p.Name)
 	assert.Equal(t, 20.0, p.Price)
+	assert.Equal(t, int64(2), p.Version)
+}
+
+func TestUpdateProduct_StaleVersion(t *testing.T) {
+	db, err := ConnectToTestDBAndMigrate()
+	if err != nil {
+		t.Error(err)
+	}
+	productDB := NewProductDB(db)
+	product, err := entity.NewProduct("Product 1", 10.0)
+	assert.NoError(t, err)
+	assert.NoError(t, productDB.CreateProduct(product))
+
+	// Dois editores leem a mesma versão: só a primeira gravação é aceita
+	first, err := productDB.FindByID(product.ID.String())
+	assert.NoError(t, err)
+	second, err := productDB.FindByID(product.ID.String())
+	assert.NoError(t, err)
+	first.Name = "First"
+	assert.NoError(t, productDB.Update(first))
+	assert.Equal(t, int64(2), first.Version)
+	second.Name = "Second"
+	assert.ErrorIs(t, productDB.Update(second), ErrStaleVersion)
+
+	p, err := productDB.FindByID(product.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, "First", p.Name)
+	assert.Equal(t, int64(2), p.Version)
 }
 
 func TestDeleteProduct(t *testing.T) {
@@ -121,9 +155,289 @@ func TestDeleteProduct(t *testing.T) {
 	assert.NoError(t, err)
 
 	productDB := NewProductDB(db)
-	err = productDB.Delete(product.ID.String())
+	err = productDB.Delete(product.ID.String(), 1)
 	assert.NoError(t, err)
 
 	_, err = productDB.FindByID(product.ID.String())
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	err = productDB.Delete(product.ID.String(), 1)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// O produto continua na lixeira até ser removido definitivamente
+	deleted, err := productDB.FindDeletedByID(product.ID.String())
+	assert.NoError(t, err)
+	assert.NotNil(t, deleted.DeletedAt)
+	assert.Equal(t, int64(2), deleted.Version)
+}
+
+func TestDeleteProduct_StaleVersion(t *testing.T) {
+	db, err := ConnectToTestDBAndMigrate()
+	if err != nil {
+		t.Error(err)
+	}
+	productDB := NewProductDB(db)
+	product, err := entity.NewProduct("Product 1", 10.0)
+	assert.NoError(t, err)
+	assert.NoError(t, productDB.CreateProduct(product))
+
+	// Outra alteração é gravada entre a leitura e a exclusão
+	read, err := productDB.FindByID(product.ID.String())
+	assert.NoError(t, err)
+	concurrent := *read
+	concurrent.Name = "Concurrent"
+	assert.NoError(t, productDB.Update(&concurrent))
+
+	assert.ErrorIs(t, productDB.Delete(product.ID.String(), read.Version), ErrStaleVersion)
+	p, err := productDB.FindByID(product.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, "Concurrent", p.Name)
+	assert.Nil(t, p.DeletedAt)
+}
+
+func TestRestoreProduct(t *testing.T) {
+	db, err := ConnectToTestDBAndMigrate()
+	if err != nil {
+		t.Error(err)
+	}
+	productDB := NewProductDB(db)
+	product, err := entity.NewProduct("Product 1", 10.0)
+	assert.NoError(t, err)
+	assert.NoError(t, productDB.CreateProduct(product))
+
+	assert.ErrorIs(t, productDB.Restore(product.ID.String(), 1), ErrNotFound)
+	assert.NoError(t, productDB.Delete(product.ID.String(), 1))
+	// A restauração também exige a versão atual, que a exclusão incrementou
+	assert.ErrorIs(t, productDB.Restore(product.ID.String(), 1), ErrStaleVersion)
+	assert.NoError(t, productDB.Restore(product.ID.String(), 2))
+
+	p, err := productDB.FindByID(product.ID.String())
+	assert.NoError(t, err)
+	assert.Nil(t, p.DeletedAt)
+	assert.Equal(t, int64(3), p.Version)
+	_, err = productDB.FindDeletedByID(product.ID.String())
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestPurgeProduct(t *testing.T) {
+	db, err := ConnectToTestDBAndMigrate()
+	if err != nil {
+		t.Error(err)
+	}
+	productDB := NewProductDB(db)
+	product, err := entity.NewProduct("Product 1", 10.0)
+	assert.NoError(t, err)
+	assert.NoError(t, productDB.CreateProduct(product))
+
+	// Só produtos na lixeira podem ser removidos definitivamente
+	assert.ErrorIs(t, productDB.Purge(product.ID.String()), ErrNotFound)
+	assert.NoError(t, productDB.Delete(product.ID.String(), 1))
+	assert.NoError(t, productDB.Purge(product.ID.String()))
+
+	var count int64
+	db.Model(&entity.Product{}).Where("id = ?", product.ID.String()).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestPurgeDeletedBefore(t *testing.T) {
+	db, err := ConnectToTestDBAndMigrate()
+	if err != nil {
+		t.Error(err)
+	}
+	productDB := NewProductDB(db)
+	old, _ := entity.NewProduct("Old", 10.0)
+	recent, _ := entity.NewProduct("Recent", 10.0)
+	active, _ := entity.NewProduct("Active", 10.0)
+	for _, p := range []*entity.Product{old, recent, active} {
+		assert.NoError(t, productDB.CreateProduct(p))
+	}
+	assert.NoError(t, productDB.Delete(old.ID.String(), 1))
+	assert.NoError(t, productDB.Delete(recent.ID.String(), 1))
+	db.Model(&entity.Product{}).Where("id = ?", old.ID.String()).Update("deleted_at", time.Now().Add(-48*time.Hour))
+
+	purged, err := productDB.PurgeDeletedBefore(time.Now().Add(-24 * time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	_, err = productDB.FindDeletedByID(old.ID.String())
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = productDB.FindDeletedByID(recent.ID.String())
+	assert.NoError(t, err)
+	_, err = productDB.FindByID(active.ID.String())
+	assert.NoError(t, err)
+}
+
+func TestFindAllProducts_Trash(t *testing.T) {
+	db, err := ConnectToTestDBAndMigrate()
+	if err != nil {
+		t.Error(err)
+	}
+	productDB := NewProductDB(db)
+	kept, _ := entity.NewProduct("Kept", 10.0)
+	trashed, _ := entity.NewProduct("Trashed", 10.0)
+	assert.NoError(t, productDB.CreateProduct(kept))
+	assert.NoError(t, productDB.CreateProduct(trashed))
+	assert.NoError(t, productDB.Delete(trashed.ID.String(), 1))
+
+	products, err := productDB.FindAll(ProductQuery{Page: 1, Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+	assert.Equal(t, "Kept", products[0].Name)
+
+	products, err = productDB.FindAll(ProductQuery{Page: 1, Limit: 10, Filter: ProductFilter{Deleted: true}})
+	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+	assert.Equal(t, "Trashed", products[0].Name)
+}
+
+func TestFindAllProducts_FilterAndSort(t *testing.T) {
+	db, err := ConnectToTestDBAndMigrate()
+	if err != nil {
+		t.Error(err)
+	}
+	productDB := NewProductDB(db)
+
+	base := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, p := range []struct {
+		name  string
+		price float64
+	}{
+		{"Notebook", 3500}, {"Mouse", 50}, {"Mousepad", 30}, {"Monitor", 1200}, {"100%_Cotton Shirt", 80},
+	} {
+		product, err := entity.NewProduct(p.name, p.price)
+		assert.NoError(t, err)
+		product.CreatedAt = base.AddDate(0, 0, i)
+		assert.NoError(t, db.Create(product).Error)
+	}
+
+	products, err := productDB.FindAll(ProductQuery{Filter: ProductFilter{Name: "mouse"}, Sort: []SortField{{Field: "price"}}})
+	assert.NoError(t, err)
+	assert.Len(t, products, 2)
+	assert.Equal(t, "Mousepad", products[0].Name)
+	assert.Equal(t, "Mouse", products[1].Name)
+
+	minPrice, maxPrice := 50.0, 1200.0
+	filter := ProductFilter{MinPrice: &minPrice, MaxPrice: &maxPrice}
+	products, err = productDB.FindAll(ProductQuery{Filter: filter, Sort: []SortField{{Field: "price", Desc: true}}})
+	assert.NoError(t, err)
+	assert.Len(t, products, 3)
+	assert.Equal(t, "Monitor", products[0].Name)
+	assert.Equal(t, "Mouse", products[2].Name)
+
+	total, err := productDB.Count(filter)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+
+	createdAfter := base.AddDate(0, 0, 3)
+	products, err = productDB.FindAll(ProductQuery{Filter: ProductFilter{CreatedAfter: &createdAfter}})
+	assert.NoError(t, err)
+	assert.Len(t, products, 2)
+	assert.Equal(t, "Monitor", products[0].Name)
+
+	// Curingas do LIKE são tratados como texto
+	products, err = productDB.FindAll(ProductQuery{Filter: ProductFilter{Name: "0%_c"}})
+	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+	products, err = productDB.FindAll(ProductQuery{Filter: ProductFilter{Name: "%"}})
+	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+
+	_, err = productDB.FindAll(ProductQuery{Sort: []SortField{{Field: "price; DROP TABLE products"}}})
+	assert.ErrorIs(t, err, ErrInvalidSort)
+}
+
+func TestFindAllProducts_FilterByOwner(t *testing.T) {
+	db, err := ConnectToTestDBAndMigrate()
+	if err != nil {
+		t.Error(err)
+	}
+	productDB := NewProductDB(db)
+
+	owner := entityPkg.NewId()
+	for i, name := range []string{"Mine", "Other", "Legacy"} {
+		product, err := entity.NewProduct(name, 10)
+		assert.NoError(t, err)
+		switch i {
+		case 0:
+			product.CreatedBy = &owner
+		case 1:
+			other := entityPkg.NewId()
+			product.CreatedBy = &other
+		}
+		assert.NoError(t, productDB.CreateProduct(product))
+	}
+
+	filter := ProductFilter{CreatedBy: owner.String()}
+	products, err := productDB.FindAll(ProductQuery{Filter: filter})
+	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+	assert.Equal(t, "Mine", products[0].Name)
+	assert.True(t, products[0].IsOwnedBy(owner))
+	total, err := productDB.Count(filter)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+}
+
+func TestParseProductSort(t *testing.T) {
+	fields, err := ParseProductSort("price:desc,name:asc")
+	assert.NoError(t, err)
+	assert.Equal(t, []SortField{{Field: "price", Desc: true}, {Field: "name"}}, fields)
+
+	fields, err = ParseProductSort("desc")
+	assert.NoError(t, err)
+	assert.Equal(t, []SortField{{Field: "created_at", Desc: true}}, fields)
+
+	fields, err = ParseProductSort("")
+	assert.NoError(t, err)
+	assert.Empty(t, fields)
+
+	_, err = ParseProductSort("id:asc")
+	assert.ErrorIs(t, err, ErrInvalidSort)
+	_, err = ParseProductSort("price:up")
+	assert.ErrorIs(t, err, ErrInvalidSort)
+	_, err = ParseProductSort("price,price:desc")
+	assert.ErrorIs(t, err, ErrInvalidSort)
+}
+
+func TestFindAfterProducts(t *testing.T) {
+	db, err := ConnectToTestDBAndMigrate()
+	if err != nil {
+		t.Error(err)
+	}
+	productDB := NewProductDB(db)
+
+	// Vários produtos com o mesmo created_at para garantir o desempate pelo id
+	createdAt := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 7; i++ {
+		product, err := entity.NewProduct(fmt.Sprintf("Product %d", i), 10)
+		assert.NoError(t, err)
+		product.CreatedAt = createdAt.Add(time.Duration(i/3) * time.Second)
+		assert.NoError(t, db.Create(product).Error)
+	}
+
+	seen := map[string]bool{}
+	var after *ProductCursor
+	for pages := 0; ; pages++ {
+		assert.Less(t, pages, 4)
+		products, err := productDB.FindAfter(after, 3, ProductFilter{})
+		assert.NoError(t, err)
+		if len(products) == 0 {
+			break
+		}
+		for _, p := range products {
+			assert.False(t, seen[p.ID.String()])
+			seen[p.ID.String()] = true
+		}
+		last := products[len(products)-1]
+		after = &ProductCursor{CreatedAt: last.CreatedAt, ID: last.ID.String()}
+
+		// Um produto inserido antes da posição atual não afeta as próximas páginas
+		if pages == 0 {
+			product, err := entity.NewProduct("Inserted", 10)
+			assert.NoError(t, err)
+			product.CreatedAt = createdAt.Add(-time.Hour)
+			assert.NoError(t, db.Create(product).Error)
+		}
+	}
+	assert.Len(t, seen, 7)
 }
@@ -0,0 +1,126 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrInvalidSort = errors.New("invalid sort")
+
+// productSortableFields são os únicos campos aceitos em ORDER BY
+var productSortableFields = map[string]string{
+	"name":       "name",
+	"price":      "price",
+	"created_at": "created_at",
+}
+
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+type ProductFilter struct {
+	Name          string
+	MinPrice      *float64
+	MaxPrice      *float64
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	CreatedBy     string
+	Deleted       bool // Lista apenas os produtos na lixeira
+}
+
+// ProductCursor é a posição do último produto lido na paginação por keyset
+type ProductCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+}
+
+type ProductQuery struct {
+	Page   int
+	Limit  int
+	Filter ProductFilter
+	Sort   []SortField
+}
+
+// ParseProductSort interpreta o parâmetro sort no formato "price:desc,name:asc" ou apenas "asc"/"desc"
+func ParseProductSort(sort string) ([]SortField, error) {
+	sort = strings.TrimSpace(sort)
+	if sort == "" {
+		return nil, nil
+	}
+	if sort == "asc" || sort == "desc" {
+		return []SortField{{Field: "created_at", Desc: sort == "desc"}}, nil
+	}
+
+	var fields []SortField
+	seen := map[string]bool{}
+	for _, part := range strings.Split(sort, ",") {
+		field, direction, _ := strings.Cut(strings.TrimSpace(part), ":")
+		if _, ok := productSortableFields[field]; !ok || seen[field] {
+			return nil, fmt.Errorf("%w: field %q", ErrInvalidSort, field)
+		}
+		switch direction {
+		case "", "asc":
+			fields = append(fields, SortField{Field: field})
+		case "desc":
+			fields = append(fields, SortField{Field: field, Desc: true})
+		default:
+			return nil, fmt.Errorf("%w: direction %q", ErrInvalidSort, direction)
+		}
+		seen[field] = true
+	}
+	return fields, nil
+}
+
+func (f ProductFilter) apply(db *gorm.DB) *gorm.DB {
+	if f.Name != "" {
+		db = db.Where("LOWER(name) LIKE ? ESCAPE '\\'", "%"+escapeLike(strings.ToLower(f.Name))+"%")
+	}
+	if f.MinPrice != nil {
+		db = db.Where("price >= ?", *f.MinPrice)
+	}
+	if f.MaxPrice != nil {
+		db = db.Where("price <= ?", *f.MaxPrice)
+	}
+	if f.CreatedAfter != nil {
+		db = db.Where("created_at >= ?", *f.CreatedAfter)
+	}
+	if f.CreatedBefore != nil {
+		db = db.Where("created_at <= ?", *f.CreatedBefore)
+	}
+	if f.CreatedBy != "" {
+		db = db.Where("created_by = ?", f.CreatedBy)
+	}
+	if f.Deleted {
+		db = db.Where("deleted_at IS NOT NULL")
+	} else {
+		db = db.Where("deleted_at IS NULL")
+	}
+	return db
+}
+
+func applySort(db *gorm.DB, sort []SortField) (*gorm.DB, error) {
+	if len(sort) == 0 {
+		return db.Order("created_at asc"), nil
+	}
+	for _, s := range sort {
+		column, ok := productSortableFields[s.Field]
+		if !ok {
+			return nil, fmt.Errorf("%w: field %q", ErrInvalidSort, s.Field)
+		}
+		if s.Desc {
+			db = db.Order(column + " desc")
+		} else {
+			db = db.Order(column + " asc")
+		}
+	}
+	return db, nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,39 @@
+package database
+
+import (
+	"time"
+
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	"gorm.io/gorm"
+)
+
+type RecoveryCodeDB struct {
+	DB *gorm.DB
+}
+
+func NewRecoveryCodeDB(db *gorm.DB) *RecoveryCodeDB {
+	return &RecoveryCodeDB{DB: db}
+}
+
+// Replace troca os códigos do usuário pelos novos, invalidando os anteriores
+func (db *RecoveryCodeDB) Replace(userID string, codes []*entity.RecoveryCode) error {
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&entity.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(codes).Error
+	})
+	return translateError(err)
+}
+
+// Use consome o código. Retorna false se ele não existir ou já tiver sido usado.
+func (db *RecoveryCodeDB) Use(userID, hash string) (bool, error) {
+	result := db.DB.Model(&entity.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", time.Now())
+	return result.RowsAffected == 1, translateError(result.Error)
+}
+
+func (db *RecoveryCodeDB) DeleteByUser(userID string) error {
+	return translateError(db.DB.Where("user_id = ?", userID).Delete(&entity.RecoveryCode{}).Error)
+}
@@ -0,0 +1,53 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	entityPkg "github.com/gsouza97/go-expert-api/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestRecoveryCodeDB(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.AutoMigrate(&entity.RecoveryCode{})
+	codeDB := NewRecoveryCodeDB(db)
+	userID := entityPkg.NewId()
+
+	codes, plain, err := entity.NewRecoveryCodes(userID, 2)
+	assert.NoError(t, err)
+	assert.NoError(t, codeDB.Replace(userID.String(), codes))
+
+	used, err := codeDB.Use(userID.String(), entity.HashRecoveryCode(plain[0]))
+	assert.NoError(t, err)
+	assert.True(t, used)
+	used, err = codeDB.Use(userID.String(), entity.HashRecoveryCode(plain[0]))
+	assert.NoError(t, err)
+	assert.False(t, used)
+
+	// Códigos de outro usuário não são aceitos
+	used, err = codeDB.Use(entityPkg.NewId().String(), entity.HashRecoveryCode(plain[1]))
+	assert.NoError(t, err)
+	assert.False(t, used)
+
+	// Gerar novos códigos invalida os anteriores
+	newCodes, newPlain, err := entity.NewRecoveryCodes(userID, 2)
+	assert.NoError(t, err)
+	assert.NoError(t, codeDB.Replace(userID.String(), newCodes))
+	used, err = codeDB.Use(userID.String(), entity.HashRecoveryCode(plain[1]))
+	assert.NoError(t, err)
+	assert.False(t, used)
+	used, err = codeDB.Use(userID.String(), entity.HashRecoveryCode(newPlain[1]))
+	assert.NoError(t, err)
+	assert.True(t, used)
+
+	assert.NoError(t, codeDB.DeleteByUser(userID.String()))
+	used, err = codeDB.Use(userID.String(), entity.HashRecoveryCode(newPlain[0]))
+	assert.NoError(t, err)
+	assert.False(t, used)
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"time"
+
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	"gorm.io/gorm"
+)
+
+type RefreshTokenDB struct {
+	DB *gorm.DB
+}
+
+func NewRefreshTokenDB(db *gorm.DB) *RefreshTokenDB {
+	return &RefreshTokenDB{DB: db}
+}
+
+func (db *RefreshTokenDB) Create(token *entity.RefreshToken) error {
+	return translateError(db.DB.Create(token).Error)
+}
+
+func (db *RefreshTokenDB) FindByHash(hash string) (*entity.RefreshToken, error) {
+	var token entity.RefreshToken
+	err := db.DB.Where("token_hash = ?", hash).First(&token).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &token, nil
+}
+
+// Revoke retorna false se o token já estava revogado, o que detecta usos concorrentes
+func (db *RefreshTokenDB) Revoke(id string) (bool, error) {
+	result := db.DB.Model(&entity.RefreshToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	return result.RowsAffected == 1, translateError(result.Error)
+}
+
+func (db *RefreshTokenDB) RevokeFamily(familyID string) error {
+	err := db.DB.Model(&entity.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+	return translateError(err)
+}
+
+func (db *RefreshTokenDB) RevokeAllByUser(userID string) error {
+	err := db.DB.Model(&entity.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+	return translateError(err)
+}
+
+type RevokedTokenDB struct {
+	DB *gorm.DB
+}
+
+func NewRevokedTokenDB(db *gorm.DB) *RevokedTokenDB {
+	return &RevokedTokenDB{DB: db}
+}
+
+func (db *RevokedTokenDB) Revoke(jti string, expiresAt time.Time) error {
+	return translateError(db.DB.Save(&entity.RevokedToken{JTI: jti, ExpiresAt: expiresAt}).Error)
+}
+
+func (db *RevokedTokenDB) IsRevoked(jti string) (bool, error) {
+	var count int64
+	err := db.DB.Model(&entity.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error
+	return count > 0, translateError(err)
+}
+
+// DeleteExpired remove da denylist os tokens que já expiraram e não precisam mais ser bloqueados
+func (db *RevokedTokenDB) DeleteExpired() error {
+	return translateError(db.DB.Where("expires_at < ?", time.Now()).Delete(&entity.RevokedToken{}).Error)
+}
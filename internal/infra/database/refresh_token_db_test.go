@@ -0,0 +1,114 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	entityPkg "github.com/gsouza97/go-expert-api/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func connectToTokenTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.AutoMigrate(&entity.RefreshToken{}, &entity.RevokedToken{})
+	return db
+}
+
+func TestRefreshTokenDB_CreateAndFindByHash(t *testing.T) {
+	db := connectToTokenTestDB(t)
+	tokenDB := NewRefreshTokenDB(db)
+
+	token, plain, err := entity.NewRefreshToken(entityPkg.NewId(), entityPkg.NewId(), time.Hour)
+	assert.NoError(t, err)
+	assert.NoError(t, tokenDB.Create(token))
+
+	found, err := tokenDB.FindByHash(entity.HashToken(plain))
+	assert.NoError(t, err)
+	assert.Equal(t, token.ID, found.ID)
+	assert.Equal(t, token.FamilyID, found.FamilyID)
+	assert.False(t, found.IsRevoked())
+
+	_, err = tokenDB.FindByHash(entity.HashToken("unknown"))
+	assert.Error(t, err)
+}
+
+func TestRefreshTokenDB_Revoke(t *testing.T) {
+	db := connectToTokenTestDB(t)
+	tokenDB := NewRefreshTokenDB(db)
+
+	token, plain, err := entity.NewRefreshToken(entityPkg.NewId(), entityPkg.NewId(), time.Hour)
+	assert.NoError(t, err)
+	assert.NoError(t, tokenDB.Create(token))
+
+	revoked, err := tokenDB.Revoke(token.ID.String())
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	revoked, err = tokenDB.Revoke(token.ID.String())
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	found, err := tokenDB.FindByHash(entity.HashToken(plain))
+	assert.NoError(t, err)
+	assert.True(t, found.IsRevoked())
+}
+
+func TestRefreshTokenDB_RevokeFamily(t *testing.T) {
+	db := connectToTokenTestDB(t)
+	tokenDB := NewRefreshTokenDB(db)
+
+	userID := entityPkg.NewId()
+	familyID := entityPkg.NewId()
+	first, firstPlain, _ := entity.NewRefreshToken(userID, familyID, time.Hour)
+	second, secondPlain, _ := entity.NewRefreshToken(userID, familyID, time.Hour)
+	other, otherPlain, _ := entity.NewRefreshToken(userID, entityPkg.NewId(), time.Hour)
+	assert.NoError(t, tokenDB.Create(first))
+	assert.NoError(t, tokenDB.Create(second))
+	assert.NoError(t, tokenDB.Create(other))
+
+	assert.NoError(t, tokenDB.RevokeFamily(familyID.String()))
+
+	for _, plain := range []string{firstPlain, secondPlain} {
+		found, err := tokenDB.FindByHash(entity.HashToken(plain))
+		assert.NoError(t, err)
+		assert.True(t, found.IsRevoked())
+	}
+	found, err := tokenDB.FindByHash(entity.HashToken(otherPlain))
+	assert.NoError(t, err)
+	assert.False(t, found.IsRevoked())
+
+	assert.NoError(t, tokenDB.RevokeAllByUser(userID.String()))
+	found, err = tokenDB.FindByHash(entity.HashToken(otherPlain))
+	assert.NoError(t, err)
+	assert.True(t, found.IsRevoked())
+}
+
+func TestRevokedTokenDB(t *testing.T) {
+	db := connectToTokenTestDB(t)
+	revokedDB := NewRevokedTokenDB(db)
+
+	revoked, err := revokedDB.IsRevoked("jti-1")
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	assert.NoError(t, revokedDB.Revoke("jti-1", time.Now().Add(time.Hour)))
+	assert.NoError(t, revokedDB.Revoke("jti-2", time.Now().Add(-time.Hour)))
+
+	revoked, err = revokedDB.IsRevoked("jti-1")
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	assert.NoError(t, revokedDB.DeleteExpired())
+	revoked, err = revokedDB.IsRevoked("jti-2")
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+	revoked, err = revokedDB.IsRevoked("jti-1")
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+}
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	entityPkg "github.com/gsouza97/go-expert-api/pkg/entity"
+	"gorm.io/gorm"
+)
+
+type TenantDB struct {
+	DB *gorm.DB
+}
+
+func NewTenantDB(db *gorm.DB) *TenantDB {
+	return &TenantDB{DB: db}
+}
+
+func (db *TenantDB) Create(tenant *entity.Tenant) error {
+	return translateError(db.DB.Create(tenant).Error)
+}
+
+func (db *TenantDB) FindByID(id string) (*entity.Tenant, error) {
+	var tenant entity.Tenant
+	err := db.DB.Where("id = ?", id).First(&tenant).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &tenant, nil
+}
+
+func (db *TenantDB) FindBySlug(slug string) (*entity.Tenant, error) {
+	var tenant entity.Tenant
+	err := db.DB.Where("slug = ?", slug).First(&tenant).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &tenant, nil
+}
+
+func (db *TenantDB) FindAll() ([]*entity.Tenant, error) {
+	var tenants []*entity.Tenant
+	err := db.DB.Order("slug asc").Find(&tenants).Error
+	return tenants, translateError(err)
+}
+
+// tenantScope restringe a consulta ao tenant. Sem tenant enxerga todos, como no login e nos jobs.
+func tenantScope(db *gorm.DB, tenantID string) *gorm.DB {
+	if tenantID == "" {
+		return db
+	}
+	return db.Where("tenant_id = ?", tenantID)
+}
+
+// setTenant grava no registro o tenant do repositório, ignorando o valor que ele trazia
+func setTenant(target *entityPkg.ID, tenantID string) error {
+	id, err := entityPkg.ParseId(tenantID)
+	if err != nil {
+		return err
+	}
+	*target = id
+	return nil
+}
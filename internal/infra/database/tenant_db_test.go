@@ -0,0 +1,122 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	entityPkg "github.com/gsouza97/go-expert-api/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func connectToTenantTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.AutoMigrate(&entity.Tenant{}, &entity.User{}, &entity.Product{})
+	return db
+}
+
+func TestTenantDB(t *testing.T) {
+	tenantDB := NewTenantDB(connectToTenantTestDB(t))
+
+	acme, err := entity.NewTenant("Acme", "acme")
+	assert.NoError(t, err)
+	assert.NoError(t, tenantDB.Create(acme))
+	duplicate, err := entity.NewTenant("Acme 2", "ACME")
+	assert.NoError(t, err)
+	assert.ErrorIs(t, tenantDB.Create(duplicate), ErrConflict)
+
+	found, err := tenantDB.FindBySlug("acme")
+	assert.NoError(t, err)
+	assert.Equal(t, acme.ID, found.ID)
+	_, err = tenantDB.FindBySlug("globex")
+	assert.ErrorIs(t, err, ErrNotFound)
+	found, err = tenantDB.FindByID(acme.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, "Acme", found.Name)
+}
+
+func TestUserDB_ForTenant(t *testing.T) {
+	userDB := NewUserDB(connectToTenantTestDB(t))
+	acme := entityPkg.NewId().String()
+	globex := entityPkg.NewId().String()
+
+	john, _ := entity.NewUser("John", "john@acme.com", "12345678")
+	// O tenant informado no registro é substituído pelo do repositório
+	john.TenantID = entityPkg.NewId()
+	assert.NoError(t, userDB.ForTenant(acme).CreateUser(john))
+	assert.Equal(t, acme, john.TenantID.String())
+	bob, _ := entity.NewUser("Bob", "bob@globex.com", "12345678")
+	assert.NoError(t, userDB.ForTenant(globex).CreateUser(bob))
+
+	users := userDB.ForTenant(globex)
+	_, err := users.FindByID(john.ID.String())
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = users.FindByEmail("john@acme.com")
+	assert.ErrorIs(t, err, ErrNotFound)
+	all, err := users.FindAll(UserQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, all, 1)
+	assert.Equal(t, bob.ID, all[0].ID)
+	total, err := users.Count(UserFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+
+	john.Name = "Stolen"
+	assert.ErrorIs(t, users.Update(john), ErrNotFound)
+	assert.ErrorIs(t, users.RevokeSessions(john.ID.String()), ErrNotFound)
+	verified, err := users.VerifyEmail(john.ID.String(), john.Email, time.Now())
+	assert.NoError(t, err)
+	assert.False(t, verified)
+	assert.ErrorIs(t, users.Delete(john.ID.String()), ErrNotFound)
+
+	// A atualização pelo próprio tenant não consegue mover o usuário
+	john.TenantID, _ = entityPkg.ParseId(globex)
+	assert.NoError(t, userDB.ForTenant(acme).Update(john))
+	found, err := userDB.FindByID(john.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, "Stolen", found.Name)
+	assert.Equal(t, acme, found.TenantID.String())
+	assert.Equal(t, int64(0), found.SessionVersion)
+
+	// Sem tenant o repositório enxerga todos, como no login
+	found, err = userDB.FindByEmail("bob@globex.com")
+	assert.NoError(t, err)
+	assert.Equal(t, globex, found.TenantID.String())
+}
+
+func TestProductDB_ForTenant(t *testing.T) {
+	productDB := NewProductDB(connectToTenantTestDB(t))
+	acme := entityPkg.NewId().String()
+	globex := entityPkg.NewId().String()
+
+	mouse, _ := entity.NewProduct("Mouse", 10)
+	assert.NoError(t, productDB.ForTenant(acme).CreateProduct(mouse))
+	keyboard, _ := entity.NewProduct("Keyboard", 20)
+	assert.NoError(t, productDB.ForTenant(globex).CreateProduct(keyboard))
+
+	products := productDB.ForTenant(globex)
+	_, err := products.FindByID(mouse.ID.String())
+	assert.ErrorIs(t, err, ErrNotFound)
+	all, err := products.FindAll(ProductQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, all, 1)
+	assert.Equal(t, "Keyboard", all[0].Name)
+	total, err := products.Count(ProductFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	page, err := products.FindAfter(nil, 10, ProductFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, page, 1)
+
+	mouse.Name = "Stolen"
+	assert.ErrorIs(t, products.Update(mouse), ErrNotFound)
+	assert.ErrorIs(t, products.Delete(mouse.ID.String(), mouse.Version), ErrNotFound)
+	found, err := productDB.ForTenant(acme).FindByID(mouse.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, "Mouse", found.Name)
+}
@@ -1,27 +1,143 @@
 package database
 
 import (
+	"time"
+
 	"github.com/gsouza97/go-expert-api/internal/entity"
 	"gorm.io/gorm"
 )
 
 type UserDB struct {
-	DB *gorm.DB
+	DB       *gorm.DB
+	TenantID string // Restringe as consultas ao tenant
 }
 
 func NewUserDB(db *gorm.DB) *UserDB {
 	return &UserDB{DB: db}
 }
 
+// ForTenant retorna uma cópia do repositório que só lê e grava os usuários do tenant
+func (db *UserDB) ForTenant(tenantID string) UserDBInterface {
+	return &UserDB{DB: db.DB, TenantID: tenantID}
+}
+
+func (db *UserDB) scoped() *gorm.DB {
+	return tenantScope(db.DB, db.TenantID)
+}
+
 func (db *UserDB) CreateUser(user *entity.User) error {
-	return db.DB.Create(user).Error
+	if db.TenantID != "" {
+		if err := setTenant(&user.TenantID, db.TenantID); err != nil {
+			return err
+		}
+	}
+	return translateError(db.DB.Create(user).Error)
 }
 
 func (db *UserDB) FindByEmail(email string) (*entity.User, error) {
 	var user entity.User
-	err := db.DB.Where("email = ?", email).First(&user).Error
+	err := db.scoped().Where("email = ?", entity.NormalizeEmail(email)).First(&user).Error
 	if err != nil {
-		return nil, err
+		return nil, translateError(err)
 	}
 	return &user, nil
 }
+
+func (db *UserDB) FindByID(id string) (*entity.User, error) {
+	var user entity.User
+	err := db.scoped().Where("id = ?", id).First(&user).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &user, nil
+}
+
+// FindAll lista os usuários ordenados pelo email
+func (db *UserDB) FindAll(query UserQuery) ([]*entity.User, error) {
+	var users []*entity.User
+	tx := query.Filter.apply(db.scoped()).Order("email asc")
+	if query.Page != 0 && query.Limit != 0 {
+		tx = tx.Limit(query.Limit).Offset((query.Page - 1) * query.Limit)
+	}
+	err := tx.Find(&users).Error
+	return users, translateError(err)
+}
+
+func (db *UserDB) Count(filter UserFilter) (int64, error) {
+	var total int64
+	err := filter.apply(db.scoped().Model(&entity.User{})).Count(&total).Error
+	return total, translateError(err)
+}
+
+// Update não grava o SessionVersion, para não reativar tokens revogados depois da leitura, nem o tenant
+func (db *UserDB) Update(user *entity.User) error {
+	_, err := db.FindByID(user.ID.String())
+	if err != nil {
+		return err
+	}
+	return translateError(db.scoped().Omit("session_version", "tenant_id").Save(user).Error)
+}
+
+// RevokeSessions invalida todos os access tokens já emitidos para o usuário
+func (db *UserDB) RevokeSessions(id string) error {
+	result := db.scoped().Model(&entity.User{}).
+		Where("id = ?", id).
+		Update("session_version", gorm.Expr("session_version + 1"))
+	if result.Error != nil {
+		return translateError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// MoveToTenant também revoga as sessões, cujos tokens carregam o tenant antigo
+func (db *UserDB) MoveToTenant(id, tenantID string) error {
+	result := db.scoped().Model(&entity.User{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"tenant_id":       tenantID,
+			"session_version": gorm.Expr("session_version + 1"),
+		})
+	if result.Error != nil {
+		return translateError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// VerifyEmail retorna false se o usuário não existir, tiver trocado de email ou já estiver verificado
+func (db *UserDB) VerifyEmail(id, email string, verifiedAt time.Time) (bool, error) {
+	result := db.scoped().Model(&entity.User{}).
+		Where("id = ? AND email = ? AND email_verified_at IS NULL", id, email).
+		Update("email_verified_at", verifiedAt)
+	return result.RowsAffected == 1, translateError(result.Error)
+}
+
+// UseTOTPStep retorna false se um código do mesmo período ou de um posterior já tiver sido usado
+func (db *UserDB) UseTOTPStep(id string, step int64) (bool, error) {
+	result := db.scoped().Model(&entity.User{}).
+		Where("id = ? AND totp_last_step < ?", id, step).
+		Update("totp_last_step", step)
+	return result.RowsAffected == 1, translateError(result.Error)
+}
+
+// Delete também remove as sessões, os tokens, os códigos de recuperação e as chaves da API do usuário
+func (db *UserDB) Delete(id string) error {
+	user, err := db.FindByID(id)
+	if err != nil {
+		return err
+	}
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&entity.RefreshToken{}, &entity.PasswordResetToken{}, &entity.RecoveryCode{}, &entity.APIKey{}} {
+			if err := tx.Where("user_id = ?", user.ID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(user).Error
+	})
+	return translateError(err)
+}
@@ -1,7 +1,9 @@
 package database
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gsouza97/go-expert-api/internal/entity"
 	"github.com/stretchr/testify/assert"
@@ -16,7 +18,7 @@ func TestCreateUser(t *testing.T) {
 	}
 	db.AutoMigrate(&entity.User{})
 
-	user, _ := entity.NewUser("John", "johndoe@test.com", "123456")
+	user, _ := entity.NewUser("John", "johndoe@test.com", "12345678")
 	userDB := NewUserDB(db)
 
 	err = userDB.CreateUser(user)
@@ -37,7 +39,7 @@ func TestFindByEmail(t *testing.T) {
 		t.Error(err)
 	}
 	db.AutoMigrate(&entity.User{})
-	user, _ := entity.NewUser("John", "johndoe@test.com", "123456")
+	user, _ := entity.NewUser("John", "johndoe@test.com", "12345678")
 	userDB := NewUserDB(db)
 
 	err = userDB.CreateUser(user)
@@ -50,3 +52,204 @@ func TestFindByEmail(t *testing.T) {
 	assert.Equal(t, user.Email, userFound.Email)
 	assert.NotNil(t, userFound.Password)
 }
+
+func TestFindByEmail_Normalized(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	db.AutoMigrate(&entity.User{})
+	user, _ := entity.NewUser("John", "johndoe@test.com", "12345678")
+	userDB := NewUserDB(db)
+	assert.Nil(t, userDB.CreateUser(user))
+
+	userFound, err := userDB.FindByEmail(" JohnDoe@Test.com ")
+	assert.Nil(t, err)
+	assert.Equal(t, user.ID, userFound.ID)
+}
+
+func TestCreateUser_DuplicateEmail(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	db.AutoMigrate(&entity.User{})
+	userDB := NewUserDB(db)
+
+	user, _ := entity.NewUser("John", "johndoe@test.com", "12345678")
+	assert.Nil(t, userDB.CreateUser(user))
+
+	other, _ := entity.NewUser("Johnny", "JOHNDOE@test.com", "87654321")
+	err = userDB.CreateUser(other)
+	assert.ErrorIs(t, err, ErrConflict)
+}
+
+func TestVerifyEmail(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	db.AutoMigrate(&entity.User{})
+	user, _ := entity.NewUser("John", "johndoe@test.com", "12345678")
+	userDB := NewUserDB(db)
+	assert.Nil(t, userDB.CreateUser(user))
+
+	verified, err := userDB.VerifyEmail(user.ID.String(), "other@test.com", time.Now())
+	assert.Nil(t, err)
+	assert.False(t, verified)
+
+	verified, err = userDB.VerifyEmail(user.ID.String(), user.Email, time.Now())
+	assert.Nil(t, err)
+	assert.True(t, verified)
+
+	userFound, err := userDB.FindByID(user.ID.String())
+	assert.Nil(t, err)
+	assert.True(t, userFound.IsEmailVerified())
+
+	// O mesmo token não pode ser usado duas vezes
+	verified, err = userDB.VerifyEmail(user.ID.String(), user.Email, time.Now())
+	assert.Nil(t, err)
+	assert.False(t, verified)
+}
+
+func TestUseTOTPStep(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	db.AutoMigrate(&entity.User{})
+	user, _ := entity.NewUser("John", "johndoe@test.com", "12345678")
+	userDB := NewUserDB(db)
+	assert.Nil(t, userDB.CreateUser(user))
+
+	used, err := userDB.UseTOTPStep(user.ID.String(), 100)
+	assert.Nil(t, err)
+	assert.True(t, used)
+
+	// O mesmo período, ou um anterior, não pode ser reutilizado
+	for _, step := range []int64{100, 99} {
+		used, err = userDB.UseTOTPStep(user.ID.String(), step)
+		assert.Nil(t, err)
+		assert.False(t, used)
+	}
+
+	userFound, err := userDB.FindByID(user.ID.String())
+	assert.Nil(t, err)
+	assert.Equal(t, int64(100), userFound.TOTPLastStep)
+}
+
+func TestDeleteUser(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	db.AutoMigrate(&entity.User{}, &entity.RefreshToken{}, &entity.PasswordResetToken{}, &entity.RecoveryCode{}, &entity.APIKey{})
+	user, _ := entity.NewUser("John", "johndoe@test.com", "12345678")
+	other, _ := entity.NewUser("Jane", "janedoe@test.com", "12345678")
+	userDB := NewUserDB(db)
+	assert.Nil(t, userDB.CreateUser(user))
+	assert.Nil(t, userDB.CreateUser(other))
+	for _, u := range []*entity.User{user, other} {
+		token, _, err := entity.NewRefreshToken(u.ID, u.ID, time.Hour)
+		assert.Nil(t, err)
+		assert.Nil(t, NewRefreshTokenDB(db).Create(token))
+	}
+
+	assert.Nil(t, userDB.Delete(user.ID.String()))
+	_, err = userDB.FindByID(user.ID.String())
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, userDB.Delete(user.ID.String()), ErrNotFound)
+
+	// Apenas as sessões do usuário removido são apagadas
+	var count int64
+	db.Model(&entity.RefreshToken{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+	_, err = userDB.FindByID(other.ID.String())
+	assert.Nil(t, err)
+}
+
+func TestFindAllUsers(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	db.AutoMigrate(&entity.User{})
+	userDB := NewUserDB(db)
+	for _, name := range []string{"John", "Jane", "Mary", "Jack_"} {
+		user, err := entity.NewUser(name, strings.ToLower(name)+"@test.com", "12345678")
+		assert.Nil(t, err)
+		if name == "Mary" {
+			user.Role = entity.RoleAdmin
+			user.Disable(time.Now())
+		}
+		assert.Nil(t, userDB.CreateUser(user))
+	}
+
+	users, err := userDB.FindAll(UserQuery{Page: 1, Limit: 2})
+	assert.Nil(t, err)
+	assert.Len(t, users, 2)
+	assert.Equal(t, "jack_@test.com", users[0].Email)
+	assert.Equal(t, "jane@test.com", users[1].Email)
+
+	users, err = userDB.FindAll(UserQuery{Filter: UserFilter{Search: "JA"}})
+	assert.Nil(t, err)
+	assert.Len(t, users, 2)
+
+	// Os curingas do LIKE são tratados como texto
+	users, err = userDB.FindAll(UserQuery{Filter: UserFilter{Search: "_"}})
+	assert.Nil(t, err)
+	assert.Len(t, users, 1)
+
+	disabled := true
+	total, err := userDB.Count(UserFilter{Role: entity.RoleAdmin, Disabled: &disabled})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), total)
+	disabled = false
+	total, err = userDB.Count(UserFilter{Disabled: &disabled})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), total)
+}
+
+func TestRevokeSessions(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	db.AutoMigrate(&entity.User{})
+	user, _ := entity.NewUser("John", "johndoe@test.com", "12345678")
+	userDB := NewUserDB(db)
+	assert.Nil(t, userDB.CreateUser(user))
+
+	assert.Nil(t, userDB.RevokeSessions(user.ID.String()))
+	assert.ErrorIs(t, userDB.RevokeSessions("unknown"), ErrNotFound)
+
+	// Um Update com o usuário carregado antes da revogação não volta a versão
+	user.Name = "Johnny"
+	assert.Nil(t, userDB.Update(user))
+	userFound, err := userDB.FindByID(user.ID.String())
+	assert.Nil(t, err)
+	assert.Equal(t, "Johnny", userFound.Name)
+	assert.Equal(t, int64(1), userFound.SessionVersion)
+}
+
+func TestMoveToTenant(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	db.AutoMigrate(&entity.User{})
+	user, _ := entity.NewUser("John", "johndoe@test.com", "12345678")
+	userDB := NewUserDB(db)
+	assert.Nil(t, userDB.CreateUser(user))
+	tenant, err := entity.NewTenant("globex", "Globex")
+	assert.Nil(t, err)
+	tenantID := tenant.ID.String()
+
+	assert.Nil(t, userDB.MoveToTenant(user.ID.String(), tenantID))
+	assert.ErrorIs(t, userDB.MoveToTenant("unknown", tenantID), ErrNotFound)
+
+	userFound, err := userDB.ForTenant(tenantID).FindByID(user.ID.String())
+	assert.Nil(t, err)
+	assert.Equal(t, tenantID, userFound.TenantID.String())
+	assert.Equal(t, int64(1), userFound.SessionVersion)
+}
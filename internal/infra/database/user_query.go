@@ -0,0 +1,37 @@
+package database
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+type UserFilter struct {
+	Search   string // Parte do nome ou do email
+	Role     string
+	Disabled *bool
+}
+
+type UserQuery struct {
+	Page   int
+	Limit  int
+	Filter UserFilter
+}
+
+func (f UserFilter) apply(db *gorm.DB) *gorm.DB {
+	if f.Search != "" {
+		pattern := "%" + escapeLike(strings.ToLower(strings.TrimSpace(f.Search))) + "%"
+		db = db.Where("(LOWER(name) LIKE ? ESCAPE '\\' OR email LIKE ? ESCAPE '\\')", pattern, pattern)
+	}
+	if f.Role != "" {
+		db = db.Where("role = ?", f.Role)
+	}
+	if f.Disabled != nil {
+		if *f.Disabled {
+			db = db.Where("disabled_at IS NOT NULL")
+		} else {
+			db = db.Where("disabled_at IS NULL")
+		}
+	}
+	return db
+}
@@ -0,0 +1,43 @@
+// Package mail envia os emails transacionais da API (ex: verificação de conta)
+package mail
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string // Texto puro
+}
+
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// format monta a mensagem no formato RFC 5322 usado pelo SMTP e pelos arquivos .eml
+func format(from string, msg Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// validate impede quebras de linha nos cabeçalhos (header injection)
+func validate(msg Message) error {
+	if msg.To == "" {
+		return fmt.Errorf("mail: recipient is required")
+	}
+	if strings.ContainsAny(msg.To+msg.Subject, "\r\n") {
+		return fmt.Errorf("mail: headers must not contain line breaks")
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package mail
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileMailer_Send(t *testing.T) {
+	dir := t.TempDir()
+	mailer := NewFileMailer(dir, "no-reply@test.com")
+
+	err := mailer.Send(Message{To: "johndoe@test.com", Subject: "Hello", Body: "line 1\nline 2"})
+	assert.NoError(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	content, err := os.ReadFile(files[0])
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "From: no-reply@test.com\r\n")
+	assert.Contains(t, string(content), "To: johndoe@test.com\r\n")
+	assert.Contains(t, string(content), "Subject: Hello\r\n")
+	assert.True(t, strings.HasSuffix(string(content), "\r\n\r\nline 1\r\nline 2"))
+}
+
+func TestMemoryMailer_Send(t *testing.T) {
+	mailer := NewMemoryMailer()
+	assert.NoError(t, mailer.Send(Message{To: "johndoe@test.com", Subject: "Hello", Body: "body"}))
+	assert.Equal(t, []Message{{To: "johndoe@test.com", Subject: "Hello", Body: "body"}}, mailer.Messages())
+}
+
+func TestSend_RejectsHeaderInjection(t *testing.T) {
+	mailer := NewMemoryMailer()
+	assert.Error(t, mailer.Send(Message{To: "johndoe@test.com\r\nBcc: other@test.com", Subject: "Hello"}))
+	assert.Error(t, mailer.Send(Message{To: "johndoe@test.com", Subject: "Hello\nBcc: other@test.com"}))
+	assert.Error(t, mailer.Send(Message{Subject: "Hello"}))
+	assert.Empty(t, mailer.Messages())
+}
@@ -0,0 +1,412 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth"
+	"github.com/gsouza97/go-expert-api/internal/dto"
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	"github.com/gsouza97/go-expert-api/internal/infra/database"
+	"github.com/gsouza97/go-expert-api/internal/infra/webserver/problem"
+)
+
+// maxLockoutEvents limita a listagem de bloqueios aos mais recentes
+const maxLockoutEvents = 100
+
+// AdminHandler agrupa os endpoints restritos a quem tem a permissão users:manage
+type AdminHandler struct {
+	UserDB         database.UserDBInterface
+	RefreshTokenDB database.RefreshTokenDBInterface
+	ThrottleDB     database.LoginThrottleDBInterface
+	Throttle       *LoginThrottler
+	MaxPageSize    int
+}
+
+func NewAdminHandler(userDB database.UserDBInterface, refreshTokenDB database.RefreshTokenDBInterface, throttleDB database.LoginThrottleDBInterface, throttle *LoginThrottler, maxPageSize int) *AdminHandler {
+	return &AdminHandler{
+		UserDB:         userDB,
+		RefreshTokenDB: refreshTokenDB,
+		ThrottleDB:     throttleDB,
+		Throttle:       throttle,
+		MaxPageSize:    maxPageSize,
+	}
+}
+
+// List lockouts godoc
+// @Summary      List lockouts
+// @Description  List the most recent login lockouts of the tenant, by account (email:) or client IP (ip:). A lockout belongs to the tenant of the account whose failed login caused it; lockouts caused by unknown accounts are not listed.
+// @Tags         admin
+// @Produce      json
+// @Param        active    query     bool  false  "only lockouts still in effect"
+// @Success      200  {array}   entity.LockoutEvent
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
+// @Router       /admin/lockouts [get]
+// @Security	 ApiKeyAuth
+func (h *AdminHandler) GetLockouts(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := currentTenant(w, r)
+	if !ok {
+		return
+	}
+	activeOnly := r.URL.Query().Get("active") == "true"
+	events, err := h.ThrottleDB.FindLockouts(tenantID, activeOnly, maxLockoutEvents)
+	if err != nil {
+		writeRepositoryError(w, r, err, "lockout not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(events)
+}
+
+// Unlock user godoc
+// @Summary      Unlock user
+// @Description  Remove the login lockout of a user before it expires
+// @Tags         admin
+// @Param		 id    path    string    true  "user id"  Format(uuid)
+// @Success      204
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
+// @Router       /admin/users/{id}/unlock [post]
+// @Security	 ApiKeyAuth
+func (h *AdminHandler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	users, ok := h.users(w, r)
+	if !ok {
+		return
+	}
+	u, err := users.FindByID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeRepositoryError(w, r, err, "user not found")
+		return
+	}
+	if err := h.Throttle.Unlock(u.Email); err != nil {
+		writeRepositoryError(w, r, err, "user not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// List users godoc
+// @Summary      List users
+// @Description  List users ordered by email, optionally searching by name or email
+// @Tags         admin
+// @Produce      json
+// @Param        page      query     int     false  "page number"
+// @Param        limit     query     int     false  "items per page"
+// @Param        q         query     string  false  "part of the name or email"
+// @Param        role      query     string  false  "role"  Enums(admin, editor, viewer)
+// @Param        disabled  query     bool    false  "only disabled (true) or enabled (false) accounts"
+// @Success      200  {object}  dto.UserListOutput
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
+// @Router       /admin/users [get]
+// @Security	 ApiKeyAuth
+func (h *AdminHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
+	query, err := parseUserQuery(r.URL.Query())
+	if err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+	if query.Page == 0 {
+		query.Page = 1
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultPageSize
+	}
+	if h.MaxPageSize > 0 && query.Limit > h.MaxPageSize {
+		query.Limit = h.MaxPageSize
+	}
+
+	tenantUsers, ok := h.users(w, r)
+	if !ok {
+		return
+	}
+	users, err := tenantUsers.FindAll(query)
+	if err != nil {
+		writeRepositoryError(w, r, err, "user not found")
+		return
+	}
+	total, err := tenantUsers.Count(query.Filter)
+	if err != nil {
+		writeRepositoryError(w, r, err, "user not found")
+		return
+	}
+
+	if users == nil {
+		users = []*entity.User{}
+	}
+	output := dto.UserListOutput{
+		Items:      users,
+		Page:       query.Page,
+		Limit:      query.Limit,
+		Total:      total,
+		TotalPages: totalPages(total, query.Limit),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Total-Count", strconv.FormatInt(total, 10))
+	w.Header().Set("Link", paginationLinks(r.URL, output.Page, output.Limit, output.TotalPages))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+}
+
+// Get user godoc
+// @Summary      Get user
+// @Description  Get a user by id
+// @Tags         admin
+// @Produce      json
+// @Param		 id    path    string    true  "user id"  Format(uuid)
+// @Success      200  {object}  entity.User
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
+// @Router       /admin/users/{id} [get]
+// @Security	 ApiKeyAuth
+func (h *AdminHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	users, ok := h.users(w, r)
+	if !ok {
+		return
+	}
+	u, err := users.FindByID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeRepositoryError(w, r, err, "user not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(u)
+}
+
+// Update user role godoc
+// @Summary      Change user role
+// @Description  Change the role of a user. The user sessions are ended so the new role applies on the next token.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param		 id    path    string    true  "user id"  Format(uuid)
+// @Param        request    body     dto.UpdateRoleInput  true  "new role"
+// @Success      200  {object}  entity.User
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      409  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
+// @Router       /admin/users/{id}/role [put]
+// @Security	 ApiKeyAuth
+func (h *AdminHandler) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	var input dto.UpdateRoleInput
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		invalidBody(w, r)
+		return
+	}
+	if !requireFields(w, r, map[string]string{"role": input.Role}) {
+		return
+	}
+	users, ok := h.users(w, r)
+	if !ok {
+		return
+	}
+	u, ok := h.otherUser(w, r, users, "you cannot change your own role")
+	if !ok {
+		return
+	}
+	lowered := !entity.RoleIncludes(input.Role, u.Role)
+	if err := u.SetRole(input.Role); err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+	if err := users.Update(u); err != nil {
+		writeRepositoryError(w, r, err, "user not found")
+		return
+	}
+	// Os access tokens carregam a role. Quem perdeu permissões perde também os refresh tokens.
+	if lowered {
+		err = h.revokeSessions(users, u)
+	} else {
+		err = users.RevokeSessions(u.ID.String())
+	}
+	if err != nil {
+		writeRepositoryError(w, r, err, "user not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(u)
+}
+
+// Disable user godoc
+// @Summary      Disable user
+// @Description  Disable a user account and end all of its sessions
+// @Tags         admin
+// @Produce      json
+// @Param		 id    path    string    true  "user id"  Format(uuid)
+// @Success      200  {object}  entity.User
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      409  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
+// @Router       /admin/users/{id}/disable [post]
+// @Security	 ApiKeyAuth
+func (h *AdminHandler) DisableUser(w http.ResponseWriter, r *http.Request) {
+	users, ok := h.users(w, r)
+	if !ok {
+		return
+	}
+	u, ok := h.otherUser(w, r, users, "you cannot disable your own account")
+	if !ok {
+		return
+	}
+	u.Disable(time.Now())
+	if err := users.Update(u); err != nil {
+		writeRepositoryError(w, r, err, "user not found")
+		return
+	}
+	if err := h.revokeSessions(users, u); err != nil {
+		writeRepositoryError(w, r, err, "user not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(u)
+}
+
+// Enable user godoc
+// @Summary      Enable user
+// @Description  Enable a disabled user account
+// @Tags         admin
+// @Produce      json
+// @Param		 id    path    string    true  "user id"  Format(uuid)
+// @Success      200  {object}  entity.User
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
+// @Router       /admin/users/{id}/enable [post]
+// @Security	 ApiKeyAuth
+func (h *AdminHandler) EnableUser(w http.ResponseWriter, r *http.Request) {
+	users, ok := h.users(w, r)
+	if !ok {
+		return
+	}
+	u, err := users.FindByID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeRepositoryError(w, r, err, "user not found")
+		return
+	}
+	u.Enable()
+	if err := users.Update(u); err != nil {
+		writeRepositoryError(w, r, err, "user not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(u)
+}
+
+// Logout user godoc
+// @Summary      Force logout
+// @Description  End all sessions of a user: the refresh tokens are revoked and the access tokens stop being accepted
+// @Tags         admin
+// @Param		 id    path    string    true  "user id"  Format(uuid)
+// @Success      204
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
+// @Router       /admin/users/{id}/logout [post]
+// @Security	 ApiKeyAuth
+func (h *AdminHandler) LogoutUser(w http.ResponseWriter, r *http.Request) {
+	users, ok := h.users(w, r)
+	if !ok {
+		return
+	}
+	u, err := users.FindByID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeRepositoryError(w, r, err, "user not found")
+		return
+	}
+	if err := h.revokeSessions(users, u); err != nil {
+		writeRepositoryError(w, r, err, "user not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// users retorna o repositório de usuários restrito ao tenant do administrador
+func (h *AdminHandler) users(w http.ResponseWriter, r *http.Request) (database.UserDBInterface, bool) {
+	tenantID, ok := currentTenant(w, r)
+	if !ok {
+		return nil, false
+	}
+	return h.UserDB.ForTenant(tenantID), true
+}
+
+// otherUser responde 409 quando o usuário do path é o próprio administrador
+func (h *AdminHandler) otherUser(w http.ResponseWriter, r *http.Request, users database.UserDBInterface, selfDetail string) (*entity.User, bool) {
+	id := chi.URLParam(r, "id")
+	if token, _, err := jwtauth.FromContext(r.Context()); err == nil && token != nil && token.Subject() == id {
+		problem.Conflict(w, r, selfDetail)
+		return nil, false
+	}
+	u, err := users.FindByID(id)
+	if err != nil {
+		writeRepositoryError(w, r, err, "user not found")
+		return nil, false
+	}
+	return u, true
+}
+
+func (h *AdminHandler) revokeSessions(users database.UserDBInterface, u *entity.User) error {
+	if err := h.RefreshTokenDB.RevokeAllByUser(u.ID.String()); err != nil {
+		return err
+	}
+	return users.RevokeSessions(u.ID.String())
+}
+
+func parseUserQuery(values url.Values) (database.UserQuery, error) {
+	var query database.UserQuery
+	var err error
+
+	if query.Page, err = parseOptionalInt(values, "page"); err != nil {
+		return query, err
+	}
+	if query.Limit, err = parseOptionalInt(values, "limit"); err != nil {
+		return query, err
+	}
+	query.Filter.Search = strings.TrimSpace(values.Get("q"))
+	query.Filter.Role = values.Get("role")
+	if query.Filter.Role != "" && !entity.IsValidRole(query.Filter.Role) {
+		return query, &paramError{Param: "role", Message: entity.ErrInvalidRole.Error()}
+	}
+	if value := values.Get("disabled"); value != "" {
+		disabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return query, &paramError{Param: "disabled", Message: "disabled must be true or false"}
+		}
+		query.Filter.Disabled = &disabled
+	}
+	return query, nil
+}
@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth"
+	"github.com/gsouza97/go-expert-api/internal/dto"
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	"github.com/gsouza97/go-expert-api/internal/infra/database"
+	"github.com/gsouza97/go-expert-api/internal/infra/webserver/problem"
+	"github.com/stretchr/testify/assert"
+)
+
+// adminRequest executa o handler como o administrador adminID, com o id do path informado
+func adminRequest(t *testing.T, handler http.HandlerFunc, method, target, id, body string, admin *entity.User) *httptest.ResponseRecorder {
+	tokenAuth := jwtauth.New("HS256", []byte("secret"), nil)
+	token, _, err := tokenAuth.Encode(map[string]interface{}{"sub": admin.ID.String(), "tid": admin.TenantID.String(), "role": entity.RoleAdmin})
+	assert.NoError(t, err)
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id)
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	req = req.WithContext(jwtauth.NewContext(ctx, token, nil))
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+func newTestAdminHandler(t *testing.T) (*AdminHandler, *UserHandler, *entity.User) {
+	h, _ := newTestUserHandler(t)
+	h.Verifier.Required = false
+	for _, body := range []string{
+		`{"name":"Admin","email":"admin@test.com","password":"12345678"}`,
+		`{"name":"John","email":"johndoe@test.com","password":"12345678"}`,
+		`{"name":"Jane","email":"janedoe@test.com","password":"12345678"}`,
+	} {
+		rec := postJSON(h.CreateUser, "/users", body)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+	}
+	admin, err := h.UserDB.FindByEmail("admin@test.com")
+	assert.NoError(t, err)
+	admin.Role = entity.RoleAdmin
+	assert.NoError(t, h.UserDB.Update(admin))
+	return NewAdminHandler(h.UserDB, h.Tokens.RefreshTokenDB, h.Throttle.ThrottleDB, h.Throttle, 2), h, admin
+}
+
+func TestAdminGetUsers(t *testing.T) {
+	a, _, admin := newTestAdminHandler(t)
+	adminID := admin.ID.String()
+
+	rec := adminRequest(t, a.GetUsers, http.MethodGet, "/admin/users?limit=10", "", "", admin)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "3", rec.Header().Get("X-Total-Count"))
+	assert.NotContains(t, rec.Body.String(), "password")
+	var list dto.UserListOutput
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&list))
+	// O limite é reduzido para o máximo configurado
+	assert.Equal(t, 2, list.Limit)
+	assert.Equal(t, 2, list.TotalPages)
+	assert.Equal(t, "admin@test.com", list.Items[0].Email)
+
+	rec = adminRequest(t, a.GetUsers, http.MethodGet, "/admin/users?q=JOHN", "", "", admin)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&list))
+	assert.Len(t, list.Items, 1)
+	assert.Equal(t, "johndoe@test.com", list.Items[0].Email)
+
+	rec = adminRequest(t, a.GetUsers, http.MethodGet, "/admin/users?role=admin", "", "", admin)
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&list))
+	assert.Equal(t, int64(1), list.Total)
+
+	rec = adminRequest(t, a.GetUsers, http.MethodGet, "/admin/users?role=root", "", "", admin)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"field":"role"`)
+	rec = adminRequest(t, a.GetUsers, http.MethodGet, "/admin/users?disabled=maybe", "", "", admin)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = adminRequest(t, a.GetUser, http.MethodGet, "/admin/users/"+adminID, adminID, "", admin)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"email":"admin@test.com"`)
+	rec = adminRequest(t, a.GetUser, http.MethodGet, "/admin/users/unknown", "unknown", "", admin)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestAdminUpdateUserRole(t *testing.T) {
+	a, h, admin := newTestAdminHandler(t)
+	adminID := admin.ID.String()
+	rec := postJSON(h.GetJWT, "/users/getToken", `{"email":"johndoe@test.com","password":"12345678"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var session dto.GetJWTOutput
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&session))
+	john, err := h.UserDB.FindByEmail("johndoe@test.com")
+	assert.NoError(t, err)
+	id := john.ID.String()
+
+	rec = adminRequest(t, a.UpdateUserRole, http.MethodPut, "/admin/users/"+id+"/role", id, `{"role":"root"}`, admin)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = adminRequest(t, a.UpdateUserRole, http.MethodPut, "/admin/users/"+adminID+"/role", adminID, `{"role":"viewer"}`, admin)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	rec = adminRequest(t, a.UpdateUserRole, http.MethodPut, "/admin/users/"+id+"/role", id, `{"role":"editor"}`, admin)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	john, err = h.UserDB.FindByID(id)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.RoleEditor, john.Role)
+	assert.Equal(t, int64(1), john.SessionVersion)
+
+	// Com a promoção o refresh token continua valendo e gera um token com a nova role
+	rec = postJSON(h.RefreshJWT, "/users/refresh", `{"refresh_token":"`+session.RefreshToken+`"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&session))
+
+	// Ao perder permissões o usuário perde também os refresh tokens
+	rec = adminRequest(t, a.UpdateUserRole, http.MethodPut, "/admin/users/"+id+"/role", id, `{"role":"viewer"}`, admin)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = postJSON(h.RefreshJWT, "/users/refresh", `{"refresh_token":"`+session.RefreshToken+`"}`)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestAdminDisableUser(t *testing.T) {
+	a, h, admin := newTestAdminHandler(t)
+	adminID := admin.ID.String()
+	credentials := `{"email":"johndoe@test.com","password":"12345678"}`
+	rec := postJSON(h.GetJWT, "/users/getToken", credentials)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var session dto.GetJWTOutput
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&session))
+	john, err := h.UserDB.FindByEmail("johndoe@test.com")
+	assert.NoError(t, err)
+	id := john.ID.String()
+
+	rec = adminRequest(t, a.DisableUser, http.MethodPost, "/admin/users/"+adminID+"/disable", adminID, "", admin)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	rec = adminRequest(t, a.DisableUser, http.MethodPost, "/admin/users/"+id+"/disable", id, "", admin)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "disabled_at")
+
+	rec = postJSON(h.GetJWT, "/users/getToken", credentials)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), problem.CodeDisabled)
+	rec = postJSON(h.RefreshJWT, "/users/refresh", `{"refresh_token":"`+session.RefreshToken+`"}`)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = adminRequest(t, a.GetUsers, http.MethodGet, "/admin/users?disabled=true", "", "", admin)
+	assert.Contains(t, rec.Body.String(), `"total":1`)
+
+	rec = adminRequest(t, a.EnableUser, http.MethodPost, "/admin/users/"+id+"/enable", id, "", admin)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "disabled_at")
+	rec = postJSON(h.GetJWT, "/users/getToken", credentials)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestAdminLogoutUser(t *testing.T) {
+	a, h, admin := newTestAdminHandler(t)
+	rec := postJSON(h.GetJWT, "/users/getToken", `{"email":"johndoe@test.com","password":"12345678"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var session dto.GetJWTOutput
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&session))
+	john, err := h.UserDB.FindByEmail("johndoe@test.com")
+	assert.NoError(t, err)
+	id := john.ID.String()
+
+	rec = adminRequest(t, a.LogoutUser, http.MethodPost, "/admin/users/"+id+"/logout", id, "", admin)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = postJSON(h.RefreshJWT, "/users/refresh", `{"refresh_token":"`+session.RefreshToken+`"}`)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	john, err = h.UserDB.FindByID(id)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), john.SessionVersion)
+
+	// Os tokens emitidos depois carregam a nova versão
+	rec = postJSON(h.GetJWT, "/users/getToken", `{"email":"johndoe@test.com","password":"12345678"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&session))
+	token, err := h.Tokens.Jwt.Decode(session.AccessToken)
+	assert.NoError(t, err)
+	version, _ := token.Get("sv")
+	assert.EqualValues(t, 1, version)
+}
+
+// newTestTenant cadastra o tenant com um administrador (admin@SLUG.com) e um usuário (bob@SLUG.com)
+// newTestTenantUser cria o usuário direto no tenant, como faz o "server tenant add-user"
+func newTestTenantUser(t *testing.T, h *UserHandler, tenant *entity.Tenant, email string) {
+	u, err := entity.NewUser("User", email, "12345678")
+	assert.NoError(t, err)
+	assert.NoError(t, h.UserDB.ForTenant(tenant.ID.String()).CreateUser(u))
+}
+
+func newTestTenant(t *testing.T, h *UserHandler, slug string) (*entity.Tenant, *entity.User) {
+	tenant, err := entity.NewTenant(slug, slug)
+	assert.NoError(t, err)
+	assert.NoError(t, h.Tenants.TenantDB.Create(tenant))
+	for _, name := range []string{"admin", "bob"} {
+		newTestTenantUser(t, h, tenant, name+"@"+slug+".com")
+	}
+	admin, err := h.UserDB.FindByEmail("admin@" + slug + ".com")
+	assert.NoError(t, err)
+	admin.Role = entity.RoleAdmin
+	assert.NoError(t, h.UserDB.Update(admin))
+	return tenant, admin
+}
+
+func TestAdminTenantIsolation(t *testing.T) {
+	a, h, admin := newTestAdminHandler(t)
+	globex, globexAdmin := newTestTenant(t, h, "globex")
+	bob, err := h.UserDB.FindByEmail("bob@globex.com")
+	assert.NoError(t, err)
+	assert.Equal(t, globex.ID, bob.TenantID)
+	assert.NotEqual(t, globex.ID, admin.TenantID)
+	id := bob.ID.String()
+
+	// O token identifica o tenant do usuário
+	rec := postJSON(h.GetJWT, "/users/getToken", `{"email":"bob@globex.com","password":"12345678"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var session dto.GetJWTOutput
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&session))
+	token, err := h.Tokens.Jwt.Decode(session.AccessToken)
+	assert.NoError(t, err)
+	tid, _ := token.Get("tid")
+	assert.Equal(t, globex.ID.String(), tid)
+
+	// Cada administrador só enxerga e altera os usuários do próprio tenant
+	rec = adminRequest(t, a.GetUsers, http.MethodGet, "/admin/users", "", "", admin)
+	assert.Equal(t, "3", rec.Header().Get("X-Total-Count"))
+	assert.NotContains(t, rec.Body.String(), "globex.com")
+	rec = adminRequest(t, a.GetUsers, http.MethodGet, "/admin/users", "", "", globexAdmin)
+	assert.Equal(t, "2", rec.Header().Get("X-Total-Count"))
+	assert.NotContains(t, rec.Body.String(), "test.com")
+	for _, handler := range []http.HandlerFunc{a.GetUser, a.DisableUser, a.EnableUser, a.LogoutUser, a.UnlockUser} {
+		rec = adminRequest(t, handler, http.MethodPost, "/admin/users/"+id, id, "", admin)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	}
+	rec = adminRequest(t, a.UpdateUserRole, http.MethodPut, "/admin/users/"+id+"/role", id, `{"role":"admin"}`, admin)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	bob, err = h.UserDB.FindByID(id)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.RoleViewer, bob.Role)
+	assert.False(t, bob.IsDisabled())
+
+	// Os bloqueios de conta só aparecem para o tenant da conta
+	for i := 0; i < 3; i++ {
+		postJSON(h.GetJWT, "/users/getToken", `{"email":"bob@globex.com","password":"wrong"}`)
+	}
+	rec = adminRequest(t, a.GetLockouts, http.MethodGet, "/admin/lockouts", "", "", admin)
+	assert.NotContains(t, rec.Body.String(), "bob@globex.com")
+	rec = adminRequest(t, a.GetLockouts, http.MethodGet, "/admin/lockouts", "", "", globexAdmin)
+	assert.Contains(t, rec.Body.String(), "bob@globex.com")
+}
+
+func TestAdminGetLockouts_TenantIsolation(t *testing.T) {
+	a, h, admin := newTestAdminHandler(t)
+	globex, globexAdmin := newTestTenant(t, h, "globex")
+	throttleDB := h.Throttle.ThrottleDB.(*database.LoginThrottleDB)
+	lockouts := func(admin *entity.User) []entity.LockoutEvent {
+		rec := adminRequest(t, a.GetLockouts, http.MethodGet, "/admin/lockouts", "", "", admin)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var events []entity.LockoutEvent
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(&events))
+		return events
+	}
+
+	// Um bloqueio do tenant padrão, anterior aos do outro tenant
+	assert.NoError(t, throttleDB.Lock(entity.NewLockoutEvent(entity.EmailThrottleKey("johndoe@test.com"), &admin.TenantID, 3, time.Now().Add(time.Minute))))
+
+	// O bloqueio por IP causado por tentativas nas contas da globex pertence à globex
+	req := func(email string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/users/getToken", strings.NewReader(`{"email":"`+email+`","password":"wrong"}`))
+		r.RemoteAddr = "198.51.100.7:1234"
+		return r
+	}
+	// Cada conta fica abaixo do próprio limite, mas o IP atinge o seu
+	for i := 0; i < 5; i++ {
+		email := "user" + strconv.Itoa(i) + "@globex.com"
+		newTestTenantUser(t, h, globex, email)
+		for j := 0; j < 2; j++ {
+			h.GetJWT(httptest.NewRecorder(), req(email))
+		}
+	}
+	events := lockouts(globexAdmin)
+	keys := []string{}
+	for _, event := range events {
+		keys = append(keys, event.Key)
+		assert.Equal(t, globex.ID, *event.TenantID)
+	}
+	assert.Contains(t, keys, entity.IPThrottleKey("198.51.100.7"))
+	for _, event := range lockouts(admin) {
+		assert.NotEqual(t, entity.IPThrottleKey("198.51.100.7"), event.Key)
+		assert.Equal(t, admin.TenantID, *event.TenantID)
+	}
+
+	// Bloqueios de contas inexistentes não pertencem a nenhum tenant
+	assert.NoError(t, h.Throttle.Failure("nobody@test.com", "203.0.113.9", nil))
+	assert.NoError(t, throttleDB.Lock(entity.NewLockoutEvent(entity.IPThrottleKey("203.0.113.9"), nil, 10, time.Now().Add(time.Minute))))
+	for _, events := range [][]entity.LockoutEvent{lockouts(admin), lockouts(globexAdmin)} {
+		for _, event := range events {
+			assert.NotEqual(t, entity.IPThrottleKey("203.0.113.9"), event.Key)
+		}
+	}
+
+	// O limite da listagem é aplicado depois do filtro por tenant
+	for i := 0; i < maxLockoutEvents; i++ {
+		assert.NoError(t, throttleDB.Lock(entity.NewLockoutEvent(entity.EmailThrottleKey("bob@globex.com"), &globex.ID, 3, time.Now().Add(time.Minute))))
+	}
+	events = lockouts(admin)
+	assert.Len(t, events, 1)
+	assert.Equal(t, entity.EmailThrottleKey("johndoe@test.com"), events[0].Key)
+	assert.Len(t, lockouts(globexAdmin), maxLockoutEvents)
+}
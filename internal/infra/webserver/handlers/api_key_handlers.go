@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gsouza97/go-expert-api/internal/dto"
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	"github.com/gsouza97/go-expert-api/internal/infra/database"
+)
+
+// APIKeyHandler gerencia as chaves pessoais (/users/me) e as de contas de serviço (/admin)
+type APIKeyHandler struct {
+	APIKeyDB database.APIKeyDBInterface
+	UserDB   database.UserDBInterface
+}
+
+func NewAPIKeyHandler(apiKeyDB database.APIKeyDBInterface, userDB database.UserDBInterface) *APIKeyHandler {
+	return &APIKeyHandler{
+		APIKeyDB: apiKeyDB,
+		UserDB:   userDB,
+	}
+}
+
+// Create API key godoc
+// @Summary      Create API key
+// @Description  Create a personal API key, sent in the X-API-Key header. The key is returned only in this response.
+// @Tags         api-keys
+// @Accept       json
+// @Produce      json
+// @Param        request    body     dto.CreateAPIKeyInput  true  "api key request"
+// @Success      201  {object}  dto.CreateAPIKeyOutput
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
+// @Router       /users/me/api-keys [post]
+// @Security	 ApiKeyAuth
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	u, ok := loadCurrentUser(w, r, h.UserDB)
+	if !ok {
+		return
+	}
+	h.create(w, r, u)
+}
+
+// List API keys godoc
+// @Summary      List API keys
+// @Description  List the API keys of the current user, including revoked ones
+// @Tags         api-keys
+// @Produce      json
+// @Success      200  {array}   dto.APIKeyOutput
+// @Failure      401  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
+// @Router       /users/me/api-keys [get]
+// @Security	 ApiKeyAuth
+func (h *APIKeyHandler) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	u, ok := loadCurrentUser(w, r, h.UserDB)
+	if !ok {
+		return
+	}
+	h.list(w, r, u)
+}
+
+// Revoke API key godoc
+// @Summary      Revoke API key
+// @Description  Revoke an API key of the current user
+// @Tags         api-keys
+// @Param		 id    path    string    true  "api key id"  Format(uuid)
+// @Success      204
+// @Failure      401  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
+// @Router       /users/me/api-keys/{id} [delete]
+// @Security	 ApiKeyAuth
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	u, ok := loadCurrentUser(w, r, h.UserDB)
+	if !ok {
+		return
+	}
+	h.revoke(w, r, u, chi.URLParam(r, "id"))
+}
+
+// Create user API key godoc
+// @Summary      Create API key for a user
+// @Description  Create an API key for another user, usually a service account. The key is returned only in this response.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param		 id    path    string    true  "user id"  Format(uuid)
+// @Param        request    body     dto.CreateAPIKeyInput  true  "api key request"
+// @Success      201  {object}  dto.CreateAPIKeyOutput
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
+// @Router       /admin/users/{id}/api-keys [post]
+// @Security	 ApiKeyAuth
+func (h *APIKeyHandler) CreateUserAPIKey(w http.ResponseWriter, r *http.Request) {
+	u, ok := h.tenantUser(w, r)
+	if !ok {
+		return
+	}
+	h.create(w, r, u)
+}
+
+// List user API keys godoc
+// @Summary      List API keys of a user
+// @Description  List the API keys of a user, including revoked ones
+// @Tags         admin
+// @Produce      json
+// @Param		 id    path    string    true  "user id"  Format(uuid)
+// @Success      200  {array}   dto.APIKeyOutput
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
+// @Router       /admin/users/{id}/api-keys [get]
+// @Security	 ApiKeyAuth
+func (h *APIKeyHandler) GetUserAPIKeys(w http.ResponseWriter, r *http.Request) {
+	u, ok := h.tenantUser(w, r)
+	if !ok {
+		return
+	}
+	h.list(w, r, u)
+}
+
+// Revoke user API key godoc
+// @Summary      Revoke API key of a user
+// @Description  Revoke an API key of a user
+// @Tags         admin
+// @Param		 id     path    string    true  "user id"  Format(uuid)
+// @Param		 keyID  path    string    true  "api key id"  Format(uuid)
+// @Success      204
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
+// @Router       /admin/users/{id}/api-keys/{keyID} [delete]
+// @Security	 ApiKeyAuth
+func (h *APIKeyHandler) RevokeUserAPIKey(w http.ResponseWriter, r *http.Request) {
+	u, ok := h.tenantUser(w, r)
+	if !ok {
+		return
+	}
+	h.revoke(w, r, u, chi.URLParam(r, "keyID"))
+}
+
+// tenantUser carrega o usuário do path, que precisa pertencer ao tenant do administrador
+func (h *APIKeyHandler) tenantUser(w http.ResponseWriter, r *http.Request) (*entity.User, bool) {
+	tenantID, ok := currentTenant(w, r)
+	if !ok {
+		return nil, false
+	}
+	u, err := h.UserDB.ForTenant(tenantID).FindByID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeRepositoryError(w, r, err, "user not found")
+		return nil, false
+	}
+	return u, true
+}
+
+func (h *APIKeyHandler) create(w http.ResponseWriter, r *http.Request, u *entity.User) {
+	var input dto.CreateAPIKeyInput
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		invalidBody(w, r)
+		return
+	}
+	if !requireFields(w, r, map[string]string{"name": input.Name}) {
+		return
+	}
+
+	// Uma chave não pode ter permissões que a role do dono não tem
+	scopes := make([]entity.Permission, len(input.Scopes))
+	for i, scope := range input.Scopes {
+		scopes[i] = entity.Permission(scope)
+		if !u.HasPermission(scopes[i]) {
+			writeValidationError(w, r, entity.ErrInvalidScope)
+			return
+		}
+	}
+	key, plain, err := entity.NewAPIKey(u.ID, input.Name, scopes, input.ExpiresAt)
+	if err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+	if err := h.APIKeyDB.Create(key); err != nil {
+		writeRepositoryError(w, r, err, "api key not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(dto.CreateAPIKeyOutput{APIKeyOutput: apiKeyOutput(key), Key: plain})
+}
+
+func (h *APIKeyHandler) list(w http.ResponseWriter, r *http.Request, u *entity.User) {
+	keys, err := h.APIKeyDB.FindByUser(u.ID.String())
+	if err != nil {
+		writeRepositoryError(w, r, err, "api key not found")
+		return
+	}
+	output := make([]dto.APIKeyOutput, len(keys))
+	for i, key := range keys {
+		output[i] = apiKeyOutput(key)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+}
+
+func (h *APIKeyHandler) revoke(w http.ResponseWriter, r *http.Request, u *entity.User, id string) {
+	if err := h.APIKeyDB.Revoke(id, u.ID.String()); err != nil {
+		writeRepositoryError(w, r, err, "api key not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func apiKeyOutput(key *entity.APIKey) dto.APIKeyOutput {
+	return dto.APIKeyOutput{
+		ID:         key.ID.String(),
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     append([]string{}, key.ScopeList()...),
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		CreatedAt:  key.CreatedAt,
+		RevokedAt:  key.RevokedAt,
+	}
+}
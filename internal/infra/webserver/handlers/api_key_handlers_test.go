@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth"
+	"github.com/gsouza97/go-expert-api/internal/dto"
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	"github.com/gsouza97/go-expert-api/internal/infra/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKeys(t *testing.T) {
+	h, _ := newTestUserHandler(t)
+	session := newTestSession(t, h)
+	a := NewAPIKeyHandler(database.NewAPIKeyDB(h.UserDB.(*database.UserDB).DB), h.UserDB)
+
+	// Um viewer não pode criar uma chave com permissão de escrita
+	rec := authenticatedPost(t, h, a.CreateAPIKey, http.MethodPost, `{"name":"CI","scopes":["products:write"]}`, session.AccessToken)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"field":"scopes"`)
+	rec = authenticatedPost(t, h, a.CreateAPIKey, http.MethodPost, `{"scopes":["products:read"]}`, session.AccessToken)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = authenticatedPost(t, h, a.CreateAPIKey, http.MethodPost, `{"name":"CI","expires_at":"2000-01-01T00:00:00Z"}`, session.AccessToken)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"field":"expires_at"`)
+
+	expiresAt := time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339)
+	rec = authenticatedPost(t, h, a.CreateAPIKey, http.MethodPost, `{"name":"CI","scopes":["products:read"],"expires_at":"`+expiresAt+`"}`, session.AccessToken)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	var created dto.CreateAPIKeyOutput
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&created))
+	assert.NotEmpty(t, created.Key)
+	assert.Equal(t, []string{"products:read"}, created.Scopes)
+	prefix, err := entity.ParseAPIKeyPrefix(created.Key)
+	assert.NoError(t, err)
+	assert.Equal(t, created.Prefix, prefix)
+
+	// A chave completa não aparece mais na listagem
+	rec = authenticatedPost(t, h, a.GetAPIKeys, http.MethodGet, "", session.AccessToken)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), created.Key)
+	var keys []dto.APIKeyOutput
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&keys))
+	assert.Len(t, keys, 1)
+	assert.Nil(t, keys[0].RevokedAt)
+
+	revoke := func(id string) int {
+		token, err := h.Tokens.Jwt.Decode(session.AccessToken)
+		assert.NoError(t, err)
+		req := httptest.NewRequest(http.MethodDelete, "/users/me/api-keys/"+id, nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+		req = req.WithContext(jwtauth.NewContext(ctx, token, nil))
+		rec := httptest.NewRecorder()
+		a.RevokeAPIKey(rec, req)
+		return rec.Code
+	}
+	assert.Equal(t, http.StatusNotFound, revoke("unknown"))
+	assert.Equal(t, http.StatusNoContent, revoke(created.ID))
+
+	rec = authenticatedPost(t, h, a.GetAPIKeys, http.MethodGet, "", session.AccessToken)
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&keys))
+	assert.NotNil(t, keys[0].RevokedAt)
+}
+
+func TestAdminAPIKeys(t *testing.T) {
+	admin, h, adminUser := newTestAdminHandler(t)
+	a := NewAPIKeyHandler(database.NewAPIKeyDB(h.UserDB.(*database.UserDB).DB), admin.UserDB)
+	john, err := h.UserDB.FindByEmail("johndoe@test.com")
+	assert.NoError(t, err)
+	id := john.ID.String()
+
+	rec := adminRequest(t, a.CreateUserAPIKey, http.MethodPost, "/admin/users/"+id+"/api-keys", id, `{"name":"Service"}`, adminUser)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	var created dto.CreateAPIKeyOutput
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&created))
+
+	rec = adminRequest(t, a.GetUserAPIKeys, http.MethodGet, "/admin/users/"+id+"/api-keys", id, "", adminUser)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), created.ID)
+
+	rec = adminRequest(t, a.CreateUserAPIKey, http.MethodPost, "/admin/users/unknown/api-keys", "unknown", `{"name":"Service"}`, adminUser)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	"github.com/gsouza97/go-expert-api/internal/infra/database"
+	"github.com/gsouza97/go-expert-api/internal/infra/mail"
+	"github.com/gsouza97/go-expert-api/pkg/cursor"
+)
+
+const emailVerificationPurpose = "email_verification"
+
+var ErrInvalidVerificationToken = errors.New("verification token is invalid or expired")
+
+// verificationToken inclui o email para deixar de valer se o usuário trocar de endereço
+type verificationToken struct {
+	Purpose   string `json:"pur"`
+	UserID    string `json:"sub"`
+	Email     string `json:"email"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// EmailVerifier envia os links de verificação e confirma os tokens recebidos
+type EmailVerifier struct {
+	UserDB    database.UserDBInterface
+	Signer    *cursor.Signer
+	Mailer    mail.Mailer
+	ExpiresIn time.Duration
+	VerifyURL string // Recebe o token na query "token"
+	Required  bool   // GetJWT recusa contas não verificadas
+}
+
+func NewEmailVerifier(userDB database.UserDBInterface, signer *cursor.Signer, mailer mail.Mailer, expiresIn time.Duration, verifyURL string, required bool) *EmailVerifier {
+	return &EmailVerifier{
+		UserDB:    userDB,
+		Signer:    signer,
+		Mailer:    mailer,
+		ExpiresIn: expiresIn,
+		VerifyURL: verifyURL,
+		Required:  required,
+	}
+}
+
+// Send envia um novo link; os anteriores continuam válidos até expirarem
+func (v *EmailVerifier) Send(user *entity.User) error {
+	token, err := v.Signer.Encode(verificationToken{
+		Purpose:   emailVerificationPurpose,
+		UserID:    user.ID.String(),
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(v.ExpiresIn).Unix(),
+	})
+	if err != nil {
+		return err
+	}
+	link := v.VerifyURL + "?token=" + url.QueryEscape(token)
+	return v.Mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nConfirm your email address by opening the link below:\n\n%s\n\nThe link expires in %s. If you did not create an account, ignore this email.\n",
+			user.Name, link, v.ExpiresIn),
+	})
+}
+
+// Verify retorna ErrInvalidVerificationToken para tokens expirados, adulterados ou já usados
+func (v *EmailVerifier) Verify(token string) error {
+	var claims verificationToken
+	if err := v.Signer.Decode(token, &claims); err != nil {
+		return ErrInvalidVerificationToken
+	}
+	if claims.Purpose != emailVerificationPurpose || time.Now().Unix() > claims.ExpiresAt {
+		return ErrInvalidVerificationToken
+	}
+	verified, err := v.UserDB.VerifyEmail(claims.UserID, claims.Email, time.Now())
+	if err != nil {
+		return err
+	}
+	if !verified {
+		return ErrInvalidVerificationToken
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"sort"
+
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	"github.com/gsouza97/go-expert-api/internal/infra/database"
+	"github.com/gsouza97/go-expert-api/internal/infra/webserver/problem"
+	"github.com/gsouza97/go-expert-api/pkg/password"
+)
+
+// domainFieldErrors associa os erros de validação das entidades ao campo da requisição
+var domainFieldErrors = map[error]problem.FieldError{
+	entity.ErrRequiredID:    {Field: "id", Code: "required", Message: entity.ErrRequiredID.Error()},
+	entity.ErrInvalidID:     {Field: "id", Code: "invalid", Message: entity.ErrInvalidID.Error()},
+	entity.ErrRequiredName:  {Field: "name", Code: "required", Message: entity.ErrRequiredName.Error()},
+	entity.ErrRequiredPrice: {Field: "price", Code: "required", Message: entity.ErrRequiredPrice.Error()},
+	entity.ErrInvalidPrice:  {Field: "price", Code: "invalid", Message: entity.ErrInvalidPrice.Error()},
+	entity.ErrInvalidRole:   {Field: "role", Code: "invalid", Message: entity.ErrInvalidRole.Error()},
+	entity.ErrRequiredEmail: {Field: "email", Code: "required", Message: entity.ErrRequiredEmail.Error()},
+	entity.ErrInvalidEmail:  {Field: "email", Code: "invalid", Message: entity.ErrInvalidEmail.Error()},
+	entity.ErrInvalidScope:  {Field: "scopes", Code: "invalid", Message: entity.ErrInvalidScope.Error()},
+	entity.ErrInvalidExpiry: {Field: "expires_at", Code: "invalid", Message: entity.ErrInvalidExpiry.Error()},
+	entity.ErrInvalidGrant:  {Field: "grants", Code: "invalid", Message: entity.ErrInvalidGrant.Error()},
+	entity.ErrRequiredScope: {Field: "scopes", Code: "required", Message: entity.ErrRequiredScope.Error()},
+
+	entity.ErrRequiredServiceUser: {Field: "user_id", Code: "required", Message: entity.ErrRequiredServiceUser.Error()},
+
+	password.ErrTooShort:      {Field: "password", Code: "too_short", Message: password.ErrTooShort.Error()},
+	password.ErrTooLong:       {Field: "password", Code: "too_long", Message: password.ErrTooLong.Error()},
+	password.ErrMissingUpper:  {Field: "password", Code: "missing_uppercase", Message: password.ErrMissingUpper.Error()},
+	password.ErrMissingLower:  {Field: "password", Code: "missing_lowercase", Message: password.ErrMissingLower.Error()},
+	password.ErrMissingDigit:  {Field: "password", Code: "missing_digit", Message: password.ErrMissingDigit.Error()},
+	password.ErrMissingSymbol: {Field: "password", Code: "missing_symbol", Message: password.ErrMissingSymbol.Error()},
+	password.ErrBreached:      {Field: "password", Code: "breached", Message: password.ErrBreached.Error()},
+}
+
+// paramError indica um parâmetro de query ou de path inválido
+type paramError struct {
+	Param   string
+	Message string
+}
+
+func (e *paramError) Error() string {
+	return e.Message
+}
+
+// writeValidationError responde 400 para os erros conhecidos e 500 para os demais
+func writeValidationError(w http.ResponseWriter, r *http.Request, err error) {
+	for domainErr, fieldErr := range domainFieldErrors {
+		if errors.Is(err, domainErr) {
+			problem.Validation(w, r, fieldErr)
+			return
+		}
+	}
+	var pErr *paramError
+	if errors.As(err, &pErr) {
+		problem.Validation(w, r, problem.FieldError{Field: pErr.Param, Code: "invalid", Message: pErr.Message})
+		return
+	}
+	problem.Internal(w, r)
+}
+
+// writeRepositoryError traduz os erros do repositório em 404, 409, 412 e 503. Outros erros viram 500.
+func writeRepositoryError(w http.ResponseWriter, r *http.Request, err error, notFoundDetail string) {
+	switch {
+	case errors.Is(err, database.ErrNotFound):
+		problem.NotFound(w, r, notFoundDetail)
+	case errors.Is(err, database.ErrConflict):
+		problem.Conflict(w, r, "the resource conflicts with an existing one")
+	case errors.Is(err, database.ErrStaleVersion):
+		problem.PreconditionFailed(w, r, "the resource was changed by another request, fetch it again")
+	case errors.Is(err, database.ErrUnavailable):
+		problem.Unavailable(w, r)
+	default:
+		problem.Internal(w, r)
+	}
+}
+
+func writeThrottleError(w http.ResponseWriter, r *http.Request, err error) {
+	var locked *LockedError
+	if errors.As(err, &locked) {
+		problem.TooManyRequests(w, r, locked.RetryAfter, locked.Error())
+		return
+	}
+	writeRepositoryError(w, r, err, "user not found")
+}
+
+// clientIP ignora o X-Forwarded-For, que o cliente pode forjar para escapar do bloqueio por IP
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func requiredField(field string) problem.FieldError {
+	return problem.FieldError{Field: field, Code: "required", Message: field + " is required"}
+}
+
+// requireFields responde 400 listando os campos vazios
+func requireFields(w http.ResponseWriter, r *http.Request, fields map[string]string) bool {
+	var errs []problem.FieldError
+	for _, name := range sortedKeys(fields) {
+		if fields[name] == "" {
+			errs = append(errs, requiredField(name))
+		}
+	}
+	if len(errs) > 0 {
+		problem.Validation(w, r, errs...)
+		return false
+	}
+	return true
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func invalidBody(w http.ResponseWriter, r *http.Request) {
+	problem.BadRequest(w, r, "the request body is not valid JSON")
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	"github.com/gsouza97/go-expert-api/internal/infra/webserver/problem"
+)
+
+// productETag é um ETag forte com a versão do produto
+func productETag(p *entity.Product) string {
+	return `"` + strconv.FormatInt(p.Version, 10) + `"`
+}
+
+// etagMatches compara o ETag com a lista de um If-Match (comparação forte) ou If-None-Match (RFC 9110, 13.1)
+func etagMatches(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.HasPrefix(candidate, "W/") {
+			if !weak {
+				continue
+			}
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIfMatch responde 412 para outra versão ou 428 se o If-Match obrigatório faltar
+func (h *ProductHandler) checkIfMatch(w http.ResponseWriter, r *http.Request, p *entity.Product) bool {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		if h.RequireIfMatch {
+			problem.Write(w, r, http.StatusPreconditionRequired, problem.CodePreconditionRq, "send the ETag of the product in If-Match")
+			return false
+		}
+		return true
+	}
+	if !etagMatches(ifMatch, productETag(p), false) {
+		problem.PreconditionFailed(w, r, "the product was changed by another request, fetch it again")
+		return false
+	}
+	return true
+}
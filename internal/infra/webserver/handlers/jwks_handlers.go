@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gsouza97/go-expert-api/internal/infra/webserver/problem"
+	"github.com/gsouza97/go-expert-api/pkg/jwtkeys"
+)
+
+type JWKSHandler struct {
+	Keys *jwtkeys.KeySet
+}
+
+func NewJWKSHandler(keys *jwtkeys.KeySet) *JWKSHandler {
+	return &JWKSHandler{
+		Keys: keys,
+	}
+}
+
+// Get JWKS godoc
+// @Summary      Get JSON Web Key Set
+// @Description  Public keys used to verify the access tokens. Empty when tokens are signed with HS256.
+// @Tags         auth
+// @Produce      json
+// @Success      200
+// @Failure      500  {object}  problem.Problem
+// @Router       /.well-known/jwks.json [get]
+func (h *JWKSHandler) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	set, err := h.Keys.JWKS()
+	if err != nil {
+		problem.Internal(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(set)
+}
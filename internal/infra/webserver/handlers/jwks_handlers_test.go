@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gsouza97/go-expert-api/pkg/jwtkeys"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetJWKS(t *testing.T) {
+	raw, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	key, err := jwtkeys.NewKey(raw)
+	assert.NoError(t, err)
+	keys, err := jwtkeys.NewKeySet(key)
+	assert.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	NewJWKSHandler(keys).GetJWKS(rec, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var body struct {
+		Keys []map[string]interface{} `json:"keys"`
+	}
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	assert.Len(t, body.Keys, 1)
+	assert.Equal(t, key.ID, body.Keys[0]["kid"])
+	assert.Equal(t, "ES256", body.Keys[0]["alg"])
+	assert.Equal(t, "sig", body.Keys[0]["use"])
+	assert.NotContains(t, body.Keys[0], "d")
+}
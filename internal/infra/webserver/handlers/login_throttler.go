@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	"github.com/gsouza97/go-expert-api/internal/infra/database"
+	entityPkg "github.com/gsouza97/go-expert-api/pkg/entity"
+)
+
+// LockedError indica que a conta ou o IP está temporariamente bloqueado
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("too many failed login attempts, retry in %s", e.RetryAfter.Round(time.Second))
+}
+
+// LoginThrottler bloqueia contas e IPs após falhas de login, dobrando o bloqueio a cada nova falha
+type LoginThrottler struct {
+	ThrottleDB       database.LoginThrottleDBInterface
+	MaxAttempts      int
+	MaxAttemptsPerIP int // Maior, pois vários usuários podem compartilhar um IP
+	Window           time.Duration
+	LockoutBase      time.Duration
+	LockoutMax       time.Duration
+}
+
+func NewLoginThrottler(throttleDB database.LoginThrottleDBInterface, maxAttempts, maxAttemptsPerIP int, window, lockoutBase, lockoutMax time.Duration) *LoginThrottler {
+	return &LoginThrottler{
+		ThrottleDB:       throttleDB,
+		MaxAttempts:      maxAttempts,
+		MaxAttemptsPerIP: maxAttemptsPerIP,
+		Window:           window,
+		LockoutBase:      lockoutBase,
+		LockoutMax:       lockoutMax,
+	}
+}
+
+// Check retorna um *LockedError se a conta ou o IP estiver bloqueado
+func (t *LoginThrottler) Check(email, ip string) error {
+	throttles, err := t.ThrottleDB.Find(entity.EmailThrottleKey(email), entity.IPThrottleKey(ip))
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	var retryAfter time.Duration
+	for _, throttle := range throttles {
+		if wait := throttle.RetryAfter(now); wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+	if retryAfter > 0 {
+		return &LockedError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// Failure registra a falha; tenantID é o tenant da conta tentada, nil se ela não existir
+func (t *LoginThrottler) Failure(email, ip string, tenantID *entityPkg.ID) error {
+	if err := t.registerFailure(entity.EmailThrottleKey(email), tenantID, t.MaxAttempts); err != nil {
+		return err
+	}
+	return t.registerFailure(entity.IPThrottleKey(ip), tenantID, t.MaxAttemptsPerIP)
+}
+
+// Success zera só as falhas da conta, para que um atacante não zere as do IP entrando na própria conta
+func (t *LoginThrottler) Success(email string) error {
+	return t.ThrottleDB.Reset(entity.EmailThrottleKey(email))
+}
+
+// Unlock remove o bloqueio da conta antes do prazo
+func (t *LoginThrottler) Unlock(email string) error {
+	return t.ThrottleDB.Unlock(entity.EmailThrottleKey(email))
+}
+
+func (t *LoginThrottler) registerFailure(key string, tenantID *entityPkg.ID, maxAttempts int) error {
+	now := time.Now()
+	throttle, err := t.ThrottleDB.RegisterFailure(key, now, now.Add(-t.Window))
+	if err != nil {
+		return err
+	}
+	if throttle.Failures < maxAttempts {
+		return nil
+	}
+	event := entity.NewLockoutEvent(key, tenantID, throttle.Failures, now.Add(t.lockoutDuration(throttle.Failures-maxAttempts)))
+	return t.ThrottleDB.Lock(event)
+}
+
+func (t *LoginThrottler) lockoutDuration(excess int) time.Duration {
+	duration := t.LockoutBase
+	for i := 0; i < excess && duration < t.LockoutMax; i++ {
+		duration *= 2
+	}
+	if duration > t.LockoutMax {
+		return t.LockoutMax
+	}
+	return duration
+}
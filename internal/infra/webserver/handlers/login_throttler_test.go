@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	"github.com/gsouza97/go-expert-api/internal/infra/database"
+	"github.com/gsouza97/go-expert-api/internal/infra/webserver/problem"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginThrottler_LockoutDuration(t *testing.T) {
+	throttle := NewLoginThrottler(nil, 5, 20, time.Minute, 30*time.Second, 10*time.Minute)
+	assert.Equal(t, 30*time.Second, throttle.lockoutDuration(0))
+	assert.Equal(t, time.Minute, throttle.lockoutDuration(1))
+	assert.Equal(t, 4*time.Minute, throttle.lockoutDuration(3))
+	assert.Equal(t, 10*time.Minute, throttle.lockoutDuration(5))
+	assert.Equal(t, 10*time.Minute, throttle.lockoutDuration(100))
+}
+
+func TestGetJWT_LockoutAfterFailedAttempts(t *testing.T) {
+	h, _ := newTestUserHandler(t)
+	h.Verifier.Required = false
+	rec := postJSON(h.CreateUser, "/users", `{"name":"John","email":"johndoe@test.com","password":"12345678"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	for i := 0; i < 3; i++ {
+		rec = postJSON(h.GetJWT, "/users/getToken", `{"email":"johndoe@test.com","password":"wrong"}`)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	}
+
+	// Nem a senha correta é aceita durante o bloqueio
+	rec = postJSON(h.GetJWT, "/users/getToken", `{"email":"JohnDoe@test.com","password":"12345678"}`)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Contains(t, rec.Body.String(), problem.CodeTooManyRequest)
+	retryAfter, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+	assert.NoError(t, err)
+	assert.InDelta(t, 60, retryAfter, 1)
+
+	u, err := h.UserDB.FindByEmail("johndoe@test.com")
+	assert.NoError(t, err)
+	events, err := h.Throttle.ThrottleDB.FindLockouts(u.TenantID.String(), true, 10)
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+	assert.Equal(t, entity.EmailThrottleKey("johndoe@test.com"), events[0].Key)
+	assert.Equal(t, u.TenantID, *events[0].TenantID)
+
+	// Um administrador pode desbloquear a conta antes do prazo
+	rec = postJSON(h.CreateUser, "/users", `{"name":"Admin","email":"admin@test.com","password":"12345678"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	adminUser, err := h.UserDB.FindByEmail("admin@test.com")
+	assert.NoError(t, err)
+	admin := NewAdminHandler(h.UserDB, h.Tokens.RefreshTokenDB, h.Throttle.ThrottleDB, h.Throttle, 100)
+	unlock := adminRequest(t, admin.UnlockUser, http.MethodPost, "/admin/users/"+u.ID.String()+"/unlock", u.ID.String(), "", adminUser)
+	assert.Equal(t, http.StatusNoContent, unlock.Code)
+
+	rec = postJSON(h.GetJWT, "/users/getToken", `{"email":"johndoe@test.com","password":"12345678"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	list := adminRequest(t, admin.GetLockouts, http.MethodGet, "/admin/lockouts?active=true", "", "", adminUser)
+	assert.Equal(t, http.StatusOK, list.Code)
+	var active []entity.LockoutEvent
+	assert.NoError(t, json.NewDecoder(list.Body).Decode(&active))
+	assert.Empty(t, active)
+}
+
+func TestGetJWT_UnknownEmailsAreThrottled(t *testing.T) {
+	h, _ := newTestUserHandler(t)
+
+	for i := 0; i < 3; i++ {
+		rec := postJSON(h.GetJWT, "/users/getToken", `{"email":"nobody@test.com","password":"wrong"}`)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	}
+	rec := postJSON(h.GetJWT, "/users/getToken", `{"email":"nobody@test.com","password":"wrong"}`)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+}
+
+func TestGetJWT_LockoutPerIP(t *testing.T) {
+	h, _ := newTestUserHandler(t)
+	h.Verifier.Required = false
+	rec := postJSON(h.CreateUser, "/users", `{"name":"John","email":"johndoe@test.com","password":"12345678"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	// Cada email fica abaixo do limite da conta, mas o IP atinge o seu limite
+	for i := 0; i < 10; i++ {
+		rec = postJSON(h.GetJWT, "/users/getToken", `{"email":"user`+strconv.Itoa(i)+`@test.com","password":"wrong"}`)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	}
+	rec = postJSON(h.GetJWT, "/users/getToken", `{"email":"johndoe@test.com","password":"12345678"}`)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+
+	// Outro IP não é afetado
+	req := httptest.NewRequest(http.MethodPost, "/users/getToken", strings.NewReader(`{"email":"johndoe@test.com","password":"12345678"}`))
+	req.RemoteAddr = "203.0.113.7:4321"
+	rec = httptest.NewRecorder()
+	h.GetJWT(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestLoginThrottler_FailuresExpireWithWindow(t *testing.T) {
+	h, _ := newTestUserHandler(t)
+	throttleDB := h.Throttle.ThrottleDB.(*database.LoginThrottleDB)
+	key := entity.EmailThrottleKey("johndoe@test.com")
+
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, h.Throttle.Failure("johndoe@test.com", "192.0.2.1", nil))
+	}
+	// Simula falhas antigas, fora da janela
+	err := throttleDB.DB.Model(&entity.LoginThrottle{}).Where("throttle_key = ?", key).
+		Update("last_failure_at", time.Now().Add(-2*h.Throttle.Window)).Error
+	assert.NoError(t, err)
+
+	assert.NoError(t, h.Throttle.Failure("johndoe@test.com", "192.0.2.1", nil))
+	throttles, err := throttleDB.Find(key)
+	assert.NoError(t, err)
+	assert.Len(t, throttles, 1)
+	assert.Equal(t, 1, throttles[0].Failures)
+	assert.NoError(t, h.Throttle.Check("johndoe@test.com", "192.0.2.1"))
+}
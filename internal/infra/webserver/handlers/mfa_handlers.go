@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/gsouza97/go-expert-api/internal/dto"
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	"github.com/gsouza97/go-expert-api/internal/infra/database"
+	"github.com/gsouza97/go-expert-api/internal/infra/webserver/problem"
+)
+
+// MFA login godoc
+// @Summary      Complete login with a second factor
+// @Description  Exchange the MFA token returned by /users/getToken and a TOTP or recovery code for the user tokens
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request    body     dto.MFALoginInput  true  "mfa token and code"
+// @Success      200  {object}  dto.GetJWTOutput
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      429  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
+// @Router       /users/getToken/mfa [post]
+func (h *UserHandler) GetJWTWithMFA(w http.ResponseWriter, r *http.Request) {
+	var input dto.MFALoginInput
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		invalidBody(w, r)
+		return
+	}
+	if !requireFields(w, r, map[string]string{"mfa_token": input.MFAToken, "code": input.Code}) {
+		return
+	}
+
+	u, err := h.MFA.ChallengeUser(input.MFAToken)
+	if err != nil {
+		writeMFAError(w, r, err)
+		return
+	}
+	// Os códigos errados contam para o mesmo bloqueio das senhas erradas
+	ip := clientIP(r)
+	if err := h.Throttle.Check(u.Email, ip); err != nil {
+		writeThrottleError(w, r, err)
+		return
+	}
+	err = h.MFA.VerifyCode(u, input.Code)
+	if errors.Is(err, ErrInvalidMFACode) {
+		h.registerLoginFailure(u.Email, ip, &u.TenantID)
+	}
+	if err != nil {
+		writeMFAError(w, r, err)
+		return
+	}
+	h.registerLoginSuccess(u.Email)
+
+	tokens, err := h.Tokens.Issue(u)
+	if err != nil {
+		writeRepositoryError(w, r, err, "user not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// Enroll TOTP godoc
+// @Summary      Start TOTP enrollment
+// @Description  Generate a TOTP secret and its otpauth:// provisioning URI. It is only required at login after being confirmed.
+// @Tags         users
+// @Produce      json
+// @Success      200  {object}  dto.TOTPEnrollmentOutput
+// @Failure      401  {object}  problem.Problem
+// @Failure      409  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
+// @Router       /users/me/mfa/totp [post]
+// @Security	 ApiKeyAuth
+func (h *UserHandler) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	u, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	secret, uri, err := h.MFA.Enroll(u)
+	if err != nil {
+		writeMFAError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.TOTPEnrollmentOutput{Secret: secret, ProvisioningURI: uri})
+}
+
+// Confirm TOTP godoc
+// @Summary      Confirm TOTP enrollment
+// @Description  Enable two-factor authentication with the first code from the authenticator app. The recovery codes are shown only once.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request    body     dto.TOTPCodeInput  true  "totp code"
+// @Success      200  {object}  dto.RecoveryCodesOutput
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      409  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
+// @Router       /users/me/mfa/totp/confirm [post]
+// @Security	 ApiKeyAuth
+func (h *UserHandler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	var input dto.TOTPCodeInput
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		invalidBody(w, r)
+		return
+	}
+	if !requireFields(w, r, map[string]string{"code": input.Code}) {
+		return
+	}
+	u, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	codes, err := h.MFA.Confirm(u, input.Code)
+	if err != nil {
+		writeMFAError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.RecoveryCodesOutput{RecoveryCodes: codes})
+}
+
+// Disable TOTP godoc
+// @Summary      Disable TOTP
+// @Description  Disable two-factor authentication with the current password and a TOTP or recovery code. Wrong passwords and codes count towards the login lockout.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request    body     dto.DisableTOTPInput  true  "current password and totp or recovery code"
+// @Success      204
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      409  {object}  problem.Problem
+// @Failure      429  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
+// @Router       /users/me/mfa/totp [delete]
+// @Security	 ApiKeyAuth
+func (h *UserHandler) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	var input dto.DisableTOTPInput
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		invalidBody(w, r)
+		return
+	}
+	if !requireFields(w, r, map[string]string{"code": input.Code, "current_password": input.CurrentPassword}) {
+		return
+	}
+	u, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	// Um access token roubado não basta: senha e código contam para o bloqueio do login
+	if !h.checkCurrentPassword(w, r, u, input.CurrentPassword) {
+		return
+	}
+
+	err = h.MFA.Disable(u, input.Code)
+	if errors.Is(err, ErrInvalidMFACode) {
+		h.registerLoginFailure(u.Email, clientIP(r), &u.TenantID)
+	}
+	if err != nil {
+		writeMFAError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *UserHandler) currentUser(w http.ResponseWriter, r *http.Request) (*entity.User, bool) {
+	return loadCurrentUser(w, r, h.UserDB)
+}
+
+// loadCurrentUser responde 401 se o usuário do claim sub não existir mais
+func loadCurrentUser(w http.ResponseWriter, r *http.Request, userDB database.UserDBInterface) (*entity.User, bool) {
+	token, _, err := jwtauth.FromContext(r.Context())
+	if err != nil || token == nil || token.Subject() == "" {
+		problem.Write(w, r, http.StatusUnauthorized, problem.CodeInvalidToken, "access token is invalid")
+		return nil, false
+	}
+	u, err := userDB.FindByID(token.Subject())
+	if errors.Is(err, database.ErrNotFound) {
+		problem.Write(w, r, http.StatusUnauthorized, problem.CodeInvalidToken, "access token is invalid")
+		return nil, false
+	}
+	if err != nil {
+		writeRepositoryError(w, r, err, "user not found")
+		return nil, false
+	}
+	return u, true
+}
+
+func writeMFAError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidMFAToken):
+		problem.Write(w, r, http.StatusUnauthorized, problem.CodeInvalidToken, err.Error())
+	case errors.Is(err, ErrInvalidMFACode):
+		problem.Write(w, r, http.StatusUnauthorized, problem.CodeInvalidMFA, err.Error())
+	case errors.Is(err, ErrMFAAlreadyEnabled), errors.Is(err, ErrMFANotEnrolled), errors.Is(err, ErrMFANotEnabled):
+		problem.Conflict(w, r, err.Error())
+	default:
+		writeRepositoryError(w, r, err, "user not found")
+	}
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/gsouza97/go-expert-api/internal/dto"
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	"github.com/gsouza97/go-expert-api/internal/infra/webserver/problem"
+	"github.com/gsouza97/go-expert-api/pkg/totp"
+	"github.com/stretchr/testify/assert"
+)
+
+// authenticatedPost simula o jwtkeys.Verifier colocando o access token no contexto
+func authenticatedPost(t *testing.T, h *UserHandler, handler http.HandlerFunc, method, body, accessToken string) *httptest.ResponseRecorder {
+	token, err := h.Tokens.Jwt.Decode(accessToken)
+	assert.NoError(t, err)
+	req := httptest.NewRequest(method, "/users/me/mfa/totp", strings.NewReader(body))
+	req = req.WithContext(jwtauth.NewContext(req.Context(), token, nil))
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+func TestTOTPLogin(t *testing.T) {
+	h, _ := newTestUserHandler(t)
+	h.Verifier.Required = false
+	credentials := `{"email":"johndoe@test.com","password":"12345678"}`
+
+	rec := postJSON(h.CreateUser, "/users", `{"name":"John","email":"johndoe@test.com","password":"12345678"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	rec = postJSON(h.GetJWT, "/users/getToken", credentials)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var session dto.GetJWTOutput
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&session))
+
+	rec = authenticatedPost(t, h, h.EnrollTOTP, http.MethodPost, "", session.AccessToken)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var enrollment dto.TOTPEnrollmentOutput
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&enrollment))
+	assert.Contains(t, enrollment.ProvisioningURI, "secret="+enrollment.Secret)
+
+	// Antes da confirmação o login continua com um único passo
+	rec = postJSON(h.GetJWT, "/users/getToken", credentials)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = authenticatedPost(t, h, h.ConfirmTOTP, http.MethodPost, `{"code":"000000"}`, session.AccessToken)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Body.String(), problem.CodeInvalidMFA)
+
+	// A confirmação usa o código do período anterior para que o login abaixo possa usar o atual
+	previous, err := totp.Code(enrollment.Secret, time.Now().Add(-totp.Period*time.Second))
+	assert.NoError(t, err)
+	rec = authenticatedPost(t, h, h.ConfirmTOTP, http.MethodPost, `{"code":"`+previous+`"}`, session.AccessToken)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var recovery dto.RecoveryCodesOutput
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&recovery))
+	assert.Len(t, recovery.RecoveryCodes, 10)
+
+	rec = postJSON(h.GetJWT, "/users/getToken", credentials)
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	var challenge dto.MFAChallengeOutput
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&challenge))
+	assert.True(t, challenge.MFARequired)
+	assert.NotEmpty(t, challenge.MFAToken)
+
+	current, err := totp.Code(enrollment.Secret, time.Now())
+	assert.NoError(t, err)
+	login := func(code string) *httptest.ResponseRecorder {
+		return postJSON(h.GetJWTWithMFA, "/users/getToken/mfa", `{"mfa_token":"`+challenge.MFAToken+`","code":"`+code+`"}`)
+	}
+	rec = login(current)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var tokens dto.GetJWTOutput
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&tokens))
+	assert.NotEmpty(t, tokens.AccessToken)
+
+	// Códigos TOTP e de recuperação não podem ser reutilizados
+	rec = login(current)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	rec = login(recovery.RecoveryCodes[0])
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = login(recovery.RecoveryCodes[0])
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = postJSON(h.GetJWTWithMFA, "/users/getToken/mfa", `{"mfa_token":"forged","code":"`+recovery.RecoveryCodes[1]+`"}`)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Body.String(), problem.CodeInvalidToken)
+
+	rec = authenticatedPost(t, h, h.EnrollTOTP, http.MethodPost, "", session.AccessToken)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	// Desligar o segundo fator exige também a senha atual
+	rec = authenticatedPost(t, h, h.DisableTOTP, http.MethodDelete, `{"code":"`+recovery.RecoveryCodes[1]+`"}`, session.AccessToken)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"field":"current_password"`)
+	rec = authenticatedPost(t, h, h.DisableTOTP, http.MethodDelete, `{"code":"`+recovery.RecoveryCodes[1]+`","current_password":"12345678"}`, session.AccessToken)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	rec = postJSON(h.GetJWT, "/users/getToken", credentials)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestTOTPManager_ExpiredChallenge(t *testing.T) {
+	h, _ := newTestUserHandler(t)
+	h.MFA.ChallengeExpiresIn = -time.Minute
+	u, err := entity.NewUser("John", "johndoe@test.com", "12345678")
+	assert.NoError(t, err)
+	u.TOTPEnabled = true
+
+	challenge, err := h.MFA.Challenge(u)
+	assert.NoError(t, err)
+	_, err = h.MFA.ChallengeUser(challenge)
+	assert.ErrorIs(t, err, ErrInvalidMFAToken)
+}
+
+func TestDisableTOTP_Throttled(t *testing.T) {
+	h, _ := newTestUserHandler(t)
+	session := newTestSession(t, h)
+	rec := authenticatedPost(t, h, h.EnrollTOTP, http.MethodPost, "", session.AccessToken)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var enrollment dto.TOTPEnrollmentOutput
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&enrollment))
+	current, err := totp.Code(enrollment.Secret, time.Now())
+	assert.NoError(t, err)
+	rec = authenticatedPost(t, h, h.ConfirmTOTP, http.MethodPost, `{"code":"`+current+`"}`, session.AccessToken)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Senhas e códigos errados contam para o mesmo bloqueio
+	rec = authenticatedPost(t, h, h.DisableTOTP, http.MethodDelete, `{"code":"000000","current_password":"wrong-password"}`, session.AccessToken)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	for i := 0; i < 2; i++ {
+		rec = authenticatedPost(t, h, h.DisableTOTP, http.MethodDelete, `{"code":"000000","current_password":"12345678"}`, session.AccessToken)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	}
+	next, err := totp.Code(enrollment.Secret, time.Now().Add(totp.Period*time.Second))
+	assert.NoError(t, err)
+	rec = authenticatedPost(t, h, h.DisableTOTP, http.MethodDelete, `{"code":"`+next+`","current_password":"12345678"}`, session.AccessToken)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+
+	u, err := h.UserDB.FindByEmail("johndoe@test.com")
+	assert.NoError(t, err)
+	assert.True(t, u.IsMFAEnabled())
+}
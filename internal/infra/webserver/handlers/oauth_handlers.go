@@ -0,0 +1,437 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gsouza97/go-expert-api/internal/dto"
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	"github.com/gsouza97/go-expert-api/internal/infra/database"
+	entityPkg "github.com/gsouza97/go-expert-api/pkg/entity"
+)
+
+// Códigos de erro do endpoint de token definidos na RFC 6749, seção 5.2
+const (
+	OAuthInvalidRequest         = "invalid_request"
+	OAuthInvalidClient          = "invalid_client"
+	OAuthInvalidGrant           = "invalid_grant"
+	OAuthUnauthorizedClient     = "unauthorized_client"
+	OAuthUnsupportedGrantType   = "unsupported_grant_type"
+	OAuthInvalidScope           = "invalid_scope"
+	OAuthServerError            = "server_error"
+	OAuthTemporarilyUnavailable = "temporarily_unavailable"
+)
+
+const (
+	oauthRealm           = `Basic realm="oauth"`
+	oauthFormContentType = "application/x-www-form-urlencoded"
+)
+
+// OAuthHandler implementa o endpoint de token OAuth2 e o cadastro dos clientes
+type OAuthHandler struct {
+	ClientDB database.OAuthClientDBInterface
+	UserDB   database.UserDBInterface
+	Tokens   *TokenIssuer
+	Verifier *EmailVerifier
+	Throttle *LoginThrottler
+}
+
+func NewOAuthHandler(clientDB database.OAuthClientDBInterface, userDB database.UserDBInterface, tokens *TokenIssuer, verifier *EmailVerifier, throttle *LoginThrottler) *OAuthHandler {
+	return &OAuthHandler{
+		ClientDB: clientDB,
+		UserDB:   userDB,
+		Tokens:   tokens,
+		Verifier: verifier,
+		Throttle: throttle,
+	}
+}
+
+// OAuth token godoc
+// @Summary      OAuth2 token
+// @Description  Issue an access token with the client_credentials or password grant (RFC 6749). The client authenticates with HTTP Basic or with client_id and client_secret in the form. Errors follow RFC 6749 section 5.2.
+// @Tags         oauth
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        grant_type     formData  string  true   "client_credentials or password"
+// @Param        scope          formData  string  false  "space-separated permissions, defaults to all permissions of the client"
+// @Param        username       formData  string  false  "user email, password grant only"
+// @Param        password       formData  string  false  "user password, password grant only"
+// @Param        client_id      formData  string  false  "client id, when HTTP Basic is not used"
+// @Param        client_secret  formData  string  false  "client secret, when HTTP Basic is not used"
+// @Success      200  {object}  dto.OAuthTokenOutput
+// @Failure      400  {object}  dto.OAuthErrorOutput
+// @Failure      401  {object}  dto.OAuthErrorOutput
+// @Failure      500  {object}  dto.OAuthErrorOutput
+// @Failure      503  {object}  dto.OAuthErrorOutput
+// @Router       /oauth/token [post]
+func (h *OAuthHandler) Token(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), oauthFormContentType) {
+		writeOAuthError(w, http.StatusBadRequest, OAuthInvalidRequest, "the request body must be "+oauthFormContentType)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, OAuthInvalidRequest, "the request body is not a valid form")
+		return
+	}
+
+	client, ok := h.authenticateClient(w, r)
+	if !ok {
+		return
+	}
+
+	grant := r.PostForm.Get("grant_type")
+	if grant == "" {
+		writeOAuthError(w, http.StatusBadRequest, OAuthInvalidRequest, "grant_type is required")
+		return
+	}
+	if grant != entity.GrantClientCredentials && grant != entity.GrantPassword {
+		writeOAuthError(w, http.StatusBadRequest, OAuthUnsupportedGrantType, "grant_type "+grant+" is not supported")
+		return
+	}
+	if !client.AllowsGrant(grant) {
+		writeOAuthError(w, http.StatusBadRequest, OAuthUnauthorizedClient, "the client is not allowed to use the "+grant+" grant")
+		return
+	}
+	scope, err := client.ResolveScope(r.PostForm.Get("scope"))
+	if err != nil {
+		writeOAuthError(w, http.StatusBadRequest, OAuthInvalidScope, "the requested scope is not allowed for the client")
+		return
+	}
+
+	// O cliente só emite tokens para usuários do próprio tenant
+	users := h.UserDB.ForTenant(client.TenantID.String())
+	var u *entity.User
+	if grant == entity.GrantClientCredentials {
+		u, ok = serviceUser(w, users, client)
+	} else {
+		u, ok = h.resourceOwner(w, r, users)
+	}
+	if !ok {
+		return
+	}
+
+	// O token nunca tem mais permissões do que a role do usuário
+	scope, ok = restrictScope(w, u, scope, r.PostForm.Get("scope") != "")
+	if !ok {
+		return
+	}
+	accessToken, err := h.Tokens.IssueAccessToken(u, map[string]interface{}{
+		"scope": scope,
+		"cid":   client.ID.String(),
+	})
+	if err != nil {
+		writeOAuthError(w, http.StatusInternalServerError, OAuthServerError, "")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.OAuthTokenOutput{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(h.Tokens.AccessExpiresIn.Seconds()),
+		Scope:       scope,
+	})
+}
+
+// authenticateClient aceita as credenciais por HTTP Basic ou no corpo, mas não pelos dois ao mesmo tempo
+func (h *OAuthHandler) authenticateClient(w http.ResponseWriter, r *http.Request) (*entity.OAuthClient, bool) {
+	clientID, secret, basic := r.BasicAuth()
+	if basic {
+		if r.PostForm.Get("client_secret") != "" {
+			writeOAuthError(w, http.StatusBadRequest, OAuthInvalidRequest, "use only one client authentication method")
+			return nil, false
+		}
+		// Na autenticação Basic o id e o secret são codificados como form (RFC 6749, seção 2.3.1)
+		var idErr, secretErr error
+		clientID, idErr = url.QueryUnescape(clientID)
+		secret, secretErr = url.QueryUnescape(secret)
+		if idErr != nil || secretErr != nil {
+			writeOAuthClientError(w, basic)
+			return nil, false
+		}
+	} else {
+		clientID = r.PostForm.Get("client_id")
+		secret = r.PostForm.Get("client_secret")
+	}
+	if clientID == "" || secret == "" {
+		writeOAuthClientError(w, basic)
+		return nil, false
+	}
+
+	client, err := h.ClientDB.FindByID(clientID)
+	if errors.Is(err, database.ErrNotFound) {
+		writeOAuthClientError(w, basic)
+		return nil, false
+	}
+	if err != nil {
+		writeOAuthRepositoryError(w, err)
+		return nil, false
+	}
+	if !client.Matches(secret) || client.IsRevoked() {
+		writeOAuthClientError(w, basic)
+		return nil, false
+	}
+	return client, true
+}
+
+// serviceUser recusa contas de serviço removidas ou desativadas
+func serviceUser(w http.ResponseWriter, users database.UserDBInterface, client *entity.OAuthClient) (*entity.User, bool) {
+	u, err := users.FindByID(client.UserID.String())
+	if err == nil && u.IsDisabled() {
+		err = database.ErrNotFound
+	}
+	if errors.Is(err, database.ErrNotFound) {
+		writeOAuthError(w, http.StatusBadRequest, OAuthUnauthorizedClient, "the service account of the client is not active")
+		return nil, false
+	}
+	if err != nil {
+		writeOAuthRepositoryError(w, err)
+		return nil, false
+	}
+	return u, true
+}
+
+// resourceOwner aplica as regras do /users/getToken. Contas com dois fatores não podem usar o grant.
+func (h *OAuthHandler) resourceOwner(w http.ResponseWriter, r *http.Request, users database.UserDBInterface) (*entity.User, bool) {
+	username := r.PostForm.Get("username")
+	plain := r.PostForm.Get("password")
+	if username == "" || plain == "" {
+		writeOAuthError(w, http.StatusBadRequest, OAuthInvalidRequest, "username and password are required")
+		return nil, false
+	}
+
+	ip := clientIP(r)
+	if err := h.Throttle.Check(username, ip); err != nil {
+		var locked *LockedError
+		if errors.As(err, &locked) {
+			w.Header().Set("Retry-After", strconv.FormatInt(int64(math.Ceil(locked.RetryAfter.Seconds())), 10))
+			writeOAuthError(w, http.StatusBadRequest, OAuthInvalidGrant, locked.Error())
+			return nil, false
+		}
+		writeOAuthRepositoryError(w, err)
+		return nil, false
+	}
+
+	u, err := users.FindByEmail(username)
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		writeOAuthRepositoryError(w, err)
+		return nil, false
+	}
+	if err != nil || !u.ValidatePassword(plain) {
+		var tenantID *entityPkg.ID
+		if u != nil {
+			tenantID = &u.TenantID
+		}
+		if err := h.Throttle.Failure(username, ip, tenantID); err != nil {
+			log.Printf("error registering failed login: %v", err)
+		}
+		writeOAuthError(w, http.StatusBadRequest, OAuthInvalidGrant, "invalid username or password")
+		return nil, false
+	}
+	if u.PasswordNeedsRehash() {
+		rehashPassword(users, u, plain)
+	}
+
+	switch {
+	case u.IsDisabled():
+		writeOAuthError(w, http.StatusBadRequest, OAuthInvalidGrant, "account is disabled")
+		return nil, false
+	case h.Verifier.Required && !u.IsEmailVerified():
+		writeOAuthError(w, http.StatusBadRequest, OAuthInvalidGrant, "the email address is not verified")
+		return nil, false
+	case u.IsMFAEnabled():
+		writeOAuthError(w, http.StatusBadRequest, OAuthInvalidGrant, "two-factor authentication is enabled, use /users/getToken")
+		return nil, false
+	}
+	if err := h.Throttle.Success(u.Email); err != nil {
+		log.Printf("error resetting failed logins: %v", err)
+	}
+	return u, true
+}
+
+// restrictScope descarta as permissões que o usuário não tem, ou retorna erro se o scope foi pedido
+func restrictScope(w http.ResponseWriter, u *entity.User, scope string, explicit bool) (string, bool) {
+	var granted []string
+	for _, s := range strings.Fields(scope) {
+		if u.HasPermission(entity.Permission(s)) {
+			granted = append(granted, s)
+		} else if explicit {
+			writeOAuthError(w, http.StatusBadRequest, OAuthInvalidScope, "the user does not have the permission "+s)
+			return "", false
+		}
+	}
+	if len(granted) == 0 {
+		writeOAuthError(w, http.StatusBadRequest, OAuthInvalidScope, "the user has none of the permissions of the client")
+		return "", false
+	}
+	return strings.Join(granted, " "), true
+}
+
+// writeOAuthError usa o formato da RFC 6749, esperado pelos clientes OAuth2, em vez de problem+json
+func writeOAuthError(w http.ResponseWriter, status int, code, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(dto.OAuthErrorOutput{Error: code, ErrorDescription: description})
+}
+
+// writeOAuthClientError responde 401 com WWW-Authenticate, como pede a RFC para invalid_client
+func writeOAuthClientError(w http.ResponseWriter, basic bool) {
+	if basic {
+		w.Header().Set("WWW-Authenticate", oauthRealm)
+	}
+	writeOAuthError(w, http.StatusUnauthorized, OAuthInvalidClient, "client authentication failed")
+}
+
+func writeOAuthRepositoryError(w http.ResponseWriter, err error) {
+	if errors.Is(err, database.ErrUnavailable) {
+		w.Header().Set("Retry-After", "5")
+		writeOAuthError(w, http.StatusServiceUnavailable, OAuthTemporarilyUnavailable, "the service is temporarily unavailable, try again later")
+		return
+	}
+	writeOAuthError(w, http.StatusInternalServerError, OAuthServerError, "an unexpected error occurred")
+}
+
+// Create OAuth client godoc
+// @Summary      Create OAuth client
+// @Description  Register a partner system for the /oauth/token endpoint. The client secret is returned only in this response.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        request    body     dto.CreateOAuthClientInput  true  "client request"
+// @Success      201  {object}  dto.CreateOAuthClientOutput
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
+// @Router       /admin/oauth/clients [post]
+// @Security	 ApiKeyAuth
+func (h *OAuthHandler) CreateClient(w http.ResponseWriter, r *http.Request) {
+	var input dto.CreateOAuthClientInput
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		invalidBody(w, r)
+		return
+	}
+	if !requireFields(w, r, map[string]string{"name": input.Name}) {
+		return
+	}
+	tenantID, ok := currentTenant(w, r)
+	if !ok {
+		return
+	}
+
+	var userID *entityPkg.ID
+	if input.UserID != "" {
+		u, err := h.UserDB.ForTenant(tenantID).FindByID(input.UserID)
+		if errors.Is(err, database.ErrNotFound) {
+			writeValidationError(w, r, &paramError{Param: "user_id", Message: "user not found"})
+			return
+		}
+		if err != nil {
+			writeRepositoryError(w, r, err, "user not found")
+			return
+		}
+		userID = &u.ID
+	}
+	scopes := make([]entity.Permission, len(input.Scopes))
+	for i, scope := range input.Scopes {
+		scopes[i] = entity.Permission(scope)
+	}
+	client, secret, err := entity.NewOAuthClient(input.Name, input.Grants, scopes, userID)
+	if err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+	if err := h.ClientDB.ForTenant(tenantID).Create(client); err != nil {
+		writeRepositoryError(w, r, err, "oauth client not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(dto.CreateOAuthClientOutput{OAuthClientOutput: oauthClientOutput(client), ClientSecret: secret})
+}
+
+// List OAuth clients godoc
+// @Summary      List OAuth clients
+// @Description  List the registered OAuth clients, including revoked ones
+// @Tags         admin
+// @Produce      json
+// @Success      200  {array}   dto.OAuthClientOutput
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
+// @Router       /admin/oauth/clients [get]
+// @Security	 ApiKeyAuth
+func (h *OAuthHandler) GetClients(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := currentTenant(w, r)
+	if !ok {
+		return
+	}
+	clients, err := h.ClientDB.ForTenant(tenantID).FindAll()
+	if err != nil {
+		writeRepositoryError(w, r, err, "oauth client not found")
+		return
+	}
+	output := make([]dto.OAuthClientOutput, len(clients))
+	for i, client := range clients {
+		output[i] = oauthClientOutput(client)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+}
+
+// Revoke OAuth client godoc
+// @Summary      Revoke OAuth client
+// @Description  Revoke an OAuth client. Tokens already issued stay valid until they expire.
+// @Tags         admin
+// @Param		 id    path    string    true  "client id"  Format(uuid)
+// @Success      204
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
+// @Router       /admin/oauth/clients/{id} [delete]
+// @Security	 ApiKeyAuth
+func (h *OAuthHandler) RevokeClient(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := currentTenant(w, r)
+	if !ok {
+		return
+	}
+	if err := h.ClientDB.ForTenant(tenantID).Revoke(chi.URLParam(r, "id")); err != nil {
+		writeRepositoryError(w, r, err, "oauth client not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func oauthClientOutput(client *entity.OAuthClient) dto.OAuthClientOutput {
+	output := dto.OAuthClientOutput{
+		ClientID:  client.ID.String(),
+		Name:      client.Name,
+		Grants:    append([]string{}, client.GrantList()...),
+		Scopes:    append([]string{}, client.ScopeList()...),
+		CreatedAt: client.CreatedAt,
+		RevokedAt: client.RevokedAt,
+	}
+	if client.UserID != nil {
+		output.UserID = client.UserID.String()
+	}
+	return output
+}
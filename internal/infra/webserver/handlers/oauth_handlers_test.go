@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gsouza97/go-expert-api/internal/dto"
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	"github.com/gsouza97/go-expert-api/internal/infra/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestOAuthHandler(t *testing.T) (*OAuthHandler, *AdminHandler, *entity.User) {
+	admin, h, adminUser := newTestAdminHandler(t)
+	clientDB := database.NewOAuthClientDB(h.UserDB.(*database.UserDB).DB)
+	return NewOAuthHandler(clientDB, h.UserDB, h.Tokens, h.Verifier, h.Throttle), admin, adminUser
+}
+
+// createOAuthClient cadastra o cliente pela rota de administração e retorna o id e o secret
+func createOAuthClient(t *testing.T, o *OAuthHandler, admin *entity.User, body string) dto.CreateOAuthClientOutput {
+	rec := adminRequest(t, o.CreateClient, http.MethodPost, "/admin/oauth/clients", "", body, admin)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	var client dto.CreateOAuthClientOutput
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&client))
+	return client
+}
+
+// requestToken envia o form ao endpoint de token. Com basic, as credenciais vão no cabeçalho Authorization.
+func requestToken(o *OAuthHandler, form url.Values, clientID, secret string, basic bool) *httptest.ResponseRecorder {
+	if !basic {
+		form.Set("client_id", clientID)
+		form.Set("client_secret", secret)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if basic {
+		req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(secret))
+	}
+	rec := httptest.NewRecorder()
+	o.Token(rec, req)
+	return rec
+}
+
+func oauthError(t *testing.T, rec *httptest.ResponseRecorder) string {
+	var output dto.OAuthErrorOutput
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&output))
+	return output.Error
+}
+
+func TestOAuthClientCredentials(t *testing.T) {
+	o, _, admin := newTestOAuthHandler(t)
+	john, err := o.UserDB.FindByEmail("johndoe@test.com")
+	assert.NoError(t, err)
+	john.Role = entity.RoleEditor
+	assert.NoError(t, o.UserDB.Update(john))
+
+	client := createOAuthClient(t, o, admin,
+		`{"name":"Partner","grants":["client_credentials"],"scopes":["products:read","products:write"],"user_id":"`+john.ID.String()+`"}`)
+	assert.NotEmpty(t, client.ClientSecret)
+
+	rec := requestToken(o, url.Values{"grant_type": {"client_credentials"}, "scope": {"products:read"}}, client.ClientID, client.ClientSecret, true)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+	var output dto.OAuthTokenOutput
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&output))
+	assert.Equal(t, "Bearer", output.TokenType)
+	assert.Equal(t, "products:read", output.Scope)
+
+	token, err := o.Tokens.Jwt.Decode(output.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, john.ID.String(), token.Subject())
+	scope, _ := token.Get("scope")
+	assert.Equal(t, "products:read", scope)
+	cid, _ := token.Get("cid")
+	assert.Equal(t, client.ClientID, cid)
+
+	// Sem scope o token recebe todas as permissões do cliente
+	rec = requestToken(o, url.Values{"grant_type": {"client_credentials"}}, client.ClientID, client.ClientSecret, false)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&output))
+	assert.Equal(t, "products:read products:write", output.Scope)
+
+	rec = requestToken(o, url.Values{"grant_type": {"client_credentials"}, "scope": {"users:manage"}}, client.ClientID, client.ClientSecret, true)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, OAuthInvalidScope, oauthError(t, rec))
+
+	rec = requestToken(o, url.Values{"grant_type": {"password"}, "username": {"johndoe@test.com"}, "password": {"12345678"}}, client.ClientID, client.ClientSecret, true)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, OAuthUnauthorizedClient, oauthError(t, rec))
+
+	rec = requestToken(o, url.Values{"grant_type": {"authorization_code"}}, client.ClientID, client.ClientSecret, true)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, OAuthUnsupportedGrantType, oauthError(t, rec))
+
+	rec = requestToken(o, url.Values{}, client.ClientID, client.ClientSecret, true)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, OAuthInvalidRequest, oauthError(t, rec))
+
+	// Uma conta de serviço rebaixada não emite tokens com permissões que perdeu
+	john.Role = entity.RoleViewer
+	assert.NoError(t, o.UserDB.Update(john))
+	rec = requestToken(o, url.Values{"grant_type": {"client_credentials"}}, client.ClientID, client.ClientSecret, true)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&output))
+	assert.Equal(t, "products:read", output.Scope)
+	rec = requestToken(o, url.Values{"grant_type": {"client_credentials"}, "scope": {"products:write"}}, client.ClientID, client.ClientSecret, true)
+	assert.Equal(t, OAuthInvalidScope, oauthError(t, rec))
+}
+
+func TestOAuthClientAuthentication(t *testing.T) {
+	o, _, admin := newTestOAuthHandler(t)
+	client := createOAuthClient(t, o, admin, `{"name":"Partner","grants":["password"],"scopes":["products:read"]}`)
+	form := func() url.Values {
+		return url.Values{"grant_type": {"password"}, "username": {"johndoe@test.com"}, "password": {"12345678"}}
+	}
+
+	rec := requestToken(o, form(), client.ClientID, "wrong", true)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, OAuthInvalidClient, oauthError(t, rec))
+	assert.NotEmpty(t, rec.Header().Get("WWW-Authenticate"))
+
+	rec = requestToken(o, form(), "unknown", client.ClientSecret, false)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, OAuthInvalidClient, oauthError(t, rec))
+
+	// Os dois métodos de autenticação ao mesmo tempo não são aceitos
+	both := form()
+	both.Set("client_secret", client.ClientSecret)
+	rec = requestToken(o, both, client.ClientID, client.ClientSecret, true)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, OAuthInvalidRequest, oauthError(t, rec))
+
+	req := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(`{"grant_type":"password"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	o.Token(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, OAuthInvalidRequest, oauthError(t, rec))
+
+	rec = requestToken(o, form(), client.ClientID, client.ClientSecret, false)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Um cliente revogado deixa de autenticar
+	rec = adminRequest(t, o.RevokeClient, http.MethodDelete, "/admin/oauth/clients/"+client.ClientID, client.ClientID, "", admin)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	rec = requestToken(o, form(), client.ClientID, client.ClientSecret, false)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = adminRequest(t, o.GetClients, http.MethodGet, "/admin/oauth/clients", "", "", admin)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), client.ClientSecret)
+	var clients []dto.OAuthClientOutput
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&clients))
+	assert.Len(t, clients, 1)
+	assert.NotNil(t, clients[0].RevokedAt)
+}
+
+func TestOAuthPasswordGrant(t *testing.T) {
+	o, admin, adminUser := newTestOAuthHandler(t)
+	client := createOAuthClient(t, o, adminUser, `{"name":"Partner","grants":["password"],"scopes":["products:read","products:write"]}`)
+	token := func(email, password string) *httptest.ResponseRecorder {
+		return requestToken(o, url.Values{"grant_type": {"password"}, "username": {email}, "password": {password}}, client.ClientID, client.ClientSecret, true)
+	}
+
+	// O scope é limitado às permissões do viewer
+	rec := token("johndoe@test.com", "12345678")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var output dto.OAuthTokenOutput
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&output))
+	assert.Equal(t, "products:read", output.Scope)
+	assert.NotContains(t, rec.Body.String(), "refresh_token")
+
+	jane, err := o.UserDB.FindByEmail("janedoe@test.com")
+	assert.NoError(t, err)
+	rec = adminRequest(t, admin.DisableUser, http.MethodPost, "/admin/users/"+jane.ID.String()+"/disable", jane.ID.String(), "", adminUser)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = token("janedoe@test.com", "12345678")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, OAuthInvalidGrant, oauthError(t, rec))
+
+	// As falhas contam para o mesmo bloqueio do login
+	for i := 0; i < 3; i++ {
+		rec = token("johndoe@test.com", "wrong")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, OAuthInvalidGrant, oauthError(t, rec))
+	}
+	rec = token("johndoe@test.com", "12345678")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, OAuthInvalidGrant, oauthError(t, rec))
+	assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+}
+
+func TestCreateOAuthClient_Validation(t *testing.T) {
+	o, _, admin := newTestOAuthHandler(t)
+	create := func(body string) *httptest.ResponseRecorder {
+		return adminRequest(t, o.CreateClient, http.MethodPost, "/admin/oauth/clients", "", body, admin)
+	}
+
+	rec := create(`{"name":"Partner","grants":["client_credentials"],"scopes":["products:read"]}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"field":"user_id"`)
+	rec = create(`{"name":"Partner","grants":["client_credentials"],"scopes":["products:read"],"user_id":"unknown"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"field":"user_id"`)
+	rec = create(`{"name":"Partner","grants":["implicit"],"scopes":["products:read"]}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"field":"grants"`)
+	rec = create(`{"name":"Partner","grants":["password"],"scopes":["products:delete"]}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"field":"scopes"`)
+	rec = create(`{"name":"Partner","grants":["password"]}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"field":"scopes"`)
+}
+
+func TestOAuthTenantIsolation(t *testing.T) {
+	_, h, admin := newTestAdminHandler(t)
+	o := NewOAuthHandler(database.NewOAuthClientDB(h.UserDB.(*database.UserDB).DB), h.UserDB, h.Tokens, h.Verifier, h.Throttle)
+	_, globexAdmin := newTestTenant(t, h, "globex")
+	client := createOAuthClient(t, o, admin, `{"name":"Partner","grants":["password"],"scopes":["products:read"]}`)
+
+	// O cliente não emite tokens para usuários de outro tenant
+	rec := requestToken(o, url.Values{"grant_type": {"password"}, "username": {"bob@globex.com"}, "password": {"12345678"}}, client.ClientID, client.ClientSecret, true)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, OAuthInvalidGrant, oauthError(t, rec))
+
+	// A conta de serviço precisa ser do tenant do administrador que cadastra o cliente
+	bob, err := h.UserDB.FindByEmail("bob@globex.com")
+	assert.NoError(t, err)
+	rec = adminRequest(t, o.CreateClient, http.MethodPost, "/admin/oauth/clients", "", `{"name":"Partner","grants":["client_credentials"],"scopes":["products:read"],"user_id":"`+bob.ID.String()+`"}`, admin)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"field":"user_id"`)
+
+	rec = adminRequest(t, o.GetClients, http.MethodGet, "/admin/oauth/clients", "", "", globexAdmin)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[]`, rec.Body.String())
+	rec = adminRequest(t, o.RevokeClient, http.MethodDelete, "/admin/oauth/clients/"+client.ClientID, client.ClientID, "", globexAdmin)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
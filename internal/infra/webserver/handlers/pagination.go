@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+func totalPages(total int64, limit int) int {
+	if limit <= 0 || total <= 0 {
+		return 0
+	}
+	return int((total + int64(limit) - 1) / int64(limit))
+}
+
+// paginationLinks monta o header Link (RFC 5988) preservando os demais parâmetros da query
+func paginationLinks(u *url.URL, page, limit, totalPages int) string {
+	last := totalPages
+	if last < 1 {
+		last = 1
+	}
+
+	link := func(p int, rel string) string {
+		values := u.Query()
+		values.Set("page", strconv.Itoa(p))
+		values.Set("limit", strconv.Itoa(limit))
+		return fmt.Sprintf(`<%s?%s>; rel="%s"`, u.Path, values.Encode(), rel)
+	}
+
+	links := []string{link(1, "first")}
+	if page > 1 {
+		prev := page - 1
+		if prev > last {
+			prev = last
+		}
+		links = append(links, link(prev, "prev"))
+	}
+	if page < last {
+		links = append(links, link(page+1, "next"))
+	}
+	links = append(links, link(last, "last"))
+	return strings.Join(links, ", ")
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTotalPages(t *testing.T) {
+	assert.Equal(t, 0, totalPages(0, 10))
+	assert.Equal(t, 1, totalPages(1, 10))
+	assert.Equal(t, 1, totalPages(10, 10))
+	assert.Equal(t, 3, totalPages(23, 10))
+}
+
+func TestPaginationLinks(t *testing.T) {
+	u, _ := url.Parse("/products?name=mouse&page=2&limit=10")
+
+	links := paginationLinks(u, 2, 10, 3)
+	assert.Equal(t, `</products?limit=10&name=mouse&page=1>; rel="first", `+
+		`</products?limit=10&name=mouse&page=1>; rel="prev", `+
+		`</products?limit=10&name=mouse&page=3>; rel="next", `+
+		`</products?limit=10&name=mouse&page=3>; rel="last"`, links)
+
+	links = paginationLinks(u, 1, 10, 1)
+	assert.Equal(t, `</products?limit=10&name=mouse&page=1>; rel="first", `+
+		`</products?limit=10&name=mouse&page=1>; rel="last"`, links)
+
+	// Página além da última aponta o prev para a última página existente
+	links = paginationLinks(u, 9, 10, 3)
+	assert.Contains(t, links, `</products?limit=10&name=mouse&page=3>; rel="prev"`)
+	assert.NotContains(t, links, `rel="next"`)
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	"github.com/gsouza97/go-expert-api/internal/infra/database"
+	"github.com/gsouza97/go-expert-api/internal/infra/mail"
+)
+
+var ErrInvalidResetToken = errors.New("reset token is invalid or expired")
+
+// PasswordResetter envia os links de redefinição de senha e aplica a nova senha
+type PasswordResetter struct {
+	UserDB         database.UserDBInterface
+	ResetTokenDB   database.PasswordResetTokenDBInterface
+	RefreshTokenDB database.RefreshTokenDBInterface
+	Mailer         mail.Mailer
+	ExpiresIn      time.Duration
+	ResetURL       string // Recebe o token na query "token"
+}
+
+func NewPasswordResetter(userDB database.UserDBInterface, resetTokenDB database.PasswordResetTokenDBInterface, refreshTokenDB database.RefreshTokenDBInterface, mailer mail.Mailer, expiresIn time.Duration, resetURL string) *PasswordResetter {
+	return &PasswordResetter{
+		UserDB:         userDB,
+		ResetTokenDB:   resetTokenDB,
+		RefreshTokenDB: refreshTokenDB,
+		Mailer:         mailer,
+		ExpiresIn:      expiresIn,
+		ResetURL:       resetURL,
+	}
+}
+
+// Request ignora emails não cadastrados, para não revelar quais endereços possuem conta
+func (p *PasswordResetter) Request(email string) error {
+	user, err := p.UserDB.FindByEmail(email)
+	if errors.Is(err, database.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token, plain, err := entity.NewPasswordResetToken(user.ID, p.ExpiresIn)
+	if err != nil {
+		return err
+	}
+	if err := p.ResetTokenDB.Create(token); err != nil {
+		return err
+	}
+	link := p.ResetURL + "?token=" + url.QueryEscape(plain)
+	return p.Mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nReset your password by opening the link below:\n\n%s\n\nThe link expires in %s and can be used only once. If you did not ask for a new password, ignore this email.\n",
+			user.Name, link, p.ExpiresIn),
+	})
+}
+
+// Reset troca a senha, consome os tokens de redefinição e encerra todas as sessões
+func (p *PasswordResetter) Reset(plain, password string) error {
+	token, err := p.ResetTokenDB.FindByHash(entity.HashToken(plain))
+	if errors.Is(err, database.ErrNotFound) {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+	if token.IsUsed() || token.IsExpired() {
+		return ErrInvalidResetToken
+	}
+
+	used, err := p.ResetTokenDB.MarkUsed(token.ID.String())
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidResetToken
+	}
+
+	user, err := p.UserDB.FindByID(token.UserID.String())
+	if errors.Is(err, database.ErrNotFound) {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+	if err := user.SetPassword(password); err != nil {
+		return err
+	}
+	if err := p.UserDB.Update(user); err != nil {
+		return err
+	}
+
+	if err := p.ResetTokenDB.InvalidateByUser(user.ID.String()); err != nil {
+		return err
+	}
+	if err := p.RefreshTokenDB.RevokeAllByUser(user.ID.String()); err != nil {
+		return err
+	}
+	return p.UserDB.RevokeSessions(user.ID.String())
+}
@@ -2,53 +2,85 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
+	"math"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth"
 	"github.com/gsouza97/go-expert-api/internal/dto"
 	"github.com/gsouza97/go-expert-api/internal/entity"
 	"github.com/gsouza97/go-expert-api/internal/infra/database"
+	"github.com/gsouza97/go-expert-api/internal/infra/webserver/problem"
+	"github.com/gsouza97/go-expert-api/pkg/cursor"
 	entityPkg "github.com/gsouza97/go-expert-api/pkg/entity"
+	"github.com/gsouza97/go-expert-api/pkg/mergepatch"
 )
 
+const defaultPageSize = 20
+
 type ProductHandler struct {
-	ProductDB database.ProductDBInterface
+	ProductDB   database.ProductDBInterface
+	MaxPageSize int
+	Cursors     *cursor.Signer
+	// PUT, PATCH e DELETE sem If-Match são recusados com 428
+	RequireIfMatch bool
 }
 
-func NewProductHandler(db database.ProductDBInterface) *ProductHandler {
+func NewProductHandler(db database.ProductDBInterface, maxPageSize int, cursors *cursor.Signer, requireIfMatch bool) *ProductHandler {
 	return &ProductHandler{
-		ProductDB: db,
+		ProductDB:      db,
+		MaxPageSize:    maxPageSize,
+		Cursors:        cursors,
+		RequireIfMatch: requireIfMatch,
 	}
 }
 
 // Create product godoc
 // @Summary      Create product
-// @Description  Create product
+// @Description  Create product owned by the current user
 // @Tags         products
 // @Accept       json
 // @Produce      json
 // @Param        request    body     dto.CreateProductInput  true  "product request"
 // @Success      201
-// @Failure      400  {object}  Error
-// @Failure      500  {object}  Error
+// @Failure      400  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
 // @Router       /products [post]
 // @Security	 ApiKeyAuth
+// @Security	 XAPIKey
 func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 	var product dto.CreateProductInput
 	err := json.NewDecoder(r.Body).Decode(&product)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		invalidBody(w, r)
 		return
 	}
 	p, err := entity.NewProduct(product.Name, product.Price)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeValidationError(w, r, err)
+		return
+	}
+	userID, _, ok := productActor(w, r)
+	if !ok {
 		return
 	}
-	err = h.ProductDB.CreateProduct(p)
+	products, ok := h.products(w, r)
+	if !ok {
+		return
+	}
+	p.CreatedBy = &userID
+	p.UpdatedBy = &userID
+	err = products.CreateProduct(p)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		writeRepositoryError(w, r, err, "product could not be created")
 		return
 	}
 	w.WriteHeader(http.StatusCreated)
@@ -56,26 +88,41 @@ func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 
 // Get single product godoc
 // @Summary      Get product
-// @Description  Get product
+// @Description  Get product. The ETag header identifies the version and can be sent in If-None-Match to skip unchanged products, or in If-Match to update them.
 // @Tags         products
 // @Accept       json
 // @Produce      json
 // @Param		 id    	path     string    true  "product id"  Format(uuid)
+// @Param		 If-None-Match  header  string  false  "ETag of the cached version"
 // @Success      200  {object}  entity.Product
-// @Failure      400  {object}  Error
-// @Failure      404  {object}  Error
-// @Failure      500  {object}  Error
+// @Header       200  {string}  ETag "version of the product"
+// @Success      304  "the product has not changed"
+// @Failure      400  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
 // @Router       /products/{id} [get]
 // @Security	 ApiKeyAuth
+// @Security	 XAPIKey
 func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		w.WriteHeader(http.StatusBadRequest)
+		problem.Validation(w, r, requiredField("id"))
 		return
 	}
-	p, err := h.ProductDB.FindByID(id)
+	products, ok := h.products(w, r)
+	if !ok {
+		return
+	}
+	p, err := products.FindByID(id)
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
+		writeRepositoryError(w, r, err, "product not found")
+		return
+	}
+	etag := productETag(p)
+	w.Header().Set("ETag", etag)
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" && etagMatches(ifNoneMatch, etag, true) {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -90,117 +137,616 @@ func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 
 // List products godoc
 // @Summary      List products
-// @Description  List all product
+// @Description  List products with optional filters and sorting. Navigation links are returned in the Link header (RFC 5988).
+// @Description  When the cursor parameter is present (empty for the first page) the response is paginated by (created_at, id) and returns a dto.ProductCursorOutput instead; sort and page are not accepted in this mode.
 // @Tags         products
 // @Accept       json
 // @Produce      json
-// @Param		 page    	query     string  	false  "page number"
-// @Param		 limit   	query     string  	false  "page limit"
-// @Success      200  {array}   entity.Product
-// @Failure      500  {object}  Error
+// @Param		 cursor    		query     string  	false  "opaque cursor returned in next_cursor, enables keyset pagination"
+// @Param		 page    		query     int  		false  "page number, starting at 1"
+// @Param		 limit   		query     int  		false  "page size, capped at the configured maximum"
+// @Param		 name    		query     string  	false  "name contains (case insensitive)"
+// @Param		 min_price   	query     number  	false  "minimum price"
+// @Param		 max_price   	query     number  	false  "maximum price"
+// @Param		 created_after  query     string  	false  "created at or after (RFC 3339 or YYYY-MM-DD)"
+// @Param		 created_before query     string  	false  "created at or before (RFC 3339 or YYYY-MM-DD)"
+// @Param		 sort   		query     string  	false  "comma separated field:direction, fields: name, price, created_at"  example(price:desc,name:asc)
+// @Success      200  {object}  dto.ProductListOutput
+// @Header       200  {string}  Link "first, prev, next and last pages"
+// @Header       200  {integer} X-Total-Count "total of products matching the filters"
+// @Failure      400  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
 // @Router       /products [get]
 // @Security	 ApiKeyAuth
+// @Security	 XAPIKey
 func (h *ProductHandler) GetProducts(w http.ResponseWriter, r *http.Request) {
-	page := chi.URLParam(r, "page")
-	limit := chi.URLParam(r, "limit")
-	pageInt, err := strconv.Atoi(page)
+	query, err := parseProductQuery(r.URL.Query())
 	if err != nil {
-		pageInt = 0
+		writeValidationError(w, r, err)
+		return
 	}
-	limitInt, err := strconv.Atoi(limit)
+	h.listProducts(w, r, query)
+}
+
+// List current user products godoc
+// @Summary      List current user products
+// @Description  List the products created by the current user. Accepts the same filters, sorting and pagination as GET /products.
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param		 cursor    		query     string  	false  "opaque cursor returned in next_cursor, enables keyset pagination"
+// @Param		 page    		query     int  		false  "page number, starting at 1"
+// @Param		 limit   		query     int  		false  "page size, capped at the configured maximum"
+// @Param		 name    		query     string  	false  "name contains (case insensitive)"
+// @Param		 min_price   	query     number  	false  "minimum price"
+// @Param		 max_price   	query     number  	false  "maximum price"
+// @Param		 created_after  query     string  	false  "created at or after (RFC 3339 or YYYY-MM-DD)"
+// @Param		 created_before query     string  	false  "created at or before (RFC 3339 or YYYY-MM-DD)"
+// @Param		 sort   		query     string  	false  "comma separated field:direction, fields: name, price, created_at"  example(price:desc,name:asc)
+// @Success      200  {object}  dto.ProductListOutput
+// @Header       200  {string}  Link "first, prev, next and last pages"
+// @Header       200  {integer} X-Total-Count "total of products matching the filters"
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
+// @Router       /users/me/products [get]
+// @Security	 ApiKeyAuth
+// @Security	 XAPIKey
+func (h *ProductHandler) GetMyProducts(w http.ResponseWriter, r *http.Request) {
+	query, err := parseProductQuery(r.URL.Query())
 	if err != nil {
-		limitInt = 0
+		writeValidationError(w, r, err)
+		return
+	}
+	userID, _, ok := productActor(w, r)
+	if !ok {
+		return
 	}
+	query.Filter.CreatedBy = userID.String()
+	h.listProducts(w, r, query)
+}
 
-	sort := chi.URLParam(r, "sort")
+func (h *ProductHandler) listProducts(w http.ResponseWriter, r *http.Request, query database.ProductQuery) {
+	productDB, ok := h.products(w, r)
+	if !ok {
+		return
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultPageSize
+	}
+	if h.MaxPageSize > 0 && query.Limit > h.MaxPageSize {
+		query.Limit = h.MaxPageSize
+	}
+	if r.URL.Query().Has("cursor") {
+		h.getProductsByCursor(w, r, productDB, query)
+		return
+	}
+	if query.Page == 0 {
+		query.Page = 1
+	}
 
-	products, err := h.ProductDB.FindAll(pageInt, limitInt, sort)
+	products, err := productDB.FindAll(query)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		writeRepositoryError(w, r, err, "product not found")
+		return
+	}
+	total, err := productDB.Count(query.Filter)
+	if err != nil {
+		writeRepositoryError(w, r, err, "product not found")
 		return
 	}
+
+	if products == nil {
+		products = []*entity.Product{}
+	}
+	output := dto.ProductListOutput{
+		Items:      products,
+		Page:       query.Page,
+		Limit:      query.Limit,
+		Total:      total,
+		TotalPages: totalPages(total, query.Limit),
+	}
+
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Total-Count", strconv.FormatInt(total, 10))
+	w.Header().Set("Link", paginationLinks(r.URL, output.Page, output.Limit, output.TotalPages))
 	w.WriteHeader(http.StatusOK)
 	// retorna o json dos produtos encontrados
-	err = json.NewEncoder(w).Encode(products)
+	err = json.NewEncoder(w).Encode(output)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 }
 
+// getProductsByCursor lista os produtos após o cursor informado
+func (h *ProductHandler) getProductsByCursor(w http.ResponseWriter, r *http.Request, productDB database.ProductDBInterface, query database.ProductQuery) {
+	if query.Page != 0 {
+		writeValidationError(w, r, &paramError{Param: "page", Message: "page cannot be combined with cursor"})
+		return
+	}
+	if len(query.Sort) > 0 {
+		writeValidationError(w, r, &paramError{Param: "sort", Message: "sort cannot be combined with cursor"})
+		return
+	}
+
+	var after *database.ProductCursor
+	if c := r.URL.Query().Get("cursor"); c != "" {
+		after = &database.ProductCursor{}
+		if err := h.Cursors.Decode(c, after); err != nil {
+			writeValidationError(w, r, &paramError{Param: "cursor", Message: "cursor is invalid"})
+			return
+		}
+	}
+
+	// Busca um item a mais para saber se existe uma próxima página
+	products, err := productDB.FindAfter(after, query.Limit+1, query.Filter)
+	if err != nil {
+		writeRepositoryError(w, r, err, "product not found")
+		return
+	}
+
+	output := dto.ProductCursorOutput{Items: products, Limit: query.Limit}
+	if len(products) > query.Limit {
+		output.Items = products[:query.Limit]
+		last := output.Items[len(output.Items)-1]
+		output.NextCursor, err = h.Cursors.Encode(database.ProductCursor{CreatedAt: last.CreatedAt, ID: last.ID.String()})
+		if err != nil {
+			problem.Internal(w, r)
+			return
+		}
+		values := r.URL.Query()
+		values.Set("cursor", output.NextCursor)
+		values.Set("limit", strconv.Itoa(query.Limit))
+		w.Header().Set("Link", fmt.Sprintf(`<%s?%s>; rel="next"`, r.URL.Path, values.Encode()))
+	}
+	if output.Items == nil {
+		output.Items = []*entity.Product{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+}
+
 // Update product godoc
 // @Summary      Update product
-// @Description  Update product
+// @Description  Replace the name and price of a product. Omitted fields are not kept: the body must be the full product. Only the user who created the product or an admin can update it.
 // @Tags         products
 // @Accept       json
 // @Produce      json
 // @Param		 id    		path     string    				 true  "product id"  Format(uuid)
 // @Param        request    body     dto.CreateProductInput  true  "product request"
-// @Success      200
-// @Failure      400  {object}  Error
-// @Failure      404  {object}  Error
-// @Failure      500  {object}  Error
+// @Param		 If-Match   header   string  				 false "ETag of the version being replaced"
+// @Success      200  {object}  entity.Product
+// @Header       200  {string}  ETag "new version of the product"
+// @Failure      400  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      412  {object}  problem.Problem
+// @Failure      428  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
 // @Router       /products/{id} [put]
 // @Security	 ApiKeyAuth
+// @Security	 XAPIKey
 func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
-	id := chi.URLParam(r, "id")
-	if id == "" {
-		w.WriteHeader(http.StatusBadRequest)
+	var input dto.CreateProductInput
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		invalidBody(w, r)
 		return
 	}
-	var product entity.Product
-	err := json.NewDecoder(r.Body).Decode(&product)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+	h.modifyProduct(w, r, func(p *entity.Product) error {
+		p.Name = input.Name
+		p.Price = input.Price
+		return nil
+	})
+}
+
+// Patch product godoc
+// @Summary      Patch product
+// @Description  Change only the fields present in the body, following JSON Merge Patch (RFC 7386). Only the user who created the product or an admin can change it.
+// @Tags         products
+// @Accept       application/merge-patch+json
+// @Produce      json
+// @Param		 id    		path     string    				 true  "product id"  Format(uuid)
+// @Param        request    body     dto.CreateProductInput  true  "fields to change"
+// @Param		 If-Match   header   string  				 false "ETag of the version being changed"
+// @Success      200  {object}  entity.Product
+// @Header       200  {string}  ETag "new version of the product"
+// @Failure      400  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      415  {object}  problem.Problem
+// @Failure      412  {object}  problem.Problem
+// @Failure      428  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
+// @Router       /products/{id} [patch]
+// @Security	 ApiKeyAuth
+// @Security	 XAPIKey
+func (h *ProductHandler) PatchProduct(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), mergepatch.ContentType) {
+		w.Header().Set("Accept-Patch", mergepatch.ContentType)
+		problem.Write(w, r, http.StatusUnsupportedMediaType, problem.CodeUnsupportedMed, "the request body must be "+mergepatch.ContentType)
 		return
 	}
-	product.ID, err = entityPkg.ParseId(id)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+	patch, err := io.ReadAll(r.Body)
+	if err != nil || !json.Valid(patch) {
+		invalidBody(w, r)
 		return
 	}
-	_, err = h.ProductDB.FindByID(id)
-	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
+	h.modifyProduct(w, r, func(p *entity.Product) error {
+		current, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+		merged, err := mergepatch.Apply(current, patch)
+		if err != nil {
+			return err
+		}
+		var patched entity.Product
+		if err := json.Unmarshal(merged, &patched); err != nil {
+			return err
+		}
+		*p = patched
+		return nil
+	})
+}
+
+// modifyProduct aplica a alteração ao produto do path. Um erro de apply indica um corpo inválido.
+func (h *ProductHandler) modifyProduct(w http.ResponseWriter, r *http.Request, apply func(p *entity.Product) error) {
+	id, ok := productIDParam(w, r)
+	if !ok {
+		return
+	}
+	userID, canManage, ok := productActor(w, r)
+	if !ok {
+		return
+	}
+	products, ok := h.products(w, r)
+	if !ok {
 		return
 	}
-	err = h.ProductDB.Update(&product)
+	current, err := products.FindByID(id)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		writeRepositoryError(w, r, err, "product not found")
+		return
+	}
+	if !canModifyProduct(w, r, current, userID, canManage) {
+		return
+	}
+	if !h.checkIfMatch(w, r, current) {
+		return
+	}
+
+	product := *current
+	if err := apply(&product); err != nil {
+		invalidBody(w, r)
+		return
+	}
+	// Os campos imutáveis e o dono não mudam, mesmo que o corpo traga outros valores
+	product.ID = current.ID
+	product.TenantID = current.TenantID
+	product.CreatedAt = current.CreatedAt
+	product.CreatedBy = current.CreatedBy
+	product.Version = current.Version
+	product.UpdatedBy = &userID
+	if err := product.Validate(); err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+	if err := products.Update(&product); err != nil {
+		writeRepositoryError(w, r, err, "product not found")
 		return
 	}
+	w.Header().Set("ETag", productETag(&product))
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(product)
 }
 
 // Delete product godoc
 // @Summary      Delete product
-// @Description  Delete product
+// @Description  Move the product to the trash. It can be restored until it is purged by an admin or by the retention job. Only the user who created the product or an admin can delete it.
 // @Tags         products
 // @Accept       json
 // @Produce      json
 // @Param		 id    path    string    true  "product id"  Format(uuid)
+// @Param		 If-Match  header  string  false  "ETag of the version being deleted"
 // @Success      200
-// @Failure      400  {object}  Error
-// @Failure      404  {object}  Error
-// @Failure      500  {object}  Error
+// @Failure      400  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      412  {object}  problem.Problem
+// @Failure      428  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
 // @Router       /products/{id} [delete]
 // @Security	 ApiKeyAuth
+// @Security	 XAPIKey
 func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
-	id := chi.URLParam(r, "id")
-	if id == "" {
-		w.WriteHeader(http.StatusBadRequest)
+	id, ok := productIDParam(w, r)
+	if !ok {
 		return
 	}
-	_, err := h.ProductDB.FindByID(id)
+	userID, canManage, ok := productActor(w, r)
+	if !ok {
+		return
+	}
+	products, ok := h.products(w, r)
+	if !ok {
+		return
+	}
+	current, err := products.FindByID(id)
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
+		writeRepositoryError(w, r, err, "product not found")
+		return
+	}
+	if !canModifyProduct(w, r, current, userID, canManage) {
 		return
 	}
-	err = h.ProductDB.Delete(id)
+	if !h.checkIfMatch(w, r, current) {
+		return
+	}
+	err = products.Delete(id, current.Version)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		writeRepositoryError(w, r, err, "product not found")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// List trash godoc
+// @Summary      List deleted products
+// @Description  List the products in the trash. Admins see every deleted product of the tenant, other users only the ones they created. Accepts the same filters, sorting and pagination as GET /products.
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param		 cursor    		query     string  	false  "opaque cursor returned in next_cursor, enables keyset pagination"
+// @Param		 page    		query     int  		false  "page number, starting at 1"
+// @Param		 limit   		query     int  		false  "page size, capped at the configured maximum"
+// @Param		 name    		query     string  	false  "name contains (case insensitive)"
+// @Param		 min_price   	query     number  	false  "minimum price"
+// @Param		 max_price   	query     number  	false  "maximum price"
+// @Param		 created_after  query     string  	false  "created at or after (RFC 3339 or YYYY-MM-DD)"
+// @Param		 created_before query     string  	false  "created at or before (RFC 3339 or YYYY-MM-DD)"
+// @Param		 sort   		query     string  	false  "comma separated field:direction, fields: name, price, created_at"  example(price:desc,name:asc)
+// @Success      200  {object}  dto.ProductListOutput
+// @Header       200  {string}  Link "first, prev, next and last pages"
+// @Header       200  {integer} X-Total-Count "total of products matching the filters"
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
+// @Router       /products/trash [get]
+// @Security	 ApiKeyAuth
+// @Security	 XAPIKey
+func (h *ProductHandler) GetTrash(w http.ResponseWriter, r *http.Request) {
+	query, err := parseProductQuery(r.URL.Query())
+	if err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+	userID, canManage, ok := productActor(w, r)
+	if !ok {
+		return
+	}
+	if !canManage {
+		query.Filter.CreatedBy = userID.String()
+	}
+	query.Filter.Deleted = true
+	h.listProducts(w, r, query)
+}
+
+// Restore product godoc
+// @Summary      Restore product
+// @Description  Take a product out of the trash. Only the user who created the product or an admin can restore it.
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param		 id    path    string    true  "product id"  Format(uuid)
+// @Success      200  {object}  entity.Product
+// @Header       200  {string}  ETag "version of the restored product"
+// @Failure      400  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      412  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
+// @Router       /products/{id}/restore [post]
+// @Security	 ApiKeyAuth
+// @Security	 XAPIKey
+func (h *ProductHandler) RestoreProduct(w http.ResponseWriter, r *http.Request) {
+	id, ok := productIDParam(w, r)
+	if !ok {
+		return
+	}
+	userID, canManage, ok := productActor(w, r)
+	if !ok {
+		return
+	}
+	products, ok := h.products(w, r)
+	if !ok {
+		return
+	}
+	current, err := products.FindDeletedByID(id)
+	if err != nil {
+		writeRepositoryError(w, r, err, "product not found in trash")
+		return
+	}
+	if !canModifyProduct(w, r, current, userID, canManage) {
+		return
+	}
+	if err := products.Restore(id, current.Version); err != nil {
+		writeRepositoryError(w, r, err, "product not found in trash")
+		return
+	}
+	p, err := products.FindByID(id)
+	if err != nil {
+		writeRepositoryError(w, r, err, "product not found")
 		return
 	}
+	w.Header().Set("ETag", productETag(p))
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(p)
+}
+
+// Purge product godoc
+// @Summary      Purge product
+// @Description  Permanently delete a product that is in the trash. Requires the products:manage permission.
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param		 id    path    string    true  "product id"  Format(uuid)
+// @Success      204
+// @Failure      400  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
+// @Router       /products/trash/{id} [delete]
+// @Security	 ApiKeyAuth
+// @Security	 XAPIKey
+func (h *ProductHandler) PurgeProduct(w http.ResponseWriter, r *http.Request) {
+	id, ok := productIDParam(w, r)
+	if !ok {
+		return
+	}
+	products, ok := h.products(w, r)
+	if !ok {
+		return
+	}
+	if err := products.Purge(id); err != nil {
+		writeRepositoryError(w, r, err, "product not found in trash")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// products retorna o repositório de produtos restrito ao tenant do usuário autenticado
+func (h *ProductHandler) products(w http.ResponseWriter, r *http.Request) (database.ProductDBInterface, bool) {
+	tenantID, ok := currentTenant(w, r)
+	if !ok {
+		return nil, false
+	}
+	return h.ProductDB.ForTenant(tenantID), true
+}
+
+// productActor retorna o usuário autenticado e se ele pode alterar produtos de outros usuários
+func productActor(w http.ResponseWriter, r *http.Request) (entityPkg.ID, bool, bool) {
+	token, claims, err := jwtauth.FromContext(r.Context())
+	if err != nil || token == nil {
+		problem.Write(w, r, http.StatusUnauthorized, problem.CodeInvalidToken, "access token is invalid")
+		return entityPkg.ID{}, false, false
+	}
+	userID, err := entityPkg.ParseId(token.Subject())
+	if err != nil {
+		problem.Write(w, r, http.StatusUnauthorized, problem.CodeInvalidToken, "access token is invalid")
+		return entityPkg.ID{}, false, false
+	}
+	role, _ := claims["role"].(string)
+	canManage := entity.RoleHasPermission(role, entity.PermissionProductsManage)
+	if scope, ok := claims["scope"].(string); ok && !entity.ScopeHasPermission(scope, entity.PermissionProductsManage) {
+		canManage = false
+	}
+	return userID, canManage, true
+}
+
+// canModifyProduct responde 403 se o usuário não for o dono nem puder gerenciar o produto
+func canModifyProduct(w http.ResponseWriter, r *http.Request, p *entity.Product, userID entityPkg.ID, canManage bool) bool {
+	if canManage || p.IsOwnedBy(userID) {
+		return true
+	}
+	problem.Forbidden(w, r, "only the owner of the product or an admin can change it")
+	return false
+}
+
+// parseProductQuery ignora os parâmetros ausentes e recusa os inválidos
+func parseProductQuery(values url.Values) (database.ProductQuery, error) {
+	var query database.ProductQuery
+	var err error
+
+	if query.Page, err = parseOptionalInt(values, "page"); err != nil {
+		return query, err
+	}
+	if query.Limit, err = parseOptionalInt(values, "limit"); err != nil {
+		return query, err
+	}
+	if query.Sort, err = database.ParseProductSort(values.Get("sort")); err != nil {
+		return query, &paramError{Param: "sort", Message: err.Error()}
+	}
+
+	query.Filter.Name = strings.TrimSpace(values.Get("name"))
+	if query.Filter.MinPrice, err = parseOptionalFloat(values, "min_price"); err != nil {
+		return query, err
+	}
+	if query.Filter.MaxPrice, err = parseOptionalFloat(values, "max_price"); err != nil {
+		return query, err
+	}
+	if query.Filter.MinPrice != nil && query.Filter.MaxPrice != nil && *query.Filter.MinPrice > *query.Filter.MaxPrice {
+		return query, &paramError{Param: "min_price", Message: "min_price must not be greater than max_price"}
+	}
+	if query.Filter.CreatedAfter, err = parseOptionalTime(values, "created_after"); err != nil {
+		return query, err
+	}
+	if query.Filter.CreatedBefore, err = parseOptionalTime(values, "created_before"); err != nil {
+		return query, err
+	}
+	return query, nil
+}
+
+func parseOptionalInt(values url.Values, param string) (int, error) {
+	value := values.Get(param)
+	if value == "" {
+		return 0, nil
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil || i < 0 {
+		return 0, &paramError{Param: param, Message: fmt.Sprintf("%s must be a positive integer", param)}
+	}
+	return i, nil
+}
+
+// productIDParam valida o id do produto na rota
+func productIDParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		problem.Validation(w, r, requiredField("id"))
+		return "", false
+	}
+	if _, err := entityPkg.ParseId(id); err != nil {
+		writeValidationError(w, r, entity.ErrInvalidID)
+		return "", false
+	}
+	return id, true
+}
+
+func parseOptionalFloat(values url.Values, param string) (*float64, error) {
+	value := values.Get(param)
+	if value == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f < 0 || math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, &paramError{Param: param, Message: fmt.Sprintf("%s must be a positive number", param)}
+	}
+	return &f, nil
+}
+
+func parseOptionalTime(values url.Values, param string) (*time.Time, error) {
+	value := values.Get(param)
+	if value == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, &paramError{Param: param, Message: fmt.Sprintf("%s must be a RFC 3339 date time or a YYYY-MM-DD date", param)}
 }
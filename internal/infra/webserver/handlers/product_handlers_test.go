@@ -0,0 +1,573 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth"
+	"github.com/gsouza97/go-expert-api/internal/dto"
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	"github.com/gsouza97/go-expert-api/internal/infra/database"
+	"github.com/gsouza97/go-expert-api/internal/infra/webserver/problem"
+	"github.com/gsouza97/go-expert-api/pkg/cursor"
+	entityPkg "github.com/gsouza97/go-expert-api/pkg/entity"
+	"github.com/gsouza97/go-expert-api/pkg/mergepatch"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestParseProductQuery(t *testing.T) {
+	values, _ := url.ParseQuery("page=2&limit=10&name=mouse&min_price=10&max_price=99.9&created_after=2023-01-02&sort=price:desc,name")
+	query, err := parseProductQuery(values)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, query.Page)
+	assert.Equal(t, 10, query.Limit)
+	assert.Equal(t, "mouse", query.Filter.Name)
+	assert.Equal(t, 10.0, *query.Filter.MinPrice)
+	assert.Equal(t, 99.9, *query.Filter.MaxPrice)
+	assert.Equal(t, time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC), *query.Filter.CreatedAfter)
+	assert.Nil(t, query.Filter.CreatedBefore)
+	assert.Equal(t, []database.SortField{{Field: "price", Desc: true}, {Field: "name"}}, query.Sort)
+
+	query, err = parseProductQuery(url.Values{})
+	assert.NoError(t, err)
+	assert.Equal(t, database.ProductQuery{}, query)
+}
+
+func TestParseProductQuery_Invalid(t *testing.T) {
+	for _, raw := range []string{
+		"page=abc",
+		"limit=-1",
+		"min_price=cheap",
+		"min_price=NaN",
+		"max_price=Inf",
+		"max_price=-Inf",
+		"min_price=10&max_price=5",
+		"created_after=yesterday",
+		"sort=password:asc",
+		"sort=price:sideways",
+	} {
+		values, _ := url.ParseQuery(raw)
+		_, err := parseProductQuery(values)
+		assert.Error(t, err, raw)
+	}
+}
+
+func newTestProductDB(t *testing.T) *database.ProductDB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.AutoMigrate(&entity.Product{})
+	return database.NewProductDB(db)
+}
+
+// readerClaims são as claims de um usuário com acesso de leitura aos produtos do tenant
+func readerClaims(tenantID string) map[string]interface{} {
+	return map[string]interface{}{"sub": entityPkg.NewId().String(), "tid": tenantID, "role": entity.RoleViewer}
+}
+
+func TestGetProducts_Envelope(t *testing.T) {
+	productDB := newTestProductDB(t)
+	tenantID := entityPkg.NewId().String()
+	for i := 1; i <= 7; i++ {
+		product, err := entity.NewProduct(fmt.Sprintf("Product %d", i), float64(i))
+		assert.NoError(t, err)
+		assert.NoError(t, productDB.ForTenant(tenantID).CreateProduct(product))
+	}
+	h := NewProductHandler(productDB, 5, cursor.NewSigner([]byte("secret")), false)
+	reader := readerClaims(tenantID)
+
+	rec := productRequest(t, h.GetProducts, http.MethodGet, "/products?limit=50&page=2&sort=price", "", "", reader)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var output dto.ProductListOutput
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&output))
+	assert.Equal(t, 2, output.Page)
+	assert.Equal(t, 5, output.Limit)
+	assert.Equal(t, int64(7), output.Total)
+	assert.Equal(t, 2, output.TotalPages)
+	assert.Len(t, output.Items, 2)
+	assert.Equal(t, "Product 6", output.Items[0].Name)
+	assert.Equal(t, "7", rec.Header().Get("X-Total-Count"))
+	assert.Contains(t, rec.Header().Get("Link"), `rel="prev"`)
+	assert.NotContains(t, rec.Header().Get("Link"), `rel="next"`)
+
+	rec = productRequest(t, h.GetProducts, http.MethodGet, "/products?name=nothing", "", "", reader)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"items":[],"page":1,"limit":5,"total":0,"total_pages":0}`, rec.Body.String())
+
+	rec = productRequest(t, h.GetProducts, http.MethodGet, "/products?sort=secret", "", "", reader)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestGetProducts_Cursor(t *testing.T) {
+	productDB := newTestProductDB(t)
+	tenantID := entityPkg.NewId().String()
+	for i := 1; i <= 5; i++ {
+		product, err := entity.NewProduct(fmt.Sprintf("Product %d", i), float64(i))
+		assert.NoError(t, err)
+		assert.NoError(t, productDB.ForTenant(tenantID).CreateProduct(product))
+	}
+	h := NewProductHandler(productDB, 100, cursor.NewSigner([]byte("secret")), false)
+	reader := readerClaims(tenantID)
+
+	var names []string
+	target := "/products?cursor=&limit=2"
+	for target != "" {
+		rec := productRequest(t, h.GetProducts, http.MethodGet, target, "", "", reader)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var output dto.ProductCursorOutput
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(&output))
+		for _, p := range output.Items {
+			names = append(names, p.Name)
+		}
+		target = ""
+		if output.NextCursor != "" {
+			assert.Contains(t, rec.Header().Get("Link"), `rel="next"`)
+			target = "/products?limit=2&cursor=" + url.QueryEscape(output.NextCursor)
+		}
+	}
+	assert.ElementsMatch(t, []string{"Product 1", "Product 2", "Product 3", "Product 4", "Product 5"}, names)
+
+	for _, target := range []string{
+		"/products?cursor=forged",
+		"/products?cursor=&sort=price",
+		"/products?cursor=&page=2",
+	} {
+		rec := productRequest(t, h.GetProducts, http.MethodGet, target, "", "", reader)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, target)
+	}
+}
+
+func TestCreateProduct_ValidationProblem(t *testing.T) {
+	h := NewProductHandler(newTestProductDB(t), 100, cursor.NewSigner([]byte("secret")), false)
+
+	for body, field := range map[string]string{
+		`{"name": "", "price": 10}`:        "name",
+		`{"name": "Product", "price": -1}`: "price",
+		`{"name": "Product", "price": 0}`:  "price",
+	} {
+		rec := httptest.NewRecorder()
+		h.CreateProduct(rec, httptest.NewRequest(http.MethodPost, "/products", strings.NewReader(body)))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, problem.ContentType, rec.Header().Get("Content-Type"))
+
+		var p problem.Problem
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(&p))
+		assert.Equal(t, problem.CodeValidation, p.Code)
+		assert.Equal(t, "/products", p.Instance)
+		assert.Len(t, p.Errors, 1)
+		assert.Equal(t, field, p.Errors[0].Field)
+	}
+
+	rec := httptest.NewRecorder()
+	h.CreateProduct(rec, httptest.NewRequest(http.MethodPost, "/products", strings.NewReader("{")))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), problem.CodeInvalidRequest)
+}
+
+func TestGetProduct_RepositoryErrors(t *testing.T) {
+	productDB := newTestProductDB(t)
+	h := NewProductHandler(productDB, 100, cursor.NewSigner([]byte("secret")), false)
+	reader := readerClaims(entityPkg.NewId().String())
+	get := func(id string) *httptest.ResponseRecorder {
+		return productRequest(t, h.GetProduct, http.MethodGet, "/products/"+id, id, "", reader)
+	}
+
+	rec := get(entityPkg.NewId().String())
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Contains(t, rec.Body.String(), problem.CodeNotFound)
+
+	// Com o banco fora do ar a resposta não pode ser confundida com um produto inexistente
+	sqlDB, err := productDB.DB.DB()
+	assert.NoError(t, err)
+	assert.NoError(t, sqlDB.Close())
+
+	rec = get(entityPkg.NewId().String())
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(t, rec.Body.String(), problem.CodeUnavailable)
+	assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+}
+
+// productRequest executa o handler com as claims informadas no contexto e o id do path
+func productRequest(t *testing.T, handler http.HandlerFunc, method, target, id, body string, claims map[string]interface{}) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler(rec, newProductRequest(t, method, target, id, body, claims))
+	return rec
+}
+
+func newProductRequest(t *testing.T, method, target, id, body string, claims map[string]interface{}) *http.Request {
+	tokenAuth := jwtauth.New("HS256", []byte("secret"), nil)
+	token, _, err := tokenAuth.Encode(claims)
+	assert.NoError(t, err)
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id)
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	return req.WithContext(jwtauth.NewContext(ctx, token, nil))
+}
+
+func TestProductOwnership(t *testing.T) {
+	productDB := newTestProductDB(t)
+	h := NewProductHandler(productDB, 100, cursor.NewSigner([]byte("secret")), false)
+	tenantID := entityPkg.NewId().String()
+	owner := map[string]interface{}{"sub": entityPkg.NewId().String(), "tid": tenantID, "role": entity.RoleEditor}
+	other := map[string]interface{}{"sub": entityPkg.NewId().String(), "tid": tenantID, "role": entity.RoleEditor}
+	admin := map[string]interface{}{"sub": entityPkg.NewId().String(), "tid": tenantID, "role": entity.RoleAdmin}
+
+	rec := productRequest(t, h.CreateProduct, http.MethodPost, "/products", "", `{"name":"Mouse","price":10}`, owner)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	rec = productRequest(t, h.CreateProduct, http.MethodPost, "/products", "", `{"name":"Keyboard","price":20}`, other)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	rec = productRequest(t, h.GetMyProducts, http.MethodGet, "/users/me/products", "", "", owner)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var output dto.ProductListOutput
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&output))
+	assert.Equal(t, int64(1), output.Total)
+	mouse := output.Items[0]
+	assert.Equal(t, "Mouse", mouse.Name)
+	assert.Equal(t, owner["sub"], mouse.CreatedBy.String())
+	id := mouse.ID.String()
+
+	// Outro editor não pode alterar nem remover o produto
+	rec = productRequest(t, h.UpdateProduct, http.MethodPut, "/products/"+id, id, `{"name":"Stolen","price":1}`, other)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	rec = productRequest(t, h.DeleteProduct, http.MethodDelete, "/products/"+id, id, "", other)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// O dono não pode ser trocado pelo corpo da atualização
+	rec = productRequest(t, h.UpdateProduct, http.MethodPut, "/products/"+id, id, `{"name":"Mouse 2","price":12,"created_by":"`+other["sub"].(string)+`"}`, owner)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	p, err := productDB.FindByID(id)
+	assert.NoError(t, err)
+	assert.Equal(t, "Mouse 2", p.Name)
+	assert.Equal(t, owner["sub"], p.CreatedBy.String())
+
+	// Uma credencial de admin com escopo sem products:manage só altera os próprios produtos
+	scoped := map[string]interface{}{"sub": admin["sub"], "tid": tenantID, "role": entity.RoleAdmin, "scope": "products:write"}
+	rec = productRequest(t, h.UpdateProduct, http.MethodPut, "/products/"+id, id, `{"name":"Mouse 3","price":13}`, scoped)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = productRequest(t, h.UpdateProduct, http.MethodPut, "/products/"+id, id, `{"name":"Mouse 3","price":13}`, admin)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	p, err = productDB.FindByID(id)
+	assert.NoError(t, err)
+	assert.Equal(t, owner["sub"], p.CreatedBy.String())
+	assert.Equal(t, admin["sub"], p.UpdatedBy.String())
+
+	rec = productRequest(t, h.DeleteProduct, http.MethodDelete, "/products/"+id, id, "", admin)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestProductTenantIsolation(t *testing.T) {
+	productDB := newTestProductDB(t)
+	h := NewProductHandler(productDB, 100, cursor.NewSigner([]byte("secret")), false)
+	acme := entityPkg.NewId().String()
+	globex := entityPkg.NewId().String()
+	acmeAdmin := map[string]interface{}{"sub": entityPkg.NewId().String(), "tid": acme, "role": entity.RoleAdmin}
+	globexAdmin := map[string]interface{}{"sub": entityPkg.NewId().String(), "tid": globex, "role": entity.RoleAdmin}
+
+	// O tenant vem do token, mesmo que o corpo informe outro
+	rec := productRequest(t, h.CreateProduct, http.MethodPost, "/products", "", `{"name":"Mouse","price":10,"tenant_id":"`+globex+`"}`, acmeAdmin)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	products, err := productDB.ForTenant(acme).FindAll(database.ProductQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+	id := products[0].ID.String()
+
+	// Nem um administrador de outro tenant enxerga ou altera o produto
+	rec = productRequest(t, h.GetProducts, http.MethodGet, "/products", "", "", globexAdmin)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "0", rec.Header().Get("X-Total-Count"))
+	rec = productRequest(t, h.GetProducts, http.MethodGet, "/products?cursor=", "", "", globexAdmin)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"items":[],"limit":20}`, rec.Body.String())
+	rec = productRequest(t, h.GetProduct, http.MethodGet, "/products/"+id, id, "", globexAdmin)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = productRequest(t, h.UpdateProduct, http.MethodPut, "/products/"+id, id, `{"name":"Stolen","price":1}`, globexAdmin)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = productRequest(t, h.DeleteProduct, http.MethodDelete, "/products/"+id, id, "", globexAdmin)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// A atualização pelo próprio tenant não consegue mover o produto para outro
+	rec = productRequest(t, h.UpdateProduct, http.MethodPut, "/products/"+id, id, `{"name":"Mouse 2","price":12,"tenant_id":"`+globex+`"}`, acmeAdmin)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	p, err := productDB.ForTenant(acme).FindByID(id)
+	assert.NoError(t, err)
+	assert.Equal(t, "Mouse 2", p.Name)
+	assert.Equal(t, acme, p.TenantID.String())
+
+	// Tokens sem o claim de tenant são recusados
+	rec = productRequest(t, h.GetProducts, http.MethodGet, "/products", "", "", map[string]interface{}{"sub": acmeAdmin["sub"], "role": entity.RoleAdmin})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestUpdateProduct_FullReplacement(t *testing.T) {
+	productDB := newTestProductDB(t)
+	h := NewProductHandler(productDB, 100, cursor.NewSigner([]byte("secret")), false)
+	owner := readerClaims(entityPkg.NewId().String())
+	owner["role"] = entity.RoleEditor
+	rec := productRequest(t, h.CreateProduct, http.MethodPost, "/products", "", `{"name":"Mouse","price":10}`, owner)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	products, err := productDB.ForTenant(owner["tid"].(string)).FindAll(database.ProductQuery{})
+	assert.NoError(t, err)
+	created := products[0]
+	id := created.ID.String()
+
+	// O PUT substitui o produto inteiro: um campo omitido não mantém o valor anterior
+	rec = productRequest(t, h.UpdateProduct, http.MethodPut, "/products/"+id, id, `{"name":"Mouse 2"}`, owner)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"field":"price"`)
+
+	body := `{"name":"Mouse 2","price":12,"id":"` + entityPkg.NewId().String() + `","created_at":"2000-01-01T00:00:00Z"}`
+	rec = productRequest(t, h.UpdateProduct, http.MethodPut, "/products/"+id, id, body, owner)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var output entity.Product
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&output))
+	assert.Equal(t, created.ID, output.ID)
+	assert.Equal(t, "Mouse 2", output.Name)
+
+	p, err := productDB.ForTenant(owner["tid"].(string)).FindByID(id)
+	assert.NoError(t, err)
+	assert.Equal(t, 12.0, p.Price)
+	assert.True(t, created.CreatedAt.Equal(p.CreatedAt))
+	assert.Equal(t, created.CreatedBy, p.CreatedBy)
+}
+
+func TestPatchProduct(t *testing.T) {
+	productDB := newTestProductDB(t)
+	h := NewProductHandler(productDB, 100, cursor.NewSigner([]byte("secret")), false)
+	owner := readerClaims(entityPkg.NewId().String())
+	owner["role"] = entity.RoleEditor
+	rec := productRequest(t, h.CreateProduct, http.MethodPost, "/products", "", `{"name":"Mouse","price":10}`, owner)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	products, err := productDB.ForTenant(owner["tid"].(string)).FindAll(database.ProductQuery{})
+	assert.NoError(t, err)
+	created := products[0]
+	id := created.ID.String()
+	patch := func(contentType, body string) *httptest.ResponseRecorder {
+		req := newProductRequest(t, http.MethodPatch, "/products/"+id, id, body, owner)
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		h.PatchProduct(rec, req)
+		return rec
+	}
+
+	rec = patch(mergepatch.ContentType, `{"price":15,"created_at":null,"tenant_id":"`+entityPkg.NewId().String()+`"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var output entity.Product
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&output))
+	assert.Equal(t, "Mouse", output.Name)
+	assert.Equal(t, 15.0, output.Price)
+	p, err := productDB.ForTenant(owner["tid"].(string)).FindByID(id)
+	assert.NoError(t, err)
+	assert.Equal(t, "Mouse", p.Name)
+	assert.Equal(t, 15.0, p.Price)
+	assert.True(t, created.CreatedAt.Equal(p.CreatedAt))
+
+	// O produto resultante é validado como na criação
+	rec = patch(mergepatch.ContentType, `{"name":null}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"field":"name"`)
+	rec = patch(mergepatch.ContentType, `{"price":-1}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"field":"price"`)
+	rec = patch(mergepatch.ContentType, `{"price":"cheap"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), problem.CodeInvalidRequest)
+	rec = patch(mergepatch.ContentType, `{`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = patch("application/json", `{"price":20}`)
+	assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+	assert.Equal(t, mergepatch.ContentType, rec.Header().Get("Accept-Patch"))
+
+	other := readerClaims(owner["tid"].(string))
+	other["role"] = entity.RoleEditor
+	req := newProductRequest(t, http.MethodPatch, "/products/"+id, id, `{"price":1}`, other)
+	req.Header.Set("Content-Type", mergepatch.ContentType)
+	rec = httptest.NewRecorder()
+	h.PatchProduct(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestProductETag(t *testing.T) {
+	productDB := newTestProductDB(t)
+	h := NewProductHandler(productDB, 100, cursor.NewSigner([]byte("secret")), false)
+	owner := readerClaims(entityPkg.NewId().String())
+	owner["role"] = entity.RoleEditor
+	rec := productRequest(t, h.CreateProduct, http.MethodPost, "/products", "", `{"name":"Mouse","price":10}`, owner)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	products, err := productDB.ForTenant(owner["tid"].(string)).FindAll(database.ProductQuery{})
+	assert.NoError(t, err)
+	id := products[0].ID.String()
+	send := func(handler http.HandlerFunc, method, body string, headers map[string]string) *httptest.ResponseRecorder {
+		req := newProductRequest(t, method, "/products/"+id, id, body, owner)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	rec = send(h.GetProduct, http.MethodGet, "", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	etag := rec.Header().Get("ETag")
+	assert.Equal(t, `"1"`, etag)
+	for _, ifNoneMatch := range []string{etag, "W/" + etag, `"9", ` + etag, "*"} {
+		rec = send(h.GetProduct, http.MethodGet, "", map[string]string{"If-None-Match": ifNoneMatch})
+		assert.Equal(t, http.StatusNotModified, rec.Code, ifNoneMatch)
+		assert.Empty(t, rec.Body.String())
+		assert.Equal(t, etag, rec.Header().Get("ETag"))
+	}
+	rec = send(h.GetProduct, http.MethodGet, "", map[string]string{"If-None-Match": `"9"`})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Quem alterou a partir da versão atual recebe o novo ETag; quem ficou com a antiga recebe 412
+	rec = send(h.UpdateProduct, http.MethodPut, `{"name":"Mouse 2","price":12}`, map[string]string{"If-Match": etag})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
+	assert.Contains(t, rec.Body.String(), `"version":2`)
+	rec = send(h.UpdateProduct, http.MethodPut, `{"name":"Mouse 3","price":13}`, map[string]string{"If-Match": etag})
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	assert.Contains(t, rec.Body.String(), problem.CodePrecondition)
+	rec = send(h.PatchProduct, http.MethodPatch, `{"price":14}`, map[string]string{"If-Match": etag, "Content-Type": mergepatch.ContentType})
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	// A comparação do If-Match é forte
+	rec = send(h.PatchProduct, http.MethodPatch, `{"price":14}`, map[string]string{"If-Match": `W/"2"`, "Content-Type": mergepatch.ContentType})
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	rec = send(h.DeleteProduct, http.MethodDelete, "", map[string]string{"If-Match": etag})
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+
+	// A versão enviada no corpo é ignorada
+	rec = send(h.PatchProduct, http.MethodPatch, `{"price":14,"version":1}`, map[string]string{"If-Match": `"2"`, "Content-Type": mergepatch.ContentType})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"3"`, rec.Header().Get("ETag"))
+
+	h.RequireIfMatch = true
+	rec = send(h.UpdateProduct, http.MethodPut, `{"name":"Mouse 4","price":15}`, nil)
+	assert.Equal(t, http.StatusPreconditionRequired, rec.Code)
+	rec = send(h.DeleteProduct, http.MethodDelete, "", nil)
+	assert.Equal(t, http.StatusPreconditionRequired, rec.Code)
+	rec = send(h.DeleteProduct, http.MethodDelete, "", map[string]string{"If-Match": `"3"`})
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+// racingProductDB grava uma alteração concorrente logo depois que o handler lê o produto
+type racingProductDB struct {
+	database.ProductDBInterface
+	race func(p *entity.Product)
+}
+
+func (db *racingProductDB) ForTenant(tenantID string) database.ProductDBInterface {
+	return &racingProductDB{ProductDBInterface: db.ProductDBInterface.ForTenant(tenantID), race: db.race}
+}
+
+func (db *racingProductDB) FindByID(id string) (*entity.Product, error) {
+	p, err := db.ProductDBInterface.FindByID(id)
+	if err == nil && db.race != nil {
+		concurrent := *p
+		db.race(&concurrent)
+	}
+	return p, err
+}
+
+func TestDeleteProduct_ConcurrentUpdate(t *testing.T) {
+	productDB := newTestProductDB(t)
+	admin := readerClaims(entityPkg.NewId().String())
+	admin["role"] = entity.RoleAdmin
+	tenantDB := productDB.ForTenant(admin["tid"].(string))
+	product, err := entity.NewProduct("Mouse", 10)
+	assert.NoError(t, err)
+	assert.NoError(t, tenantDB.CreateProduct(product))
+	id := product.ID.String()
+
+	racing := &racingProductDB{ProductDBInterface: productDB, race: func(p *entity.Product) {
+		p.Name = "Concurrent"
+		assert.NoError(t, tenantDB.Update(p))
+	}}
+	h := NewProductHandler(racing, 100, cursor.NewSigner([]byte("secret")), false)
+	req := newProductRequest(t, http.MethodDelete, "/products/"+id, id, "", admin)
+	req.Header.Set("If-Match", `"1"`)
+	rec := httptest.NewRecorder()
+	h.DeleteProduct(rec, req)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+
+	// A alteração concorrente continua visível e o produto não foi para a lixeira
+	p, err := tenantDB.FindByID(id)
+	assert.NoError(t, err)
+	assert.Equal(t, "Concurrent", p.Name)
+	assert.Equal(t, int64(2), p.Version)
+}
+
+func TestProductTrash(t *testing.T) {
+	productDB := newTestProductDB(t)
+	h := NewProductHandler(productDB, 100, cursor.NewSigner([]byte("secret")), false)
+	tenantID := entityPkg.NewId().String()
+	owner := map[string]interface{}{"sub": entityPkg.NewId().String(), "tid": tenantID, "role": entity.RoleEditor}
+	other := map[string]interface{}{"sub": entityPkg.NewId().String(), "tid": tenantID, "role": entity.RoleEditor}
+	admin := map[string]interface{}{"sub": entityPkg.NewId().String(), "tid": tenantID, "role": entity.RoleAdmin}
+
+	rec := productRequest(t, h.CreateProduct, http.MethodPost, "/products", "", `{"name":"Mouse","price":10}`, owner)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	products, err := productDB.ForTenant(tenantID).FindAll(database.ProductQuery{})
+	assert.NoError(t, err)
+	id := products[0].ID.String()
+
+	rec = productRequest(t, h.DeleteProduct, http.MethodDelete, "/products/"+id, id, "", owner)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = productRequest(t, h.GetProduct, http.MethodGet, "/products/"+id, id, "", owner)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = productRequest(t, h.GetProducts, http.MethodGet, "/products", "", "", owner)
+	assert.Equal(t, "0", rec.Header().Get("X-Total-Count"))
+
+	// A lixeira mostra ao editor apenas os próprios produtos, e ao admin todos os do tenant
+	rec = productRequest(t, h.GetTrash, http.MethodGet, "/products/trash", "", "", owner)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("X-Total-Count"))
+	rec = productRequest(t, h.GetTrash, http.MethodGet, "/products/trash", "", "", other)
+	assert.Equal(t, "0", rec.Header().Get("X-Total-Count"))
+	rec = productRequest(t, h.GetTrash, http.MethodGet, "/products/trash", "", "", admin)
+	assert.Equal(t, "1", rec.Header().Get("X-Total-Count"))
+
+	// Só o dono ou um admin restauram o produto
+	rec = productRequest(t, h.RestoreProduct, http.MethodPost, "/products/"+id+"/restore", id, "", other)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	rec = productRequest(t, h.RestoreProduct, http.MethodPost, "/products/"+id+"/restore", id, "", owner)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var restored entity.Product
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&restored))
+	assert.Nil(t, restored.DeletedAt)
+	assert.Equal(t, productETag(&restored), rec.Header().Get("ETag"))
+	rec = productRequest(t, h.RestoreProduct, http.MethodPost, "/products/"+id+"/restore", id, "", owner)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// Produtos fora da lixeira não são removidos definitivamente
+	rec = productRequest(t, h.PurgeProduct, http.MethodDelete, "/products/trash/"+id, id, "", admin)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = productRequest(t, h.DeleteProduct, http.MethodDelete, "/products/"+id, id, "", owner)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = productRequest(t, h.PurgeProduct, http.MethodDelete, "/products/trash/"+id, id, "", admin)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	rec = productRequest(t, h.RestoreProduct, http.MethodPost, "/products/"+id+"/restore", id, "", owner)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// Ids que não são UUIDs são recusados antes da consulta
+	for _, handler := range []http.HandlerFunc{h.DeleteProduct, h.RestoreProduct, h.PurgeProduct, h.UpdateProduct} {
+		rec = productRequest(t, handler, http.MethodPost, "/products/not-a-uuid", "not-a-uuid", "", admin)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	}
+}
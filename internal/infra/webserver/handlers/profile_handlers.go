@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/gsouza97/go-expert-api/internal/dto"
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	"github.com/gsouza97/go-expert-api/internal/infra/database"
+	"github.com/gsouza97/go-expert-api/internal/infra/webserver/problem"
+)
+
+// Get current user godoc
+// @Summary      Get current user
+// @Description  Get the profile of the user identified by the access token
+// @Tags         users
+// @Produce      json
+// @Success      200  {object}  entity.User
+// @Failure      401  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
+// @Router       /users/me [get]
+// @Security	 ApiKeyAuth
+func (h *UserHandler) GetMe(w http.ResponseWriter, r *http.Request) {
+	u, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(u)
+}
+
+// Update current user godoc
+// @Summary      Update current user
+// @Description  Change the name and/or the email of the current user. Changing the email requires the current password and a new verification.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request    body     dto.UpdateUserInput  true  "fields to change"
+// @Success      200  {object}  entity.User
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      409  {object}  problem.Problem
+// @Failure      429  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
+// @Router       /users/me [patch]
+// @Security	 ApiKeyAuth
+func (h *UserHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	var input dto.UpdateUserInput
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		invalidBody(w, r)
+		return
+	}
+	if input.Name != nil && !requireFields(w, r, map[string]string{"name": *input.Name}) {
+		return
+	}
+	u, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	if input.Name != nil {
+		u.Name = *input.Name
+	}
+	emailChanged := false
+	if input.Email != nil && entity.NormalizeEmail(*input.Email) != u.Email {
+		if !requireFields(w, r, map[string]string{"current_password": input.CurrentPassword}) {
+			return
+		}
+		if !h.checkCurrentPassword(w, r, u, input.CurrentPassword) {
+			return
+		}
+		if err := u.ChangeEmail(*input.Email); err != nil {
+			writeValidationError(w, r, err)
+			return
+		}
+		emailChanged = true
+	}
+
+	err = h.UserDB.Update(u)
+	if errors.Is(err, database.ErrConflict) {
+		problem.Conflict(w, r, "email is already registered")
+		return
+	}
+	if err != nil {
+		writeRepositoryError(w, r, err, "user not found")
+		return
+	}
+	if emailChanged {
+		if err := h.Verifier.Send(u); err != nil {
+			log.Printf("error sending verification email to user %s: %v", u.ID, err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(u)
+}
+
+// Change password godoc
+// @Summary      Change password
+// @Description  Change the password of the current user. The other sessions are revoked and a new token pair is returned for the current one.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request    body     dto.ChangePasswordInput  true  "current and new password"
+// @Success      200  {object}  dto.GetJWTOutput
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      429  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
+// @Router       /users/me/password [put]
+// @Security	 ApiKeyAuth
+func (h *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	var input dto.ChangePasswordInput
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		invalidBody(w, r)
+		return
+	}
+	if !requireFields(w, r, map[string]string{"current_password": input.CurrentPassword, "new_password": input.NewPassword}) {
+		return
+	}
+	u, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	if !h.checkCurrentPassword(w, r, u, input.CurrentPassword) {
+		return
+	}
+
+	if err := u.SetPassword(input.NewPassword); err != nil {
+		// Os erros da política se referem ao campo password, que aqui se chama new_password
+		if fieldErr, ok := domainFieldErrors[err]; ok && fieldErr.Field == "password" {
+			fieldErr.Field = "new_password"
+			problem.Validation(w, r, fieldErr)
+			return
+		}
+		writeValidationError(w, r, err)
+		return
+	}
+	if err := h.UserDB.Update(u); err != nil {
+		writeRepositoryError(w, r, err, "user not found")
+		return
+	}
+
+	// Os links de redefinição pendentes e os tokens emitidos com a senha antiga deixam de valer
+	if err := h.Resetter.ResetTokenDB.InvalidateByUser(u.ID.String()); err != nil {
+		writeRepositoryError(w, r, err, "user not found")
+		return
+	}
+	if err := h.Tokens.RefreshTokenDB.RevokeAllByUser(u.ID.String()); err != nil {
+		writeRepositoryError(w, r, err, "user not found")
+		return
+	}
+	if err := h.UserDB.RevokeSessions(u.ID.String()); err != nil {
+		writeRepositoryError(w, r, err, "user not found")
+		return
+	}
+
+	// A sessão atual continua com um novo par de tokens, emitido com a nova versão da sessão
+	u, err = h.UserDB.FindByID(u.ID.String())
+	if err != nil {
+		writeRepositoryError(w, r, err, "user not found")
+		return
+	}
+	tokens, err := h.Tokens.Issue(u)
+	if err != nil {
+		writeRepositoryError(w, r, err, "user not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// Delete current user godoc
+// @Summary      Delete current user
+// @Description  Delete the account of the current user and all of its sessions. Requires the current password.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request    body     dto.DeleteUserInput  true  "current password"
+// @Success      204
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      429  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
+// @Router       /users/me [delete]
+// @Security	 ApiKeyAuth
+func (h *UserHandler) DeleteMe(w http.ResponseWriter, r *http.Request) {
+	var input dto.DeleteUserInput
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		invalidBody(w, r)
+		return
+	}
+	if !requireFields(w, r, map[string]string{"password": input.Password}) {
+		return
+	}
+	u, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	if !h.checkCurrentPassword(w, r, u, input.Password) {
+		return
+	}
+
+	if err := h.UserDB.Delete(u.ID.String()); err != nil {
+		writeRepositoryError(w, r, err, "user not found")
+		return
+	}
+	// O access token atual é revogado; os outros são recusados com o usuário removido
+	token, _, err := jwtauth.FromContext(r.Context())
+	if err == nil && token != nil && token.JwtID() != "" {
+		if err := h.RevokedTokenDB.Revoke(token.JwtID(), token.Expiration()); err != nil {
+			log.Printf("error revoking access token of deleted user %s: %v", u.ID, err)
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkCurrentPassword confirma a senha; as falhas contam para o bloqueio do login
+func (h *UserHandler) checkCurrentPassword(w http.ResponseWriter, r *http.Request, u *entity.User, plain string) bool {
+	ip := clientIP(r)
+	if err := h.Throttle.Check(u.Email, ip); err != nil {
+		writeThrottleError(w, r, err)
+		return false
+	}
+	if !u.ValidatePassword(plain) {
+		h.registerLoginFailure(u.Email, ip, &u.TenantID)
+		problem.Write(w, r, http.StatusForbidden, problem.CodeInvalidLogin, "current password is incorrect")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gsouza97/go-expert-api/internal/dto"
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	"github.com/gsouza97/go-expert-api/internal/infra/webserver/problem"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestSession cadastra um usuário verificado e retorna os tokens do login
+func newTestSession(t *testing.T, h *UserHandler) dto.GetJWTOutput {
+	h.Verifier.Required = false
+	rec := postJSON(h.CreateUser, "/users", `{"name":"John","email":"johndoe@test.com","password":"12345678"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	rec = postJSON(h.GetJWT, "/users/getToken", `{"email":"johndoe@test.com","password":"12345678"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var session dto.GetJWTOutput
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&session))
+	return session
+}
+
+func TestUpdateMe(t *testing.T) {
+	h, mailer := newTestUserHandler(t)
+	session := newTestSession(t, h)
+	sent := len(mailer.Messages())
+
+	rec := authenticatedPost(t, h, h.GetMe, http.MethodGet, "", session.AccessToken)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var me entity.User
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&me))
+	assert.Equal(t, "johndoe@test.com", me.Email)
+	assert.NotContains(t, rec.Body.String(), "password")
+
+	rec = authenticatedPost(t, h, h.UpdateMe, http.MethodPatch, `{"name":"Johnny"}`, session.AccessToken)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"name":"Johnny"`)
+	rec = authenticatedPost(t, h, h.UpdateMe, http.MethodPatch, `{"name":""}`, session.AccessToken)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// Trocar o email exige a senha atual
+	rec = authenticatedPost(t, h, h.UpdateMe, http.MethodPatch, `{"email":"john@example.com"}`, session.AccessToken)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"field":"current_password"`)
+	rec = authenticatedPost(t, h, h.UpdateMe, http.MethodPatch, `{"email":"john@example.com","current_password":"wrong-password"}`, session.AccessToken)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	rec = authenticatedPost(t, h, h.UpdateMe, http.MethodPatch, `{"email":"John@Example.com","current_password":"12345678"}`, session.AccessToken)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	u, err := h.UserDB.FindByEmail("john@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "Johnny", u.Name)
+	assert.False(t, u.IsEmailVerified())
+	assert.Len(t, mailer.Messages(), sent+1)
+	assert.Equal(t, "john@example.com", mailer.Messages()[sent].To)
+
+	rec = postJSON(h.CreateUser, "/users", `{"name":"Jane","email":"janedoe@test.com","password":"12345678"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	rec = authenticatedPost(t, h, h.UpdateMe, http.MethodPatch, `{"email":"janedoe@test.com","current_password":"12345678"}`, session.AccessToken)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+}
+
+func TestChangePassword(t *testing.T) {
+	h, _ := newTestUserHandler(t)
+	session := newTestSession(t, h)
+
+	rec := authenticatedPost(t, h, h.ChangePassword, http.MethodPut, `{"current_password":"wrong-password","new_password":"abcdefgh"}`, session.AccessToken)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), problem.CodeInvalidLogin)
+	rec = authenticatedPost(t, h, h.ChangePassword, http.MethodPut, `{"current_password":"12345678","new_password":"abc"}`, session.AccessToken)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"field":"new_password"`)
+	rec = authenticatedPost(t, h, h.ChangePassword, http.MethodPut, `{"current_password":"12345678","new_password":"abcdefgh"}`, session.AccessToken)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var renewed dto.GetJWTOutput
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&renewed))
+
+	// As sessões abertas com a senha antiga foram encerradas e a atual continua com os novos tokens
+	rec = postJSON(h.RefreshJWT, "/users/refresh", `{"refresh_token":"`+session.RefreshToken+`"}`)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, http.StatusUnauthorized, getMe(t, h, session.AccessToken).Code)
+	assert.Equal(t, http.StatusOK, getMe(t, h, renewed.AccessToken).Code)
+	rec = postJSON(h.RefreshJWT, "/users/refresh", `{"refresh_token":"`+renewed.RefreshToken+`"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = postJSON(h.GetJWT, "/users/getToken", `{"email":"johndoe@test.com","password":"12345678"}`)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	rec = postJSON(h.GetJWT, "/users/getToken", `{"email":"johndoe@test.com","password":"abcdefgh"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestChangePassword_Throttled(t *testing.T) {
+	h, _ := newTestUserHandler(t)
+	session := newTestSession(t, h)
+
+	for i := 0; i < 3; i++ {
+		rec := authenticatedPost(t, h, h.ChangePassword, http.MethodPut, `{"current_password":"wrong-password","new_password":"abcdefgh"}`, session.AccessToken)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	}
+	rec := authenticatedPost(t, h, h.ChangePassword, http.MethodPut, `{"current_password":"12345678","new_password":"abcdefgh"}`, session.AccessToken)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+}
+
+func TestDeleteMe(t *testing.T) {
+	h, _ := newTestUserHandler(t)
+	session := newTestSession(t, h)
+
+	rec := authenticatedPost(t, h, h.DeleteMe, http.MethodDelete, `{}`, session.AccessToken)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = authenticatedPost(t, h, h.DeleteMe, http.MethodDelete, `{"password":"wrong-password"}`, session.AccessToken)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	rec = authenticatedPost(t, h, h.DeleteMe, http.MethodDelete, `{"password":"12345678"}`, session.AccessToken)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = authenticatedPost(t, h, h.GetMe, http.MethodGet, "", session.AccessToken)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	rec = postJSON(h.RefreshJWT, "/users/refresh", `{"refresh_token":"`+session.RefreshToken+`"}`)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	rec = postJSON(h.GetJWT, "/users/getToken", `{"email":"johndoe@test.com","password":"12345678"}`)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	token, err := h.Tokens.Jwt.Decode(session.AccessToken)
+	assert.NoError(t, err)
+	revoked, err := h.RevokedTokenDB.IsRevoked(token.JwtID())
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+}
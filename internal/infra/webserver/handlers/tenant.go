@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	"github.com/gsouza97/go-expert-api/internal/infra/database"
+	"github.com/gsouza97/go-expert-api/internal/infra/webserver/problem"
+)
+
+// TenantResolver encontra o tenant padrão, onde entram os usuários do cadastro público
+type TenantResolver struct {
+	TenantDB    database.TenantDBInterface
+	DefaultSlug string
+}
+
+func NewTenantResolver(tenantDB database.TenantDBInterface, defaultSlug string) *TenantResolver {
+	return &TenantResolver{
+		TenantDB:    tenantDB,
+		DefaultSlug: defaultSlug,
+	}
+}
+
+func (t *TenantResolver) Default() (*entity.Tenant, error) {
+	return t.TenantDB.FindBySlug(t.DefaultSlug)
+}
+
+// currentTenant retorna o claim tid. Tokens anteriores aos tenants, sem o claim, são recusados.
+func currentTenant(w http.ResponseWriter, r *http.Request) (string, bool) {
+	_, claims, err := jwtauth.FromContext(r.Context())
+	tenantID, _ := claims["tid"].(string)
+	if err != nil || tenantID == "" {
+		problem.Write(w, r, http.StatusUnauthorized, problem.CodeInvalidToken, "access token is invalid")
+		return "", false
+	}
+	return tenantID, true
+}
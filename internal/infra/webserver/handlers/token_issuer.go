@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gsouza97/go-expert-api/internal/dto"
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	"github.com/gsouza97/go-expert-api/internal/infra/database"
+	entityPkg "github.com/gsouza97/go-expert-api/pkg/entity"
+	"github.com/gsouza97/go-expert-api/pkg/jwtkeys"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("refresh token is invalid")
+	ErrRefreshTokenReused  = errors.New("refresh token was already used")
+)
+
+// TokenIssuer gera os access tokens (JWT de curta duração) e os refresh tokens rotativos
+type TokenIssuer struct {
+	Jwt              *jwtkeys.KeySet
+	RefreshTokenDB   database.RefreshTokenDBInterface
+	AccessExpiresIn  time.Duration
+	RefreshExpiresIn time.Duration
+}
+
+func NewTokenIssuer(jwt *jwtkeys.KeySet, refreshTokenDB database.RefreshTokenDBInterface, accessExpiresIn, refreshExpiresIn time.Duration) *TokenIssuer {
+	return &TokenIssuer{
+		Jwt:              jwt,
+		RefreshTokenDB:   refreshTokenDB,
+		AccessExpiresIn:  accessExpiresIn,
+		RefreshExpiresIn: refreshExpiresIn,
+	}
+}
+
+// Issue gera um par de tokens iniciando uma nova família de refresh tokens
+func (i *TokenIssuer) Issue(user *entity.User) (*dto.GetJWTOutput, error) {
+	return i.issue(user, entityPkg.NewId())
+}
+
+// Rotate troca o refresh token por um novo par. Reusar um token revoga toda a família.
+func (i *TokenIssuer) Rotate(refreshToken string, findUser func(id string) (*entity.User, error)) (*dto.GetJWTOutput, error) {
+	token, err := i.findRefreshToken(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if token.IsRevoked() {
+		if err := i.RefreshTokenDB.RevokeFamily(token.FamilyID.String()); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+	if token.IsExpired() {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	revoked, err := i.RefreshTokenDB.Revoke(token.ID.String())
+	if err != nil {
+		return nil, err
+	}
+	if !revoked {
+		// Outro request usou o mesmo token entre a busca e a revogação
+		if err := i.RefreshTokenDB.RevokeFamily(token.FamilyID.String()); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	user, err := findUser(token.UserID.String())
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return i.issue(user, token.FamilyID)
+}
+
+// Revoke revoga a família inteira do refresh token informado
+func (i *TokenIssuer) Revoke(refreshToken string) error {
+	token, err := i.findRefreshToken(refreshToken)
+	if err != nil {
+		return err
+	}
+	return i.RefreshTokenDB.RevokeFamily(token.FamilyID.String())
+}
+
+// findRefreshToken repassa as falhas do banco
+func (i *TokenIssuer) findRefreshToken(refreshToken string) (*entity.RefreshToken, error) {
+	token, err := i.RefreshTokenDB.FindByHash(entity.HashToken(refreshToken))
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	return token, err
+}
+
+// IssueAccessToken gera só o access token. As claims extras não sobrescrevem as padrão.
+func (i *TokenIssuer) IssueAccessToken(user *entity.User, extra map[string]interface{}) (string, error) {
+	now := time.Now()
+	claims := map[string]interface{}{}
+	for k, v := range extra {
+		claims[k] = v
+	}
+	claims["sub"] = user.ID.String()
+	claims["tid"] = user.TenantID.String()
+	claims["role"] = user.Role
+	claims["sv"] = user.SessionVersion
+	claims["jti"] = entityPkg.NewId().String()
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(i.AccessExpiresIn).Unix()
+	_, accessToken, err := i.Jwt.Encode(claims)
+	return accessToken, err
+}
+
+func (i *TokenIssuer) issue(user *entity.User, familyID entityPkg.ID) (*dto.GetJWTOutput, error) {
+	accessToken, err := i.IssueAccessToken(user, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, plain, err := entity.NewRefreshToken(user.ID, familyID, i.RefreshExpiresIn)
+	if err != nil {
+		return nil, err
+	}
+	if err := i.RefreshTokenDB.Create(refreshToken); err != nil {
+		return nil, err
+	}
+
+	return &dto.GetJWTOutput{
+		AccessToken:  accessToken,
+		RefreshToken: plain,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(i.AccessExpiresIn.Seconds()),
+	}, nil
+}
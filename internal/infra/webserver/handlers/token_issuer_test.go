@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	"github.com/gsouza97/go-expert-api/internal/infra/database"
+	"github.com/gsouza97/go-expert-api/pkg/jwtkeys"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func newTestTokenIssuer(t *testing.T) (*TokenIssuer, *entity.User) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.AutoMigrate(&entity.RefreshToken{})
+	user, err := entity.NewUser("John", "johndoe@test.com", "12345678")
+	assert.NoError(t, err)
+	tokenAuth, err := jwtkeys.NewKeySet(jwtkeys.NewHMACKey([]byte("secret")))
+	assert.NoError(t, err)
+	return NewTokenIssuer(tokenAuth, database.NewRefreshTokenDB(db), time.Minute, time.Hour), user
+}
+
+func TestTokenIssuer_Issue(t *testing.T) {
+	issuer, user := newTestTokenIssuer(t)
+
+	tokens, err := issuer.Issue(user)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, tokens.AccessToken)
+	assert.NotEmpty(t, tokens.RefreshToken)
+	assert.Equal(t, "Bearer", tokens.TokenType)
+	assert.Equal(t, int64(60), tokens.ExpiresIn)
+
+	token, err := issuer.Jwt.Decode(tokens.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID.String(), token.Subject())
+	assert.NotEmpty(t, token.JwtID())
+	role, _ := token.Get("role")
+	assert.Equal(t, entity.RoleViewer, role)
+}
+
+func TestTokenIssuer_Rotate(t *testing.T) {
+	issuer, user := newTestTokenIssuer(t)
+	findUser := func(id string) (*entity.User, error) { return user, nil }
+
+	first, err := issuer.Issue(user)
+	assert.NoError(t, err)
+
+	second, err := issuer.Rotate(first.RefreshToken, findUser)
+	assert.NoError(t, err)
+	assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+
+	third, err := issuer.Rotate(second.RefreshToken, findUser)
+	assert.NoError(t, err)
+
+	_, err = issuer.Rotate("unknown", findUser)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	// Reusar um refresh token já rotacionado revoga a família inteira
+	_, err = issuer.Rotate(first.RefreshToken, findUser)
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+	_, err = issuer.Rotate(third.RefreshToken, findUser)
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+}
+
+func TestTokenIssuer_Revoke(t *testing.T) {
+	issuer, user := newTestTokenIssuer(t)
+	findUser := func(id string) (*entity.User, error) { return user, nil }
+
+	tokens, err := issuer.Issue(user)
+	assert.NoError(t, err)
+	other, err := issuer.Issue(user)
+	assert.NoError(t, err)
+
+	assert.NoError(t, issuer.Revoke(tokens.RefreshToken))
+	_, err = issuer.Rotate(tokens.RefreshToken, findUser)
+	assert.Error(t, err)
+
+	// Outras sessões do usuário continuam válidas
+	_, err = issuer.Rotate(other.RefreshToken, findUser)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	"github.com/gsouza97/go-expert-api/internal/infra/database"
+	"github.com/gsouza97/go-expert-api/pkg/cursor"
+	"github.com/gsouza97/go-expert-api/pkg/totp"
+)
+
+const mfaChallengePurpose = "mfa_challenge"
+
+var (
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnrolled    = errors.New("two-factor authentication enrollment was not started")
+	ErrMFANotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrInvalidMFACode    = errors.New("verification code is invalid")
+	ErrInvalidMFAToken   = errors.New("mfa token is invalid or expired")
+)
+
+// mfaChallenge prova que a senha foi validada e é trocado pelos tokens junto com um código
+type mfaChallenge struct {
+	Purpose   string `json:"pur"`
+	UserID    string `json:"sub"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// TOTPManager cuida da ativação do TOTP, dos códigos de recuperação e do segundo passo do login
+type TOTPManager struct {
+	UserDB             database.UserDBInterface
+	RecoveryCodeDB     database.RecoveryCodeDBInterface
+	Signer             *cursor.Signer
+	Issuer             string // Nome exibido no app autenticador
+	ChallengeExpiresIn time.Duration
+}
+
+func NewTOTPManager(userDB database.UserDBInterface, recoveryCodeDB database.RecoveryCodeDBInterface, signer *cursor.Signer, issuer string, challengeExpiresIn time.Duration) *TOTPManager {
+	return &TOTPManager{
+		UserDB:             userDB,
+		RecoveryCodeDB:     recoveryCodeDB,
+		Signer:             signer,
+		Issuer:             issuer,
+		ChallengeExpiresIn: challengeExpiresIn,
+	}
+}
+
+// Enroll gera um novo segredo pendente. Ele só passa a ser exigido depois do Confirm.
+func (m *TOTPManager) Enroll(user *entity.User) (secret, uri string, err error) {
+	if user.IsMFAEnabled() {
+		return "", "", ErrMFAAlreadyEnabled
+	}
+	secret, err = totp.GenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+	user.TOTPSecret = secret
+	if err := m.UserDB.Update(user); err != nil {
+		return "", "", err
+	}
+	return secret, totp.ProvisioningURI(m.Issuer, user.Email, secret), nil
+}
+
+// Confirm ativa o TOTP e retorna os códigos de recuperação, que não podem ser consultados depois
+func (m *TOTPManager) Confirm(user *entity.User, code string) ([]string, error) {
+	if user.IsMFAEnabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrMFANotEnrolled
+	}
+	if err := m.useTOTPCode(user, code); err != nil {
+		return nil, err
+	}
+
+	codes, plain, err := entity.NewRecoveryCodes(user.ID, entity.RecoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.RecoveryCodeDB.Replace(user.ID.String(), codes); err != nil {
+		return nil, err
+	}
+	user.TOTPEnabled = true
+	if err := m.UserDB.Update(user); err != nil {
+		return nil, err
+	}
+	return plain, nil
+}
+
+// Disable desativa o TOTP mediante um código válido ou um código de recuperação
+func (m *TOTPManager) Disable(user *entity.User, code string) error {
+	if !user.IsMFAEnabled() {
+		return ErrMFANotEnabled
+	}
+	if err := m.useCode(user, code); err != nil {
+		return err
+	}
+	user.TOTPEnabled = false
+	user.TOTPSecret = ""
+	if err := m.UserDB.Update(user); err != nil {
+		return err
+	}
+	return m.RecoveryCodeDB.DeleteByUser(user.ID.String())
+}
+
+// Challenge gera o token do segundo passo do login
+func (m *TOTPManager) Challenge(user *entity.User) (string, error) {
+	return m.Signer.Encode(mfaChallenge{
+		Purpose:   mfaChallengePurpose,
+		UserID:    user.ID.String(),
+		ExpiresAt: time.Now().Add(m.ChallengeExpiresIn).Unix(),
+	})
+}
+
+// ChallengeUser valida o token do GetJWT e retorna o usuário que passou pela senha
+func (m *TOTPManager) ChallengeUser(token string) (*entity.User, error) {
+	var challenge mfaChallenge
+	if err := m.Signer.Decode(token, &challenge); err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+	if challenge.Purpose != mfaChallengePurpose || time.Now().Unix() > challenge.ExpiresAt {
+		return nil, ErrInvalidMFAToken
+	}
+	user, err := m.UserDB.FindByID(challenge.UserID)
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, ErrInvalidMFAToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsMFAEnabled() || user.IsDisabled() {
+		return nil, ErrInvalidMFAToken
+	}
+	return user, nil
+}
+
+// VerifyCode consome um código TOTP ou de recuperação do usuário
+func (m *TOTPManager) VerifyCode(user *entity.User, code string) error {
+	return m.useCode(user, code)
+}
+
+// useCode aceita um código TOTP de 6 dígitos ou, caso contrário, um código de recuperação
+func (m *TOTPManager) useCode(user *entity.User, code string) error {
+	if len(code) == totp.Digits {
+		return m.useTOTPCode(user, code)
+	}
+	used, err := m.RecoveryCodeDB.Use(user.ID.String(), entity.HashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+func (m *TOTPManager) useTOTPCode(user *entity.User, code string) error {
+	step, ok := totp.Validate(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return ErrInvalidMFACode
+	}
+	// Um código interceptado não pode ser reutilizado dentro do mesmo período
+	used, err := m.UserDB.UseTOTPStep(user.ID.String(), step)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidMFACode
+	}
+	user.TOTPLastStep = step
+	return nil
+}
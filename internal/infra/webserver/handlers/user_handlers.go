@@ -2,100 +2,450 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
-	"time"
 
 	"github.com/go-chi/jwtauth"
 	"github.com/gsouza97/go-expert-api/internal/dto"
 	"github.com/gsouza97/go-expert-api/internal/entity"
 	"github.com/gsouza97/go-expert-api/internal/infra/database"
+	"github.com/gsouza97/go-expert-api/internal/infra/webserver/problem"
+	entityPkg "github.com/gsouza97/go-expert-api/pkg/entity"
 )
 
 type UserHandler struct {
-	UserDB       database.UserDBInterface
-	Jwt          *jwtauth.JWTAuth // Esse JWT já faz parte do middleware do próprio chi
-	JwtExpiresIn int              // Tempo de expiração do token
+	UserDB         database.UserDBInterface
+	RevokedTokenDB database.RevokedTokenDBInterface
+	Tokens         *TokenIssuer
+	Verifier       *EmailVerifier
+	Resetter       *PasswordResetter
+	MFA            *TOTPManager
+	Throttle       *LoginThrottler
+	Tenants        *TenantResolver
 }
 
-type Error struct {
-	Message string `json:"message"`
-}
-
-func NewUserHandler(userDB database.UserDBInterface, jwt *jwtauth.JWTAuth, JWTExpiresIn int) *UserHandler {
+func NewUserHandler(userDB database.UserDBInterface, revokedTokenDB database.RevokedTokenDBInterface, tokens *TokenIssuer, verifier *EmailVerifier, resetter *PasswordResetter, mfa *TOTPManager, throttle *LoginThrottler, tenants *TenantResolver) *UserHandler {
 	return &UserHandler{
-		UserDB:       userDB,
-		Jwt:          jwt,
-		JwtExpiresIn: JWTExpiresIn,
+		UserDB:         userDB,
+		RevokedTokenDB: revokedTokenDB,
+		Tokens:         tokens,
+		Verifier:       verifier,
+		Resetter:       resetter,
+		MFA:            mfa,
+		Throttle:       throttle,
+		Tenants:        tenants,
 	}
 }
 
 // Get user JWT godoc
 // @Summary      Get user JWT
-// @Description  Get user JWT
+// @Description  Get user JWT. When two-factor authentication is enabled the response is 202 with an MFA token that must be sent to /users/getToken/mfa.
 // @Tags         users
 // @Accept       json
 // @Produce      json
 // @Param        request    body     dto.GetJWTInput  true  "user credentials"
 // @Success      200  {object}  dto.GetJWTOutput
-// @Failure      401  {object}  Error
-// @Failure      500  {object}  Error
+// @Success      202  {object}  dto.MFAChallengeOutput
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      429  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
 // @Router       /users/getToken [post]
 func (h *UserHandler) GetJWT(w http.ResponseWriter, r *http.Request) {
 	var user dto.GetJWTInput
 	err := json.NewDecoder(r.Body).Decode(&user)
-	if err != nil || user.Email == "" || user.Password == "" {
-		w.WriteHeader(http.StatusBadRequest)
+	if err != nil {
+		invalidBody(w, r)
+		return
+	}
+	if !requireFields(w, r, map[string]string{"email": user.Email, "password": user.Password}) {
+		return
+	}
+
+	// Emails inexistentes também contam, para não revelar quais contas existem
+	ip := clientIP(r)
+	if err := h.Throttle.Check(user.Email, ip); err != nil {
+		writeThrottleError(w, r, err)
 		return
 	}
 
 	u, err := h.UserDB.FindByEmail(user.Email)
+	if errors.Is(err, database.ErrNotFound) {
+		h.registerLoginFailure(user.Email, ip, nil)
+		problem.Write(w, r, http.StatusUnauthorized, problem.CodeInvalidLogin, "invalid email or password")
+		return
+	}
 	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
+		writeRepositoryError(w, r, err, "user not found")
 		return
 	}
 
 	if !u.ValidatePassword(user.Password) {
-		w.WriteHeader(http.StatusUnauthorized)
+		h.registerLoginFailure(user.Email, ip, &u.TenantID)
+		problem.Write(w, r, http.StatusUnauthorized, problem.CodeInvalidLogin, "invalid email or password")
+		return
+	}
+	if u.PasswordNeedsRehash() {
+		rehashPassword(h.UserDB, u, user.Password)
+	}
+	if u.IsDisabled() {
+		problem.Write(w, r, http.StatusForbidden, problem.CodeDisabled, "account is disabled")
+		return
+	}
+	if h.Verifier.Required && !u.IsEmailVerified() {
+		problem.Write(w, r, http.StatusForbidden, problem.CodeEmailNotVerify, "confirm your email address before signing in")
+		return
+	}
+
+	// Com TOTP ativo a senha só libera o segundo passo do login
+	if u.IsMFAEnabled() {
+		challenge, err := h.MFA.Challenge(u)
+		if err != nil {
+			problem.Internal(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(dto.MFAChallengeOutput{
+			MFARequired: true,
+			MFAToken:    challenge,
+			ExpiresIn:   int64(h.MFA.ChallengeExpiresIn.Seconds()),
+		})
 		return
 	}
 
-	m := map[string]interface{}{"sub": u.ID.String(), "exp": time.Now().Add(time.Hour * time.Duration(h.JwtExpiresIn)).Unix()}
-	_, tokenString, _ := h.Jwt.Encode(m)
+	h.registerLoginSuccess(u.Email)
+	tokens, err := h.Tokens.Issue(u)
+	if err != nil {
+		writeRepositoryError(w, r, err, "user not found")
+		return
+	}
 
-	accessToken := dto.GetJWTOutput{AccessToken: tokenString}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(accessToken)
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// findActiveUser trata uma conta desativada como inexistente, para que ela não renove os tokens
+func (h *UserHandler) findActiveUser(id string) (*entity.User, error) {
+	u, err := h.UserDB.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if u.IsDisabled() {
+		return nil, database.ErrNotFound
+	}
+	return u, nil
+}
+
+// rehashPassword não impede o login se falhar, o hash antigo continua válido
+func rehashPassword(userDB database.UserDBInterface, u *entity.User, plain string) {
+	err := u.RehashPassword(plain)
+	if err == nil {
+		err = userDB.Update(u)
+	}
+	if err != nil {
+		log.Printf("error upgrading password hash of user %s: %v", u.ID, err)
+	}
+}
+
+// registerLoginFailure não interrompe o login se o contador falhar, apenas loga
+func (h *UserHandler) registerLoginFailure(email, ip string, tenantID *entityPkg.ID) {
+	if err := h.Throttle.Failure(email, ip, tenantID); err != nil {
+		log.Printf("error registering failed login: %v", err)
+	}
+}
+
+func (h *UserHandler) registerLoginSuccess(email string) {
+	if err := h.Throttle.Success(email); err != nil {
+		log.Printf("error resetting failed logins: %v", err)
+	}
+}
+
+// Refresh JWT godoc
+// @Summary      Refresh user JWT
+// @Description  Exchange a refresh token for a new access token and refresh token. Reusing a refresh token revokes the whole token family.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request    body     dto.RefreshTokenInput  true  "refresh token"
+// @Success      200  {object}  dto.GetJWTOutput
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
+// @Router       /users/refresh [post]
+func (h *UserHandler) RefreshJWT(w http.ResponseWriter, r *http.Request) {
+	var input dto.RefreshTokenInput
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		invalidBody(w, r)
+		return
+	}
+	if !requireFields(w, r, map[string]string{"refresh_token": input.RefreshToken}) {
+		return
+	}
+
+	tokens, err := h.Tokens.Rotate(input.RefreshToken, h.findActiveUser)
+	if err != nil {
+		if errors.Is(err, ErrInvalidRefreshToken) || errors.Is(err, ErrRefreshTokenReused) {
+			problem.Write(w, r, http.StatusUnauthorized, problem.CodeInvalidToken, err.Error())
+			return
+		}
+		writeRepositoryError(w, r, err, "refresh token not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// Logout godoc
+// @Summary      Logout
+// @Description  Revoke the current access token and, when informed, the refresh token family
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request    body     dto.LogoutInput  false  "refresh token"
+// @Success      204
+// @Failure      401  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
+// @Router       /users/logout [post]
+// @Security	 ApiKeyAuth
+func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	token, _, err := jwtauth.FromContext(r.Context())
+	if err != nil || token == nil || token.JwtID() == "" {
+		problem.Write(w, r, http.StatusUnauthorized, problem.CodeInvalidToken, "access token is invalid")
+		return
+	}
+
+	err = h.RevokedTokenDB.Revoke(token.JwtID(), token.Expiration())
+	if err != nil {
+		writeRepositoryError(w, r, err, "access token not found")
+		return
+	}
+
+	// O corpo é opcional: sem refresh token apenas o access token atual é revogado
+	var input dto.LogoutInput
+	if json.NewDecoder(r.Body).Decode(&input) == nil && input.RefreshToken != "" {
+		err = h.Tokens.Revoke(input.RefreshToken)
+		if err != nil && !errors.Is(err, ErrInvalidRefreshToken) {
+			writeRepositoryError(w, r, err, "refresh token not found")
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
 // Create User godoc
 // @Summary      Create user
-// @Description  Create user
+// @Description  Create user. New users always join the default tenant. Emails are unique across all tenants; an email registered in another tenant gets the same response as a new account.
 // @Tags         users
 // @Accept       json
 // @Produce      json
 // @Param        request    body     dto.CreateUserInput  true  "user request"
 // @Success      201
-// @Failure      500  {object}  Error
+// @Failure      400  {object}  problem.Problem
+// @Failure      409  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
 // @Router       /users [post]
 func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	var user dto.CreateUserInput
 	err := json.NewDecoder(r.Body).Decode(&user)
-	if err != nil || user.Name == "" || user.Email == "" || user.Password == "" {
-		w.WriteHeader(http.StatusBadRequest)
+	if err != nil {
+		invalidBody(w, r)
+		return
+	}
+	if !requireFields(w, r, map[string]string{"name": user.Name, "email": user.Email, "password": user.Password}) {
 		return
 	}
 
 	u, err := entity.NewUser(user.Name, user.Email, user.Password)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeValidationError(w, r, err)
 		return
 	}
+	// Os operadores movem o usuário com "server tenant add-user"
+	tenant, err := h.Tenants.Default()
+	if err != nil {
+		writeRepositoryError(w, r, err, "tenant not found")
+		return
+	}
+	u.TenantID = tenant.ID
 
+	// O email é único em todos os tenants, pois o login não informa o tenant
 	err = h.UserDB.CreateUser(u)
+	if errors.Is(err, database.ErrConflict) {
+		// Não revela contas de outros tenants
+		existing, findErr := h.UserDB.FindByEmail(u.Email)
+		if findErr == nil && existing.TenantID != tenant.ID {
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		problem.Conflict(w, r, "email is already registered")
+		return
+	}
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		writeRepositoryError(w, r, err, "user not found")
 		return
 	}
+
+	// A conta já foi criada: se o envio falhar o usuário pode pedir um novo link
+	if err := h.Verifier.Send(u); err != nil {
+		log.Printf("error sending verification email to user %s: %v", u.ID, err)
+	}
 	w.WriteHeader(http.StatusCreated)
 }
+
+// Verify email godoc
+// @Summary      Verify email
+// @Description  Confirm the user email address with the token sent by email. Each token can be used only once.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request    body     dto.VerifyEmailInput  true  "verification token"
+// @Success      204
+// @Failure      400  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
+// @Router       /users/verify [post]
+func (h *UserHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var input dto.VerifyEmailInput
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		invalidBody(w, r)
+		return
+	}
+	if !requireFields(w, r, map[string]string{"token": input.Token}) {
+		return
+	}
+
+	err = h.Verifier.Verify(input.Token)
+	if errors.Is(err, ErrInvalidVerificationToken) {
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidToken, err.Error())
+		return
+	}
+	if err != nil {
+		writeRepositoryError(w, r, err, "user not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Resend verification godoc
+// @Summary      Resend verification email
+// @Description  Send a new verification link. The response is always 202 so it does not reveal whether the email is registered.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request    body     dto.ResendVerificationInput  true  "user email"
+// @Success      202
+// @Failure      400  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
+// @Router       /users/verify/resend [post]
+func (h *UserHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	var input dto.ResendVerificationInput
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		invalidBody(w, r)
+		return
+	}
+	if !requireFields(w, r, map[string]string{"email": input.Email}) {
+		return
+	}
+
+	u, err := h.UserDB.FindByEmail(input.Email)
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		writeRepositoryError(w, r, err, "user not found")
+		return
+	}
+	if err == nil && !u.IsEmailVerified() {
+		if err := h.Verifier.Send(u); err != nil {
+			log.Printf("error sending verification email to user %s: %v", u.ID, err)
+		}
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// Forgot password godoc
+// @Summary      Forgot password
+// @Description  Send a password reset link. The response is always 202 so it does not reveal whether the email is registered.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request    body     dto.ForgotPasswordInput  true  "user email"
+// @Success      202
+// @Failure      400  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
+// @Router       /users/password/forgot [post]
+func (h *UserHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var input dto.ForgotPasswordInput
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		invalidBody(w, r)
+		return
+	}
+	if !requireFields(w, r, map[string]string{"email": input.Email}) {
+		return
+	}
+
+	// Falhas no envio só são logadas, para a resposta ser a mesma de um email não cadastrado
+	err = h.Resetter.Request(input.Email)
+	if errors.Is(err, database.ErrUnavailable) {
+		problem.Unavailable(w, r)
+		return
+	}
+	if err != nil {
+		log.Printf("error requesting password reset: %v", err)
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// Reset password godoc
+// @Summary      Reset password
+// @Description  Set a new password with the token sent by email. All sessions of the user are revoked.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request    body     dto.ResetPasswordInput  true  "reset token and new password"
+// @Success      204
+// @Failure      400  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem
+// @Router       /users/password/reset [post]
+func (h *UserHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var input dto.ResetPasswordInput
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		invalidBody(w, r)
+		return
+	}
+	if !requireFields(w, r, map[string]string{"token": input.Token, "password": input.Password}) {
+		return
+	}
+	// Validada antes de consumir o token, para permitir outra tentativa com o mesmo link
+	if err := entity.CheckPasswordPolicy(input.Password); err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+
+	err = h.Resetter.Reset(input.Token, input.Password)
+	if errors.Is(err, ErrInvalidResetToken) {
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidToken, err.Error())
+		return
+	}
+	if err != nil {
+		writeRepositoryError(w, r, err, "user not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/gsouza97/go-expert-api/internal/dto"
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	"github.com/gsouza97/go-expert-api/internal/infra/database"
+	"github.com/gsouza97/go-expert-api/internal/infra/mail"
+	"github.com/gsouza97/go-expert-api/internal/infra/webserver/middlewares"
+	"github.com/gsouza97/go-expert-api/internal/infra/webserver/problem"
+	"github.com/gsouza97/go-expert-api/pkg/cursor"
+	"github.com/gsouza97/go-expert-api/pkg/jwtkeys"
+	"github.com/gsouza97/go-expert-api/pkg/password"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func newTestUserHandler(t *testing.T) (*UserHandler, *mail.MemoryMailer) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.AutoMigrate(&entity.User{}, &entity.RevokedToken{}, &entity.RefreshToken{}, &entity.PasswordResetToken{}, &entity.RecoveryCode{}, &entity.LoginThrottle{}, &entity.LockoutEvent{}, &entity.APIKey{}, &entity.OAuthClient{}, &entity.Tenant{})
+	tenant, err := entity.NewTenant("Default", entity.DefaultTenantSlug)
+	assert.NoError(t, err)
+	tenantDB := database.NewTenantDB(db)
+	assert.NoError(t, tenantDB.Create(tenant))
+	userDB := database.NewUserDB(db)
+	refreshTokenDB := database.NewRefreshTokenDB(db)
+	tokenAuth, err := jwtkeys.NewKeySet(jwtkeys.NewHMACKey([]byte("secret")))
+	assert.NoError(t, err)
+	issuer := NewTokenIssuer(tokenAuth, refreshTokenDB, time.Minute, time.Hour)
+	mailer := mail.NewMemoryMailer()
+	verifier := NewEmailVerifier(userDB, cursor.NewSigner([]byte("secret")), mailer, time.Hour, "http://app.test/verify", true)
+	resetter := NewPasswordResetter(userDB, database.NewPasswordResetTokenDB(db), refreshTokenDB, mailer, time.Hour, "http://app.test/reset")
+	mfa := NewTOTPManager(userDB, database.NewRecoveryCodeDB(db), cursor.NewSigner([]byte("secret")), "Test", time.Minute)
+	throttle := NewLoginThrottler(database.NewLoginThrottleDB(db), 3, 10, time.Minute, time.Minute, time.Hour)
+	tenants := NewTenantResolver(tenantDB, entity.DefaultTenantSlug)
+	return NewUserHandler(userDB, database.NewRevokedTokenDB(db), issuer, verifier, resetter, mfa, throttle, tenants), mailer
+}
+
+func postJSON(handler http.HandlerFunc, path, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+	return rec
+}
+
+// tokenFrom extrai o token do link enviado no email
+func tokenFrom(t *testing.T, msg mail.Message) string {
+	start := strings.Index(msg.Body, "http://app.test/")
+	assert.NotEqual(t, -1, start)
+	link, err := url.Parse(strings.Fields(msg.Body[start:])[0])
+	assert.NoError(t, err)
+	return link.Query().Get("token")
+}
+
+// getMe executa o GET /users/me atrás do RejectInactiveSessions, como nas rotas do servidor
+func getMe(t *testing.T, h *UserHandler, accessToken string) *httptest.ResponseRecorder {
+	token, err := h.Tokens.Jwt.Decode(accessToken)
+	assert.NoError(t, err)
+	req := httptest.NewRequest(http.MethodGet, "/users/me", nil)
+	req = req.WithContext(jwtauth.NewContext(req.Context(), token, nil))
+	rec := httptest.NewRecorder()
+	middlewares.RejectInactiveSessions(h.UserDB)(http.HandlerFunc(h.GetMe)).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCreateUser_DuplicateEmail(t *testing.T) {
+	h, _ := newTestUserHandler(t)
+	create := func(body string) *httptest.ResponseRecorder {
+		return postJSON(h.CreateUser, "/users", body)
+	}
+
+	rec := create(`{"name":"John","email":"johndoe@test.com","password":"12345678"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	rec = create(`{"name":"Johnny","email":" JohnDoe@TEST.com","password":"87654321"}`)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), problem.CodeConflict)
+
+	rec = create(`{"name":"John","email":"not-an-email","password":"12345678"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"field":"email"`)
+}
+
+func TestCreateUser_IgnoresTenant(t *testing.T) {
+	h, _ := newTestUserHandler(t)
+	globex, _ := newTestTenant(t, h, "globex")
+	productDB := newTestProductDB(t)
+	product, err := entity.NewProduct("Globex roadmap", 10)
+	assert.NoError(t, err)
+	assert.NoError(t, productDB.ForTenant(globex.ID.String()).CreateProduct(product))
+	products := NewProductHandler(productDB, 100, cursor.NewSigner([]byte("secret")), false)
+
+	// O cadastro público não escolhe o tenant, mesmo informando o slug de outro
+	rec := postJSON(h.CreateUser, "/users", `{"name":"Eve","email":"eve@test.com","password":"12345678","tenant":"globex"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	eve, err := h.UserDB.FindByEmail("eve@test.com")
+	assert.NoError(t, err)
+	assert.NotEqual(t, globex.ID, eve.TenantID)
+
+	session, err := h.Tokens.Issue(eve)
+	assert.NoError(t, err)
+	token, err := h.Tokens.Jwt.Decode(session.AccessToken)
+	assert.NoError(t, err)
+	tid, _ := token.Get("tid")
+	assert.Equal(t, eve.TenantID.String(), tid)
+
+	claims := map[string]interface{}{"sub": eve.ID.String(), "tid": tid, "role": eve.Role}
+	rec = productRequest(t, products.GetProducts, http.MethodGet, "/products", "", "", claims)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var output dto.ProductListOutput
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&output))
+	assert.Empty(t, output.Items)
+	rec = productRequest(t, products.GetProduct, http.MethodGet, "/products/"+product.ID.String(), product.ID.String(), "", claims)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestCreateUser_EmailInOtherTenant(t *testing.T) {
+	h, mailer := newTestUserHandler(t)
+	globex, _ := newTestTenant(t, h, "globex")
+
+	// O cadastro com o email de uma conta da globex não se distingue de um cadastro novo
+	rec := postJSON(h.CreateUser, "/users", `{"name":"Bob","email":"bob@globex.com","password":"87654321"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Empty(t, rec.Body.String())
+	assert.Empty(t, mailer.Messages())
+
+	// A conta existente não muda
+	bob, err := h.UserDB.FindByEmail("bob@globex.com")
+	assert.NoError(t, err)
+	assert.Equal(t, globex.ID, bob.TenantID)
+	assert.True(t, bob.ValidatePassword("12345678"))
+}
+
+func TestCreateUser_PasswordPolicy(t *testing.T) {
+	h, _ := newTestUserHandler(t)
+
+	rec := postJSON(h.CreateUser, "/users", `{"name":"John","email":"johndoe@test.com","password":"1234"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"field":"password"`)
+	assert.Contains(t, rec.Body.String(), `"code":"too_short"`)
+
+	rec = postJSON(h.CreateUser, "/users", `{"name":"John","email":"johndoe@test.com","password":"`+strings.Repeat("a", 73)+`"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"code":"too_long"`)
+}
+
+func TestGetJWT_UpgradesPasswordHash(t *testing.T) {
+	h, _ := newTestUserHandler(t)
+	h.Verifier.Required = false
+
+	rec := postJSON(h.CreateUser, "/users", `{"name":"John","email":"johndoe@test.com","password":"12345678"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	u, err := h.UserDB.FindByEmail("johndoe@test.com")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(u.Password, "$2a$"))
+
+	// Parâmetros baixos só para o teste ficar rápido
+	entity.SetPasswordHasher(password.NewManager(password.NewArgon2idHasher(64, 1, 1), password.NewBcryptHasher(bcrypt.DefaultCost)))
+	defer entity.SetPasswordHasher(password.NewManager(password.NewBcryptHasher(bcrypt.DefaultCost)))
+
+	rec = postJSON(h.GetJWT, "/users/getToken", `{"email":"johndoe@test.com","password":"12345678"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	u, err = h.UserDB.FindByEmail("johndoe@test.com")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(u.Password, "$argon2id$"))
+	assert.False(t, u.PasswordNeedsRehash())
+
+	rec = postJSON(h.GetJWT, "/users/getToken", `{"email":"johndoe@test.com","password":"12345678"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestEmailVerification(t *testing.T) {
+	h, mailer := newTestUserHandler(t)
+	credentials := `{"email":"johndoe@test.com","password":"12345678"}`
+
+	rec := postJSON(h.CreateUser, "/users", `{"name":"John","email":"johndoe@test.com","password":"12345678"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Len(t, mailer.Messages(), 1)
+	assert.Equal(t, "johndoe@test.com", mailer.Messages()[0].To)
+
+	rec = postJSON(h.GetJWT, "/users/getToken", credentials)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), problem.CodeEmailNotVerify)
+
+	// Reenvio não revela se o email existe
+	rec = postJSON(h.ResendVerification, "/users/verify/resend", `{"email":"nobody@test.com"}`)
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Len(t, mailer.Messages(), 1)
+	rec = postJSON(h.ResendVerification, "/users/verify/resend", `{"email":"johndoe@test.com"}`)
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Len(t, mailer.Messages(), 2)
+
+	token := tokenFrom(t, mailer.Messages()[1])
+	rec = postJSON(h.VerifyEmail, "/users/verify", `{"token":"`+token+`x"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = postJSON(h.VerifyEmail, "/users/verify", `{"token":"`+token+`"}`)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	// O token só pode ser usado uma vez, inclusive os enviados antes
+	rec = postJSON(h.VerifyEmail, "/users/verify", `{"token":"`+token+`"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = postJSON(h.VerifyEmail, "/users/verify", `{"token":"`+tokenFrom(t, mailer.Messages()[0])+`"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = postJSON(h.GetJWT, "/users/getToken", credentials)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = postJSON(h.ResendVerification, "/users/verify/resend", `{"email":"johndoe@test.com"}`)
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Len(t, mailer.Messages(), 2)
+}
+
+func TestEmailVerifier_ExpiredToken(t *testing.T) {
+	h, mailer := newTestUserHandler(t)
+	h.Verifier.ExpiresIn = -time.Minute
+
+	rec := postJSON(h.CreateUser, "/users", `{"name":"John","email":"johndoe@test.com","password":"12345678"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	err := h.Verifier.Verify(tokenFrom(t, mailer.Messages()[0]))
+	assert.ErrorIs(t, err, ErrInvalidVerificationToken)
+}
+
+func TestPasswordReset(t *testing.T) {
+	h, mailer := newTestUserHandler(t)
+	h.Verifier.Required = false
+
+	rec := postJSON(h.CreateUser, "/users", `{"name":"John","email":"johndoe@test.com","password":"12345678"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	rec = postJSON(h.GetJWT, "/users/getToken", `{"email":"johndoe@test.com","password":"12345678"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var session dto.GetJWTOutput
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&session))
+	assert.Equal(t, http.StatusOK, getMe(t, h, session.AccessToken).Code)
+	sent := len(mailer.Messages())
+
+	// A resposta é a mesma para emails cadastrados ou não
+	unknown := postJSON(h.ForgotPassword, "/users/password/forgot", `{"email":"nobody@test.com"}`)
+	known := postJSON(h.ForgotPassword, "/users/password/forgot", `{"email":"johndoe@test.com"}`)
+	assert.Equal(t, http.StatusAccepted, unknown.Code)
+	assert.Equal(t, unknown.Code, known.Code)
+	assert.Equal(t, unknown.Body.String(), known.Body.String())
+	assert.Len(t, mailer.Messages(), sent+1)
+
+	token := tokenFrom(t, mailer.Messages()[sent])
+	rec = postJSON(h.ResetPassword, "/users/password/reset", `{"token":"unknown","password":"abcdefgh"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	// Uma senha fraca não consome o token
+	rec = postJSON(h.ResetPassword, "/users/password/reset", `{"token":"`+token+`","password":"abc"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"field":"password"`)
+	rec = postJSON(h.ResetPassword, "/users/password/reset", `{"token":"`+token+`","password":"abcdefgh"}`)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	rec = postJSON(h.ResetPassword, "/users/password/reset", `{"token":"`+token+`","password":"ghijklmn"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = postJSON(h.GetJWT, "/users/getToken", `{"email":"johndoe@test.com","password":"12345678"}`)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	rec = postJSON(h.GetJWT, "/users/getToken", `{"email":"johndoe@test.com","password":"abcdefgh"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// As sessões abertas antes da redefinição foram encerradas, inclusive os access tokens
+	rec = postJSON(h.RefreshJWT, "/users/refresh", `{"refresh_token":"`+session.RefreshToken+`"}`)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	rec = getMe(t, h, session.AccessToken)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Body.String(), "access token was revoked")
+}
@@ -0,0 +1,110 @@
+package middlewares
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	"github.com/gsouza97/go-expert-api/internal/infra/database"
+	"github.com/gsouza97/go-expert-api/internal/infra/webserver/problem"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+const APIKeyHeader = "X-API-Key"
+
+// lastUsedInterval evita uma escrita no banco a cada request feita com a mesma chave
+const lastUsedInterval = time.Minute
+
+// AcceptAPIKeys usa apiKey quando há o header X-API-Key e jwt caso contrário, nunca os dois
+func AcceptAPIKeys(apiKey func(http.Handler) http.Handler, bearer ...func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		withKey := apiKey(next)
+		withBearer := next
+		for i := len(bearer) - 1; i >= 0; i-- {
+			withBearer = bearer[i](withBearer)
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get(APIKeyHeader) == "" {
+				withBearer.ServeHTTP(w, r)
+				return
+			}
+			if r.Header.Get("Authorization") != "" {
+				problem.BadRequest(w, r, "send either an Authorization header or an X-API-Key header, not both")
+				return
+			}
+			withKey.ServeHTTP(w, r)
+		})
+	}
+}
+
+// APIKeyAuthenticator coloca no contexto do jwtauth um token equivalente à chave, com as permissões no claim scope
+func APIKeyAuthenticator(apiKeyDB database.APIKeyDBInterface, userDB database.UserDBInterface) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			plain := strings.TrimSpace(r.Header.Get(APIKeyHeader))
+			key, user, err := authenticateAPIKey(apiKeyDB, userDB, plain)
+			switch {
+			case errors.Is(err, entity.ErrInvalidAPIKey):
+				problem.Write(w, r, http.StatusUnauthorized, problem.CodeInvalidToken, err.Error())
+				return
+			case errors.Is(err, database.ErrUnavailable):
+				problem.Unavailable(w, r)
+				return
+			case err != nil:
+				problem.Internal(w, r)
+				return
+			}
+			if user.IsDisabled() {
+				problem.Write(w, r, http.StatusForbidden, problem.CodeDisabled, "account is disabled")
+				return
+			}
+
+			now := time.Now()
+			if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > lastUsedInterval {
+				if err := apiKeyDB.TouchLastUsed(key.ID.String(), now); err != nil {
+					log.Printf("error updating last use of api key %s: %v", key.ID, err)
+				}
+			}
+
+			token := jwt.New()
+			token.Set(jwt.SubjectKey, user.ID.String())
+			token.Set("tid", user.TenantID.String())
+			token.Set("role", user.Role)
+			token.Set("akid", key.ID.String())
+			if key.Scopes != "" {
+				token.Set("scope", key.Scopes)
+			}
+			ctx := jwtauth.NewContext(r.Context(), token, nil)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func authenticateAPIKey(apiKeyDB database.APIKeyDBInterface, userDB database.UserDBInterface, plain string) (*entity.APIKey, *entity.User, error) {
+	prefix, err := entity.ParseAPIKeyPrefix(plain)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := apiKeyDB.FindByPrefix(prefix)
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, nil, entity.ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if !key.Matches(plain) || key.IsRevoked() || key.IsExpired() {
+		return nil, nil, entity.ErrInvalidAPIKey
+	}
+	user, err := userDB.FindByID(key.UserID.String())
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, nil, entity.ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, user, nil
+}
@@ -0,0 +1,102 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth"
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	"github.com/gsouza97/go-expert-api/internal/infra/database"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestAPIKeyAuthenticator(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.AutoMigrate(&entity.User{}, &entity.APIKey{})
+	userDB := database.NewUserDB(db)
+	apiKeyDB := database.NewAPIKeyDB(db)
+	user, err := entity.NewUser("John", "johndoe@test.com", "12345678")
+	assert.NoError(t, err)
+	user.Role = entity.RoleEditor
+	assert.NoError(t, userDB.CreateUser(user))
+
+	newKey := func(scopes ...entity.Permission) (*entity.APIKey, string) {
+		key, plain, err := entity.NewAPIKey(user.ID, "CI", scopes, nil)
+		assert.NoError(t, err)
+		assert.NoError(t, apiKeyDB.Create(key))
+		return key, plain
+	}
+	fullKey, full := newKey()
+	_, readOnly := newKey(entity.PermissionProductsRead)
+
+	tokenAuth := jwtauth.New("HS256", []byte("secret"), nil)
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		token, _, _ := jwtauth.FromContext(r.Context())
+		w.Write([]byte(token.Subject()))
+	}
+	r := chi.NewRouter()
+	r.Use(AcceptAPIKeys(APIKeyAuthenticator(apiKeyDB, userDB), jwtauth.Verifier(tokenAuth), Authenticator))
+	r.With(RequirePermission(entity.PermissionProductsRead)).Get("/products", ok)
+	r.With(RequirePermission(entity.PermissionProductsWrite)).Post("/products", ok)
+
+	do := func(method, key, bearer string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/products", nil)
+		if key != "" {
+			req.Header.Set(APIKeyHeader, key)
+		}
+		if bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+bearer)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodPost, full, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, user.ID.String(), rec.Body.String())
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, readOnly, "").Code)
+	assert.Equal(t, http.StatusForbidden, do(http.MethodPost, readOnly, "").Code)
+
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, full+"x", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "not-a-key", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "gea_unknown_secret", "").Code)
+
+	// Sem X-API-Key o JWT continua sendo aceito, mas não as duas credenciais juntas
+	_, bearer, err := tokenAuth.Encode(map[string]interface{}{"sub": "1", "role": entity.RoleViewer})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "", bearer).Code)
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "", "").Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodGet, full, bearer).Code)
+
+	keys, err := apiKeyDB.FindByUser(user.ID.String())
+	assert.NoError(t, err)
+	for _, key := range keys {
+		assert.NotNil(t, key.LastUsedAt)
+	}
+
+	user.Disable(time.Now())
+	assert.NoError(t, userDB.Update(user))
+	assert.Equal(t, http.StatusForbidden, do(http.MethodGet, full, "").Code)
+	user.Enable()
+	assert.NoError(t, userDB.Update(user))
+
+	assert.NoError(t, apiKeyDB.Revoke(fullKey.ID.String(), user.ID.String()))
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, full, "").Code)
+
+	expiresAt := time.Now().Add(time.Hour)
+	expiring, plain, err := entity.NewAPIKey(user.ID, "CI", nil, &expiresAt)
+	assert.NoError(t, err)
+	past := time.Now().Add(-time.Minute)
+	expiring.ExpiresAt = &past
+	assert.NoError(t, apiKeyDB.Create(expiring))
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, plain, "").Code)
+}
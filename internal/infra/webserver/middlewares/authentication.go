@@ -0,0 +1,48 @@
+package middlewares
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/gsouza97/go-expert-api/internal/infra/webserver/problem"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+// Authenticator substitui o jwtauth.Authenticator respondendo com problem details
+func Authenticator(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, _, err := jwtauth.FromContext(r.Context())
+		if errors.Is(err, jwtauth.ErrNoTokenFound) {
+			problem.Unauthorized(w, r, "authentication is required")
+			return
+		}
+		if err != nil || token == nil || jwt.Validate(token) != nil {
+			detail := "access token is invalid"
+			if errors.Is(err, jwtauth.ErrExpired) {
+				detail = "access token is expired"
+			}
+			problem.Write(w, r, http.StatusUnauthorized, problem.CodeInvalidToken, detail)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Recoverer trata panics nos handlers respondendo 500 sem expor detalhes do erro
+func Recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+				log.Printf("panic: %v\n%s", rec, debug.Stack())
+				problem.Internal(w, r)
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
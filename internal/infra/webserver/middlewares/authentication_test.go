@@ -0,0 +1,64 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth"
+	"github.com/gsouza97/go-expert-api/internal/infra/webserver/problem"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthenticator(t *testing.T) {
+	tokenAuth := jwtauth.New("HS256", []byte("secret"), nil)
+	r := chi.NewRouter()
+	r.Use(jwtauth.Verifier(tokenAuth))
+	r.Use(Authenticator)
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	do := func(token string) (int, problem.Problem) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		var p problem.Problem
+		if rec.Code != http.StatusOK {
+			assert.Equal(t, problem.ContentType, rec.Header().Get("Content-Type"))
+			assert.NoError(t, json.NewDecoder(rec.Body).Decode(&p))
+		}
+		return rec.Code, p
+	}
+
+	_, valid, _ := tokenAuth.Encode(map[string]interface{}{"sub": "1", "exp": time.Now().Add(time.Minute).Unix()})
+	code, _ := do(valid)
+	assert.Equal(t, http.StatusOK, code)
+
+	code, p := do("")
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.Equal(t, problem.CodeUnauthorized, p.Code)
+
+	_, expired, _ := tokenAuth.Encode(map[string]interface{}{"sub": "1", "exp": time.Now().Add(-time.Minute).Unix()})
+	code, p = do(expired)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.Equal(t, problem.CodeInvalidToken, p.Code)
+	assert.Equal(t, "access token is expired", p.Detail)
+
+	code, p = do("not-a-token")
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.Equal(t, problem.CodeInvalidToken, p.Code)
+}
+
+func TestRecoverer(t *testing.T) {
+	h := Recoverer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { panic("boom") }))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "boom")
+	assert.Contains(t, rec.Body.String(), problem.CodeInternal)
+}
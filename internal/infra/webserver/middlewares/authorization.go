@@ -0,0 +1,49 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	"github.com/gsouza97/go-expert-api/internal/infra/webserver/problem"
+)
+
+// RequirePermission deve ser usado depois de jwtauth.Verifier e jwtauth.Authenticator
+func RequirePermission(permission entity.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, claims, err := jwtauth.FromContext(r.Context())
+			if err != nil {
+				problem.Unauthorized(w, r, "authentication is required")
+				return
+			}
+			role, _ := claims["role"].(string)
+			if !entity.RoleHasPermission(role, permission) {
+				problem.Forbidden(w, r, "missing permission "+string(permission))
+				return
+			}
+			// Credenciais com escopo (ex: chaves da API) ficam limitadas às permissões listadas
+			if scope, ok := claims["scope"].(string); ok && !entity.ScopeHasPermission(scope, permission) {
+				problem.Forbidden(w, r, "missing scope "+string(permission))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RejectScopedTokens bloqueia os tokens com escopo, como os do OAuth2, nas rotas da própria conta
+func RejectScopedTokens(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, claims, err := jwtauth.FromContext(r.Context())
+		if err != nil {
+			problem.Unauthorized(w, r, "authentication is required")
+			return
+		}
+		if _, ok := claims["scope"]; ok {
+			problem.Forbidden(w, r, "scoped tokens cannot access account routes")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,65 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth"
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRouter(tokenAuth *jwtauth.JWTAuth) http.Handler {
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	r := chi.NewRouter()
+	r.Use(jwtauth.Verifier(tokenAuth))
+	r.Use(Authenticator)
+	r.With(RequirePermission(entity.PermissionProductsRead)).Get("/products", ok)
+	r.With(RequirePermission(entity.PermissionProductsWrite)).Post("/products", ok)
+	return r
+}
+
+func doRequest(t *testing.T, h http.Handler, tokenAuth *jwtauth.JWTAuth, method string, claims map[string]interface{}) int {
+	_, token, err := tokenAuth.Encode(claims)
+	assert.NoError(t, err)
+	req := httptest.NewRequest(method, "/products", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestRequirePermission(t *testing.T) {
+	tokenAuth := jwtauth.New("HS256", []byte("secret"), nil)
+	h := newTestRouter(tokenAuth)
+
+	viewer := map[string]interface{}{"sub": "1", "role": entity.RoleViewer}
+	assert.Equal(t, http.StatusOK, doRequest(t, h, tokenAuth, http.MethodGet, viewer))
+	assert.Equal(t, http.StatusForbidden, doRequest(t, h, tokenAuth, http.MethodPost, viewer))
+
+	editor := map[string]interface{}{"sub": "1", "role": entity.RoleEditor}
+	assert.Equal(t, http.StatusOK, doRequest(t, h, tokenAuth, http.MethodPost, editor))
+
+	admin := map[string]interface{}{"sub": "1", "role": entity.RoleAdmin}
+	assert.Equal(t, http.StatusOK, doRequest(t, h, tokenAuth, http.MethodPost, admin))
+
+	noRole := map[string]interface{}{"sub": "1"}
+	assert.Equal(t, http.StatusForbidden, doRequest(t, h, tokenAuth, http.MethodGet, noRole))
+}
+
+func TestRejectScopedTokens(t *testing.T) {
+	tokenAuth := jwtauth.New("HS256", []byte("secret"), nil)
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	r := chi.NewRouter()
+	r.Use(jwtauth.Verifier(tokenAuth))
+	r.Use(Authenticator)
+	r.With(RejectScopedTokens).Get("/products", ok)
+
+	user := map[string]interface{}{"sub": "1", "role": entity.RoleViewer}
+	assert.Equal(t, http.StatusOK, doRequest(t, r, tokenAuth, http.MethodGet, user))
+
+	scoped := map[string]interface{}{"sub": "1", "role": entity.RoleViewer, "scope": "products:read"}
+	assert.Equal(t, http.StatusForbidden, doRequest(t, r, tokenAuth, http.MethodGet, scoped))
+}
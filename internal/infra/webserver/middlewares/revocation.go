@@ -0,0 +1,37 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/gsouza97/go-expert-api/internal/infra/database"
+	"github.com/gsouza97/go-expert-api/internal/infra/webserver/problem"
+)
+
+// RejectRevokedTokens bloqueia access tokens sem jti ou presentes na denylist (ex: após logout)
+func RejectRevokedTokens(revokedTokenDB database.RevokedTokenDBInterface) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, _, err := jwtauth.FromContext(r.Context())
+			if err != nil || token == nil || token.JwtID() == "" {
+				problem.Write(w, r, http.StatusUnauthorized, problem.CodeInvalidToken, "access token is invalid")
+				return
+			}
+			revoked, err := revokedTokenDB.IsRevoked(token.JwtID())
+			if errors.Is(err, database.ErrUnavailable) {
+				problem.Unavailable(w, r)
+				return
+			}
+			if err != nil {
+				problem.Internal(w, r)
+				return
+			}
+			if revoked {
+				problem.Write(w, r, http.StatusUnauthorized, problem.CodeInvalidToken, "access token was revoked")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeRevokedTokenDB struct {
+	revoked map[string]bool
+}
+
+func (f *fakeRevokedTokenDB) Revoke(jti string, expiresAt time.Time) error {
+	f.revoked[jti] = true
+	return nil
+}
+
+func (f *fakeRevokedTokenDB) IsRevoked(jti string) (bool, error) {
+	return f.revoked[jti], nil
+}
+
+func (f *fakeRevokedTokenDB) DeleteExpired() error {
+	return nil
+}
+
+func TestRejectRevokedTokens(t *testing.T) {
+	tokenAuth := jwtauth.New("HS256", []byte("secret"), nil)
+	revokedDB := &fakeRevokedTokenDB{revoked: map[string]bool{}}
+
+	r := chi.NewRouter()
+	r.Use(jwtauth.Verifier(tokenAuth))
+	r.Use(Authenticator)
+	r.Use(RejectRevokedTokens(revokedDB))
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	do := func(claims map[string]interface{}) int {
+		_, token, err := tokenAuth.Encode(claims)
+		assert.NoError(t, err)
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, do(map[string]interface{}{"sub": "1", "jti": "a"}))
+	assert.Equal(t, http.StatusUnauthorized, do(map[string]interface{}{"sub": "1"}))
+
+	assert.NoError(t, revokedDB.Revoke("a", time.Now().Add(time.Hour)))
+	assert.Equal(t, http.StatusUnauthorized, do(map[string]interface{}{"sub": "1", "jti": "a"}))
+}
@@ -0,0 +1,46 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/gsouza97/go-expert-api/internal/infra/database"
+	"github.com/gsouza97/go-expert-api/internal/infra/webserver/problem"
+)
+
+// RejectInactiveSessions bloqueia os tokens de usuários removidos ou desativados e os de sessões revogadas (claim sv)
+func RejectInactiveSessions(userDB database.UserDBInterface) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, claims, err := jwtauth.FromContext(r.Context())
+			if err != nil || token == nil || token.Subject() == "" {
+				problem.Write(w, r, http.StatusUnauthorized, problem.CodeInvalidToken, "access token is invalid")
+				return
+			}
+			user, err := userDB.FindByID(token.Subject())
+			switch {
+			case errors.Is(err, database.ErrNotFound):
+				problem.Write(w, r, http.StatusUnauthorized, problem.CodeInvalidToken, "access token is invalid")
+				return
+			case errors.Is(err, database.ErrUnavailable):
+				problem.Unavailable(w, r)
+				return
+			case err != nil:
+				problem.Internal(w, r)
+				return
+			}
+			if user.IsDisabled() {
+				problem.Write(w, r, http.StatusForbidden, problem.CodeDisabled, "account is disabled")
+				return
+			}
+			// Tokens emitidos antes do claim existir equivalem à versão 0
+			version, _ := claims["sv"].(float64)
+			if int64(version) != user.SessionVersion {
+				problem.Write(w, r, http.StatusUnauthorized, problem.CodeInvalidToken, "access token was revoked")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth"
+	"github.com/gsouza97/go-expert-api/internal/entity"
+	"github.com/gsouza97/go-expert-api/internal/infra/database"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestRejectInactiveSessions(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.AutoMigrate(&entity.User{})
+	userDB := database.NewUserDB(db)
+	user, err := entity.NewUser("John", "johndoe@test.com", "12345678")
+	assert.NoError(t, err)
+	assert.NoError(t, userDB.CreateUser(user))
+
+	tokenAuth := jwtauth.New("HS256", []byte("secret"), nil)
+	r := chi.NewRouter()
+	r.Use(jwtauth.Verifier(tokenAuth))
+	r.Use(Authenticator)
+	r.Use(RejectInactiveSessions(userDB))
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	do := func(claims map[string]interface{}) int {
+		_, token, err := tokenAuth.Encode(claims)
+		assert.NoError(t, err)
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, do(map[string]interface{}{"sub": user.ID.String(), "sv": 0}))
+	assert.Equal(t, http.StatusOK, do(map[string]interface{}{"sub": user.ID.String()}))
+	assert.Equal(t, http.StatusUnauthorized, do(map[string]interface{}{"sub": "unknown"}))
+
+	assert.NoError(t, userDB.RevokeSessions(user.ID.String()))
+	assert.Equal(t, http.StatusUnauthorized, do(map[string]interface{}{"sub": user.ID.String(), "sv": 0}))
+	assert.Equal(t, http.StatusOK, do(map[string]interface{}{"sub": user.ID.String(), "sv": 1}))
+
+	user.Disable(time.Now())
+	assert.NoError(t, userDB.Update(user))
+	assert.Equal(t, http.StatusForbidden, do(map[string]interface{}{"sub": user.ID.String(), "sv": 1}))
+}
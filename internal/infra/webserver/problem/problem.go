@@ -0,0 +1,136 @@
+// Package problem escreve as respostas de erro da API no formato RFC 7807 (application/problem+json).
+package problem
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const ContentType = "application/problem+json"
+
+// Códigos estáveis que os clientes podem usar para tratar os erros
+const (
+	CodeInvalidRequest = "invalid_request"
+	CodeValidation     = "validation_error"
+	CodeUnauthorized   = "unauthorized"
+	CodeInvalidLogin   = "invalid_credentials"
+	CodeInvalidToken   = "invalid_token"
+	CodeEmailNotVerify = "email_not_verified"
+	CodeDisabled       = "account_disabled"
+	CodeInvalidMFA     = "invalid_mfa_code"
+	CodeForbidden      = "forbidden"
+	CodeNotFound       = "not_found"
+	CodeMethodNotAllow = "method_not_allowed"
+	CodeConflict       = "conflict"
+	CodeInternal       = "internal_error"
+	CodeUnavailable    = "service_unavailable"
+	CodeTooManyRequest = "too_many_requests"
+	CodeUnsupportedMed = "unsupported_media_type"
+	CodePrecondition   = "precondition_failed"
+	CodePreconditionRq = "precondition_required"
+)
+
+// Problem é o corpo de erro retornado por todos os endpoints
+type Problem struct {
+	Type     string       `json:"type" example:"urn:go-expert-api:problem:validation_error"`
+	Title    string       `json:"title" example:"Bad Request"`
+	Status   int          `json:"status" example:"400"`
+	Detail   string       `json:"detail,omitempty" example:"name is required"`
+	Instance string       `json:"instance,omitempty" example:"/products"`
+	Code     string       `json:"code" example:"validation_error"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError descreve um erro de validação de um campo específico
+type FieldError struct {
+	Field   string `json:"field" example:"name"`
+	Code    string `json:"code" example:"required"`
+	Message string `json:"message" example:"name is required"`
+}
+
+func New(r *http.Request, status int, code, detail string) *Problem {
+	return &Problem{
+		Type:     "urn:go-expert-api:problem:" + code,
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.RequestURI(),
+		Code:     code,
+	}
+}
+
+func (p *Problem) Write(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// Write responde com um problem sem erros de campo
+func Write(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	New(r, status, code, detail).Write(w)
+}
+
+// Validation responde 400 com a lista de campos inválidos
+func Validation(w http.ResponseWriter, r *http.Request, errs ...FieldError) {
+	p := New(r, http.StatusBadRequest, CodeValidation, "the request has invalid fields")
+	if len(errs) == 1 {
+		p.Detail = errs[0].Message
+	}
+	p.Errors = errs
+	p.Write(w)
+}
+
+func BadRequest(w http.ResponseWriter, r *http.Request, detail string) {
+	Write(w, r, http.StatusBadRequest, CodeInvalidRequest, detail)
+}
+
+func Unauthorized(w http.ResponseWriter, r *http.Request, detail string) {
+	Write(w, r, http.StatusUnauthorized, CodeUnauthorized, detail)
+}
+
+func Forbidden(w http.ResponseWriter, r *http.Request, detail string) {
+	Write(w, r, http.StatusForbidden, CodeForbidden, detail)
+}
+
+func NotFound(w http.ResponseWriter, r *http.Request, detail string) {
+	Write(w, r, http.StatusNotFound, CodeNotFound, detail)
+}
+
+// Internal não expõe o erro original para o cliente
+func Internal(w http.ResponseWriter, r *http.Request) {
+	Write(w, r, http.StatusInternalServerError, CodeInternal, "an unexpected error occurred")
+}
+
+func Conflict(w http.ResponseWriter, r *http.Request, detail string) {
+	Write(w, r, http.StatusConflict, CodeConflict, detail)
+}
+
+// PreconditionFailed indica que o recurso mudou desde a versão informada pelo cliente (If-Match)
+func PreconditionFailed(w http.ResponseWriter, r *http.Request, detail string) {
+	Write(w, r, http.StatusPreconditionFailed, CodePrecondition, detail)
+}
+
+// Unavailable indica uma falha temporária (ex: banco fora do ar) e sugere ao cliente tentar novamente
+func Unavailable(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Retry-After", "5")
+	Write(w, r, http.StatusServiceUnavailable, CodeUnavailable, "the service is temporarily unavailable, try again later")
+}
+
+// TooManyRequests informa em Retry-After, em segundos arredondados para cima, quando o cliente pode tentar novamente
+func TooManyRequests(w http.ResponseWriter, r *http.Request, retryAfter time.Duration, detail string) {
+	seconds := int64(math.Ceil(retryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+	Write(w, r, http.StatusTooManyRequests, CodeTooManyRequest, detail)
+}
+
+// NotFoundHandler e MethodNotAllowedHandler substituem as respostas padrão do router
+func NotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	NotFound(w, r, "the requested resource does not exist")
+}
+
+func MethodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	Write(w, r, http.StatusMethodNotAllowed, CodeMethodNotAllow, r.Method+" is not allowed on this resource")
+}
@@ -0,0 +1,45 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrite(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NotFound(rec, httptest.NewRequest(http.MethodGet, "/products/1?x=y", nil), "product not found")
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, ContentType, rec.Header().Get("Content-Type"))
+
+	var p Problem
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&p))
+	assert.Equal(t, "urn:go-expert-api:problem:not_found", p.Type)
+	assert.Equal(t, "Not Found", p.Title)
+	assert.Equal(t, http.StatusNotFound, p.Status)
+	assert.Equal(t, "product not found", p.Detail)
+	assert.Equal(t, "/products/1?x=y", p.Instance)
+	assert.Equal(t, CodeNotFound, p.Code)
+	assert.Empty(t, p.Errors)
+}
+
+func TestValidation(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Validation(rec, httptest.NewRequest(http.MethodPost, "/products", nil),
+		FieldError{Field: "name", Code: "required", Message: "name is required"})
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.JSONEq(t, `{
+		"type": "urn:go-expert-api:problem:validation_error",
+		"title": "Bad Request",
+		"status": 400,
+		"detail": "name is required",
+		"instance": "/products",
+		"code": "validation_error",
+		"errors": [{"field": "name", "code": "required", "message": "name is required"}]
+	}`, rec.Body.String())
+}
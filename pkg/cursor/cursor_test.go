@@ -0,0 +1,56 @@
+package cursor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type position struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+}
+
+func TestSigner_EncodeDecode(t *testing.T) {
+	signer := NewSigner([]byte("secret"))
+	in := position{CreatedAt: time.Date(2023, 5, 1, 10, 0, 0, 123, time.UTC), ID: "abc"}
+
+	c, err := signer.Encode(in)
+	assert.NoError(t, err)
+
+	var out position
+	assert.NoError(t, signer.Decode(c, &out))
+	assert.True(t, in.CreatedAt.Equal(out.CreatedAt))
+	assert.Equal(t, in.ID, out.ID)
+}
+
+func TestSigner_RejectsTamperedCursor(t *testing.T) {
+	signer := NewSigner([]byte("secret"))
+	c, err := signer.Encode(position{ID: "abc"})
+	assert.NoError(t, err)
+
+	forged, err := NewSigner([]byte("other")).Encode(position{ID: "xyz"})
+	assert.NoError(t, err)
+
+	var out position
+	assert.ErrorIs(t, signer.Decode(forged, &out), ErrInvalidCursor)
+	assert.ErrorIs(t, signer.Decode(c[:len(c)-2], &out), ErrInvalidCursor)
+	assert.ErrorIs(t, signer.Decode("garbage", &out), ErrInvalidCursor)
+	assert.ErrorIs(t, signer.Decode("", &out), ErrInvalidCursor)
+}
+
+func TestDeriveKey(t *testing.T) {
+	secret := []byte("shared-secret")
+	cursorKey := DeriveKey(secret, "cursor-v1")
+	assert.Equal(t, cursorKey, DeriveKey(secret, "cursor-v1"))
+	assert.NotEqual(t, cursorKey, DeriveKey(secret, "email-verification-v1"))
+	assert.NotEqual(t, secret, cursorKey)
+
+	// Um valor assinado com a chave de um propósito não é aceito com a de outro
+	encoded, err := NewSigner(cursorKey).Encode(position{ID: "abc"})
+	assert.NoError(t, err)
+	var p position
+	assert.ErrorIs(t, NewSigner(DeriveKey(secret, "mfa-challenge-v1")).Decode(encoded, &p), ErrInvalidCursor)
+	assert.ErrorIs(t, NewSigner(secret).Decode(encoded, &p), ErrInvalidCursor)
+}
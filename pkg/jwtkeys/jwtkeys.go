@@ -0,0 +1,239 @@
+// Package jwtkeys assina e verifica JWTs com chaves identificadas por "kid", publicadas via JWKS
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+var (
+	ErrInvalidPEM         = errors.New("invalid PEM data")
+	ErrUnsupportedKey     = errors.New("unsupported key type")
+	ErrPrivateKeyRequired = errors.New("signing key must be a private key")
+	ErrUnknownKeyID       = errors.New("unknown key id")
+	ErrAlgorithmMismatch  = errors.New("token algorithm does not match key")
+)
+
+// Key é uma chave de assinatura ou de verificação
+type Key struct {
+	ID        string
+	Algorithm jwa.SignatureAlgorithm
+	signKey   interface{} // nil em chaves usadas apenas para verificação
+	verifyKey interface{}
+}
+
+// KeySet assina com uma única chave ativa e verifica com qualquer chave conhecida
+type KeySet struct {
+	signing *Key
+	keys    map[string]*Key
+}
+
+// NewHMACKey cria uma chave HS256 sem kid, compatível com os tokens emitidos pelo jwtauth
+func NewHMACKey(secret []byte) *Key {
+	return &Key{Algorithm: jwa.HS256, signKey: secret, verifyKey: secret}
+}
+
+// NewKey aceita chaves RSA ou ECDSA; o kid é o thumbprint (RFC 7638) da chave pública
+func NewKey(raw interface{}) (*Key, error) {
+	var private, public interface{}
+	switch k := raw.(type) {
+	case *rsa.PrivateKey:
+		private, public = k, &k.PublicKey
+	case *rsa.PublicKey:
+		public = k
+	case *ecdsa.PrivateKey:
+		private, public = k, &k.PublicKey
+	case *ecdsa.PublicKey:
+		public = k
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, raw)
+	}
+
+	alg, err := algorithmFor(public)
+	if err != nil {
+		return nil, err
+	}
+	publicJWK, err := jwk.New(public)
+	if err != nil {
+		return nil, err
+	}
+	thumbprint, err := publicJWK.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{
+		ID:        base64.RawURLEncoding.EncodeToString(thumbprint),
+		Algorithm: alg,
+		signKey:   private,
+		verifyKey: public,
+	}, nil
+}
+
+// ParsePEM lê uma chave RSA ou ECDSA em PEM (PKCS#1, PKCS#8, SEC 1 ou PKIX)
+func ParsePEM(data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrInvalidPEM
+	}
+
+	var raw interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		raw, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		raw, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		raw, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		raw, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		raw, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%w: PEM block %q", ErrUnsupportedKey, block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewKey(raw)
+}
+
+// NewKeySet cria o KeySet com a chave de assinatura ativa e chaves adicionais apenas de verificação
+func NewKeySet(signing *Key, verification ...*Key) (*KeySet, error) {
+	if signing.signKey == nil {
+		return nil, ErrPrivateKeyRequired
+	}
+	s := &KeySet{signing: signing, keys: map[string]*Key{signing.ID: signing}}
+	for _, key := range verification {
+		if _, ok := s.keys[key.ID]; !ok {
+			s.keys[key.ID] = key
+		}
+	}
+	return s, nil
+}
+
+// Encode assina os claims com a chave ativa, adicionando o kid no header
+func (s *KeySet) Encode(claims map[string]interface{}) (jwt.Token, string, error) {
+	t := jwt.New()
+	for k, v := range claims {
+		if err := t.Set(k, v); err != nil {
+			return nil, "", err
+		}
+	}
+
+	var opts []jwt.Option
+	if s.signing.ID != "" {
+		headers := jws.NewHeaders()
+		if err := headers.Set(jws.KeyIDKey, s.signing.ID); err != nil {
+			return nil, "", err
+		}
+		opts = append(opts, jwt.WithHeaders(headers))
+	}
+	payload, err := jwt.Sign(t, s.signing.Algorithm, s.signing.signKey, opts...)
+	if err != nil {
+		return nil, "", err
+	}
+	return t, string(payload), nil
+}
+
+// Decode verifica a assinatura usando a chave indicada pelo kid do token
+func (s *KeySet) Decode(tokenString string) (jwt.Token, error) {
+	msg, err := jws.ParseString(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if len(msg.Signatures()) != 1 {
+		return nil, jwtauth.ErrUnauthorized
+	}
+	headers := msg.Signatures()[0].ProtectedHeaders()
+
+	key, ok := s.keys[headers.KeyID()]
+	if !ok {
+		return nil, ErrUnknownKeyID
+	}
+	// O algoritmo é definido pela chave e nunca pelo token, evitando ataques de troca de algoritmo
+	if headers.Algorithm() != key.Algorithm {
+		return nil, ErrAlgorithmMismatch
+	}
+	return jwt.ParseString(tokenString, jwt.WithVerify(key.Algorithm, key.verifyKey))
+}
+
+// JWKS retorna as chaves públicas de verificação. Chaves HMAC nunca são publicadas.
+func (s *KeySet) JWKS() (jwk.Set, error) {
+	set := jwk.NewSet()
+	for _, key := range s.keys {
+		if key.Algorithm == jwa.HS256 {
+			continue
+		}
+		public, err := jwk.New(key.verifyKey)
+		if err != nil {
+			return nil, err
+		}
+		public.Set(jwk.KeyIDKey, key.ID)
+		public.Set(jwk.AlgorithmKey, key.Algorithm)
+		public.Set(jwk.KeyUsageKey, jwk.ForSignature)
+		set.Add(public)
+	}
+	return set, nil
+}
+
+// Verifier funciona como o jwtauth.Verifier, mas verificando com o KeySet
+func Verifier(s *KeySet) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, err := verifyRequest(s, r)
+			ctx := jwtauth.NewContext(r.Context(), token, err)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func verifyRequest(s *KeySet, r *http.Request) (jwt.Token, error) {
+	tokenString := jwtauth.TokenFromHeader(r)
+	if tokenString == "" {
+		tokenString = jwtauth.TokenFromCookie(r)
+	}
+	if tokenString == "" {
+		return nil, jwtauth.ErrNoTokenFound
+	}
+
+	token, err := s.Decode(tokenString)
+	if err != nil {
+		return nil, jwtauth.ErrorReason(err)
+	}
+	if err := jwt.Validate(token); err != nil {
+		return token, jwtauth.ErrorReason(err)
+	}
+	return token, nil
+}
+
+func algorithmFor(public interface{}) (jwa.SignatureAlgorithm, error) {
+	switch k := public.(type) {
+	case *rsa.PublicKey:
+		return jwa.RS256, nil
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return jwa.ES256, nil
+		case elliptic.P384():
+			return jwa.ES384, nil
+		case elliptic.P521():
+			return jwa.ES512, nil
+		}
+	}
+	return "", ErrUnsupportedKey
+}
@@ -0,0 +1,189 @@
+package jwtkeys
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRSAKey(t *testing.T) *Key {
+	raw, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	key, err := NewKey(raw)
+	assert.NoError(t, err)
+	return key
+}
+
+func newECKey(t *testing.T) (*Key, *ecdsa.PrivateKey) {
+	raw, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	key, err := NewKey(raw)
+	assert.NoError(t, err)
+	return key, raw
+}
+
+func TestKeySet_EncodeDecodeRS256(t *testing.T) {
+	key := newRSAKey(t)
+	assert.Equal(t, jwa.RS256, key.Algorithm)
+	assert.NotEmpty(t, key.ID)
+
+	keys, err := NewKeySet(key)
+	assert.NoError(t, err)
+
+	_, tokenString, err := keys.Encode(map[string]interface{}{"sub": "1"})
+	assert.NoError(t, err)
+
+	token, err := keys.Decode(tokenString)
+	assert.NoError(t, err)
+	assert.Equal(t, "1", token.Subject())
+
+	// Um token HS256 que aponta para o kid da chave RSA é rejeitado antes da verificação
+	headers := jws.NewHeaders()
+	headers.Set(jws.KeyIDKey, key.ID)
+	forged, err := jwt.Sign(jwt.New(), jwa.HS256, []byte("secret"), jwt.WithHeaders(headers))
+	assert.NoError(t, err)
+	_, err = keys.Decode(string(forged))
+	assert.ErrorIs(t, err, ErrAlgorithmMismatch)
+}
+
+func TestKeySet_Rotation(t *testing.T) {
+	oldKey, _ := newECKey(t)
+	newKey, _ := newECKey(t)
+	assert.Equal(t, jwa.ES256, newKey.Algorithm)
+
+	oldKeys, err := NewKeySet(oldKey)
+	assert.NoError(t, err)
+	_, oldToken, err := oldKeys.Encode(map[string]interface{}{"sub": "1"})
+	assert.NoError(t, err)
+
+	// Após a rotação a chave antiga continua apenas para verificação
+	rotated, err := NewKeySet(newKey, oldKey)
+	assert.NoError(t, err)
+	_, err = rotated.Decode(oldToken)
+	assert.NoError(t, err)
+
+	_, newToken, err := rotated.Encode(map[string]interface{}{"sub": "2"})
+	assert.NoError(t, err)
+	_, err = oldKeys.Decode(newToken)
+	assert.ErrorIs(t, err, ErrUnknownKeyID)
+
+	// Quando a chave antiga é removida os tokens dela deixam de ser aceitos
+	withoutOld, err := NewKeySet(newKey)
+	assert.NoError(t, err)
+	_, err = withoutOld.Decode(oldToken)
+	assert.ErrorIs(t, err, ErrUnknownKeyID)
+}
+
+func TestKeySet_HMACIsCompatibleWithJwtauth(t *testing.T) {
+	keys, err := NewKeySet(NewHMACKey([]byte("secret")))
+	assert.NoError(t, err)
+
+	_, tokenString, err := jwtauth.New("HS256", []byte("secret"), nil).Encode(map[string]interface{}{"sub": "1"})
+	assert.NoError(t, err)
+	token, err := keys.Decode(tokenString)
+	assert.NoError(t, err)
+	assert.Equal(t, "1", token.Subject())
+
+	_, tokenString, err = jwtauth.New("HS256", []byte("other"), nil).Encode(map[string]interface{}{"sub": "1"})
+	assert.NoError(t, err)
+	_, err = keys.Decode(tokenString)
+	assert.Error(t, err)
+
+	set, err := keys.JWKS()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, set.Len())
+}
+
+func TestKeySet_RejectsPublicSigningKey(t *testing.T) {
+	_, raw := newECKey(t)
+	public, err := NewKey(&raw.PublicKey)
+	assert.NoError(t, err)
+	_, err = NewKeySet(public)
+	assert.ErrorIs(t, err, ErrPrivateKeyRequired)
+}
+
+func TestKeySet_JWKS(t *testing.T) {
+	current := newRSAKey(t)
+	previous, _ := newECKey(t)
+	keys, err := NewKeySet(current, previous)
+	assert.NoError(t, err)
+
+	set, err := keys.JWKS()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, set.Len())
+
+	key, ok := set.LookupKeyID(current.ID)
+	assert.True(t, ok)
+	assert.Equal(t, "RS256", key.Algorithm())
+	var raw interface{}
+	assert.NoError(t, key.Raw(&raw))
+	_, isPublic := raw.(*rsa.PublicKey)
+	assert.True(t, isPublic)
+}
+
+func TestParsePEM(t *testing.T) {
+	_, raw := newECKey(t)
+	der, err := x509.MarshalPKCS8PrivateKey(raw)
+	assert.NoError(t, err)
+	private, err := ParsePEM(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	assert.NoError(t, err)
+
+	der, err = x509.MarshalPKIXPublicKey(&raw.PublicKey)
+	assert.NoError(t, err)
+	public, err := ParsePEM(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	assert.NoError(t, err)
+
+	// A chave pública recebe o mesmo kid da privada correspondente
+	assert.Equal(t, private.ID, public.ID)
+
+	_, err = ParsePEM([]byte("not a pem"))
+	assert.ErrorIs(t, err, ErrInvalidPEM)
+}
+
+func TestVerifier(t *testing.T) {
+	keys, err := NewKeySet(newRSAKey(t))
+	assert.NoError(t, err)
+
+	h := Verifier(keys)(jwtauth.Authenticator(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, claims, _ := jwtauth.FromContext(r.Context())
+		assert.Equal(t, "1", claims["sub"])
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	do := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	_, valid, err := keys.Encode(map[string]interface{}{"sub": "1", "exp": time.Now().Add(time.Minute).Unix()})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, do(valid))
+
+	_, expired, err := keys.Encode(map[string]interface{}{"sub": "1", "exp": time.Now().Add(-time.Minute).Unix()})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, do(expired))
+
+	// Token HS256 assinado com a chave pública como segredo não pode ser aceito
+	_, forged, err := jwtauth.New("HS256", []byte("forged"), nil).Encode(map[string]interface{}{"sub": "1"})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, do(forged))
+	assert.Equal(t, http.StatusUnauthorized, do(""))
+}
@@ -0,0 +1,58 @@
+// Package mergepatch aplica documentos JSON Merge Patch (RFC 7386)
+package mergepatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+const ContentType = "application/merge-patch+json"
+
+var ErrInvalidDocument = errors.New("invalid JSON document")
+
+// Apply aplica o patch ao documento e retorna o documento resultante
+func Apply(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := decode(patch)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(merge(target, changes))
+}
+
+func merge(target, patch interface{}) interface{} {
+	changes, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	result, ok := target.(map[string]interface{})
+	if !ok {
+		result = map[string]interface{}{}
+	}
+	for name, value := range changes {
+		if value == nil {
+			delete(result, name)
+			continue
+		}
+		result[name] = merge(result[name], value)
+	}
+	return result
+}
+
+// decode preserva os números como json.Number para não perder precisão ao serializar de novo
+func decode(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return nil, ErrInvalidDocument
+	}
+	if decoder.More() {
+		return nil, ErrInvalidDocument
+	}
+	return v, nil
+}
@@ -0,0 +1,42 @@
+package mergepatch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApply(t *testing.T) {
+	// Exemplos do apêndice A da RFC 7386
+	for _, tc := range []struct{ doc, patch, want string }{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		{`{"price":19.990000000000002}`, `{}`, `{"price":19.990000000000002}`},
+	} {
+		got, err := Apply([]byte(tc.doc), []byte(tc.patch))
+		assert.NoError(t, err)
+		assert.JSONEq(t, tc.want, string(got), tc.patch)
+	}
+}
+
+func TestApply_InvalidDocument(t *testing.T) {
+	for _, patch := range []string{``, `{`, `{"a":1} {"b":2}`} {
+		_, err := Apply([]byte(`{}`), []byte(patch))
+		assert.ErrorIs(t, err, ErrInvalidDocument, patch)
+	}
+	_, err := Apply([]byte(`garbage`), []byte(`{}`))
+	assert.ErrorIs(t, err, ErrInvalidDocument)
+}
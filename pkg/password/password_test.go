@@ -0,0 +1,99 @@
+package password
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Parâmetros baixos para os testes não ficarem lentos
+func newTestArgon2idHasher() *Argon2idHasher {
+	return NewArgon2idHasher(1024, 1, 1)
+}
+
+func TestArgon2idHasher(t *testing.T) {
+	hasher := newTestArgon2idHasher()
+	hash, err := hasher.Hash("correct horse")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"))
+	assert.True(t, hasher.Recognizes(hash))
+	assert.True(t, hasher.Verify(hash, "correct horse"))
+	assert.False(t, hasher.Verify(hash, "correct horsf"))
+	assert.False(t, hasher.NeedsRehash(hash))
+
+	other, err := hasher.Hash("correct horse")
+	assert.NoError(t, err)
+	assert.NotEqual(t, hash, other)
+
+	stronger := NewArgon2idHasher(2048, 1, 1)
+	assert.True(t, stronger.NeedsRehash(hash))
+	// Hashes antigos continuam válidos com os parâmetros gravados neles
+	assert.True(t, stronger.Verify(hash, "correct horse"))
+
+	assert.False(t, hasher.Verify("$argon2id$v=19$broken", "correct horse"))
+}
+
+func TestBcryptHasher(t *testing.T) {
+	hasher := NewBcryptHasher(bcrypt.MinCost)
+	hash, err := hasher.Hash("correct horse")
+	assert.NoError(t, err)
+	assert.True(t, hasher.Recognizes(hash))
+	assert.True(t, hasher.Verify(hash, "correct horse"))
+	assert.False(t, hasher.Verify(hash, "wrong"))
+	assert.False(t, hasher.NeedsRehash(hash))
+	assert.True(t, NewBcryptHasher(bcrypt.MinCost+1).NeedsRehash(hash))
+}
+
+func TestManager_UpgradesLegacyHashes(t *testing.T) {
+	legacy := NewBcryptHasher(bcrypt.MinCost)
+	manager := NewManager(newTestArgon2idHasher(), legacy)
+
+	bcryptHash, err := legacy.Hash("correct horse")
+	assert.NoError(t, err)
+	assert.True(t, manager.Verify(bcryptHash, "correct horse"))
+	assert.True(t, manager.NeedsRehash(bcryptHash))
+
+	argonHash, err := manager.Hash("correct horse")
+	assert.NoError(t, err)
+	assert.True(t, manager.Verify(argonHash, "correct horse"))
+	assert.False(t, manager.NeedsRehash(argonHash))
+
+	assert.False(t, manager.Verify("plain-text", "plain-text"))
+}
+
+func TestPolicy_Validate(t *testing.T) {
+	policy := DefaultPolicy()
+	assert.Equal(t, ErrTooShort, policy.Validate("a"))
+	assert.Equal(t, ErrTooShort, policy.Validate("çãõ1234"))
+	assert.NoError(t, policy.Validate("12345678"))
+	assert.Equal(t, ErrTooLong, policy.Validate(strings.Repeat("a", 73)))
+
+	policy.RequireUpper = true
+	policy.RequireLower = true
+	policy.RequireDigit = true
+	policy.RequireSymbol = true
+	assert.Equal(t, ErrMissingUpper, policy.Validate("abcdefgh"))
+	assert.Equal(t, ErrMissingLower, policy.Validate("ABCDEFGH"))
+	assert.Equal(t, ErrMissingDigit, policy.Validate("ABCDefgh"))
+	assert.Equal(t, ErrMissingSymbol, policy.Validate("ABCDefgh1"))
+	assert.NoError(t, policy.Validate("ABCDefgh1!"))
+}
+
+func TestPolicy_LoadBreachedList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	err := os.WriteFile(path, []byte("# senhas vazadas\npassword1\n\nqwertyuiop\n"), 0o600)
+	assert.NoError(t, err)
+
+	policy := DefaultPolicy()
+	assert.NoError(t, policy.LoadBreachedList(path))
+	assert.Equal(t, ErrBreached, policy.Validate("password1"))
+	assert.Equal(t, ErrBreached, policy.Validate("Password1"))
+	assert.Equal(t, ErrBreached, policy.Validate("QWERTYUIOP"))
+	assert.NoError(t, policy.Validate("# senhas vazadas"))
+
+	assert.Error(t, policy.LoadBreachedList(filepath.Join(t.TempDir(), "missing.txt")))
+}
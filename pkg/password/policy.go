@@ -0,0 +1,96 @@
+package password
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	ErrTooShort      = errors.New("password is too short")
+	ErrTooLong       = errors.New("password is too long")
+	ErrMissingUpper  = errors.New("password must contain an uppercase letter")
+	ErrMissingLower  = errors.New("password must contain a lowercase letter")
+	ErrMissingDigit  = errors.New("password must contain a digit")
+	ErrMissingSymbol = errors.New("password must contain a symbol")
+	ErrBreached      = errors.New("password appears in a list of breached passwords")
+)
+
+type Policy struct {
+	MinLength     int // Em caracteres
+	MaxLength     int // Em bytes: o bcrypt ignora o que passa de 72 bytes
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	breached      map[string]struct{}
+}
+
+// DefaultPolicy segue o mínimo recomendado pelo NIST SP 800-63B, sem exigir classes de caracteres
+func DefaultPolicy() *Policy {
+	return &Policy{MinLength: 8, MaxLength: 72}
+}
+
+// Validate retorna o primeiro requisito não atendido
+func (p *Policy) Validate(password string) error {
+	if utf8.RuneCountInString(password) < p.MinLength {
+		return ErrTooShort
+	}
+	if p.MaxLength > 0 && len(password) > p.MaxLength {
+		return ErrTooLong
+	}
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	switch {
+	case p.RequireUpper && !upper:
+		return ErrMissingUpper
+	case p.RequireLower && !lower:
+		return ErrMissingLower
+	case p.RequireDigit && !digit:
+		return ErrMissingDigit
+	case p.RequireSymbol && !symbol:
+		return ErrMissingSymbol
+	}
+	if _, ok := p.breached[strings.ToLower(password)]; ok {
+		return ErrBreached
+	}
+	return nil
+}
+
+// LoadBreachedList ignora linhas vazias e iniciadas por #. A comparação não diferencia maiúsculas.
+func (p *Policy) LoadBreachedList(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	breached := map[string]struct{}{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		breached[strings.ToLower(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	p.breached = breached
+	return nil
+}